@@ -0,0 +1,129 @@
+package models
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// BagItDeclaration is the name of the tag file that identifies a bag. It is written last so that its
+// presence means the whole bag has been written.
+const BagItDeclaration = "bagit.txt"
+
+// BagItPayloadManifest is the name of the tag file listing the checksum of each file.
+const BagItPayloadManifest = "manifest-sha256.txt"
+
+// BagItTagFileNames are the names of the tag files returned by BagItTagFiles, in the same order.
+var BagItTagFileNames = []string{"bag-info.txt", BagItPayloadManifest, BagItDeclaration}
+
+// TagFile is one file of a BagIt bag.
+type TagFile struct {
+	Name    string
+	Content []byte
+}
+
+// BagItTagFiles returns the tag files of a BagIt (RFC 8493) description of the manifest: bag-info.txt with the
+// dataset metadata, manifest-sha256.txt with the checksum of each file, and the bagit.txt declaration, in the
+// order they should be written. Files without a usable SHA-256 checksum are left out of the payload manifest.
+func (m WorkspaceManifest) BagItTagFiles() []TagFile {
+	var manifest bytes.Buffer
+	encoder := &BagItEncoder{manifest: m, w: &manifest}
+	for _, file := range m.Files {
+		// writes to a bytes.Buffer do not fail
+		_ = encoder.WriteFile(file)
+	}
+	tagFiles := encoder.TagFiles()
+	return []TagFile{tagFiles[0], {Name: BagItPayloadManifest, Content: manifest.Bytes()}, tagFiles[1]}
+}
+
+// BagItEncoder writes the payload manifest, manifest-sha256.txt, of a BagIt bag. The other tag files depend on
+// the totals of the payload and are returned by TagFiles once all files have been written.
+type BagItEncoder struct {
+	manifest WorkspaceManifest
+	w        io.Writer
+	octets   int64
+	streams  int64
+}
+
+func (e *BagItEncoder) WriteCollection(string) error {
+	return nil
+}
+
+func (e *BagItEncoder) WriteFile(file ManifestDTO) error {
+	checksum, ok := sha256Checksum(file.CheckSum, file.Size)
+	if !ok || !file.FileName.Valid {
+		return nil
+	}
+	if _, err := fmt.Fprintf(e.w, "%s  %s\n", checksum, bagItPath(path.Join("data", file.Path, file.FileName.String))); err != nil {
+		return err
+	}
+	e.octets += file.Size.Int64
+	e.streams++
+	return nil
+}
+
+func (e *BagItEncoder) Close() error {
+	return nil
+}
+
+// TagFiles returns bag-info.txt and the bagit.txt declaration, in the order they should be written after the
+// payload manifest.
+func (e *BagItEncoder) TagFiles() []TagFile {
+	m := e.manifest
+	var bagInfo strings.Builder
+	writeBagInfo := func(label, value string) {
+		if len(value) > 0 {
+			// continuation lines of a value are indented
+			fmt.Fprintf(&bagInfo, "%s: %s\n", label, strings.ReplaceAll(strings.TrimSpace(value), "\n", "\n  "))
+		}
+	}
+	writeBagInfo("External-Identifier", m.DatasetNodeId)
+	writeBagInfo("Title", m.Name)
+	writeBagInfo("External-Description", m.Description)
+	writeBagInfo("License", m.License)
+	for _, contributor := range m.Contributors {
+		writeBagInfo("Contributor", contributor)
+	}
+	writeBagInfo("Bagging-Date", time.Time(m.Date).Format("2006-01-02"))
+	writeBagInfo("Payload-Oxum", fmt.Sprintf("%d.%d", e.octets, e.streams))
+
+	return []TagFile{
+		{Name: BagItTagFileNames[0], Content: []byte(bagInfo.String())},
+		{Name: BagItDeclaration, Content: []byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n")},
+	}
+}
+
+// sha256Checksum extracts the hex SHA-256 of the content of a file of the given size from its checksum, which is
+// stored as {"checksum": "...", "chunkSize": ...}. Files larger than the chunk size were uploaded in chunks, and
+// their checksum is computed from those of the chunks rather than the content, so they have no SHA-256.
+func sha256Checksum(checksum NullString, size NullInt) (string, bool) {
+	if !checksum.Valid {
+		return "", false
+	}
+	value := checksum.String
+	var stored struct {
+		Checksum  string `json:"checksum"`
+		ChunkSize int64  `json:"chunkSize"`
+	}
+	if err := json.Unmarshal([]byte(checksum.String), &stored); err == nil {
+		if stored.ChunkSize > 0 && (!size.Valid || size.Int64 > stored.ChunkSize) {
+			return "", false
+		}
+		value = stored.Checksum
+	}
+	value = strings.ToLower(value)
+	if decoded, err := hex.DecodeString(value); err != nil || len(decoded) != 32 {
+		return "", false
+	}
+	return value, true
+}
+
+// bagItPath percent-encodes the characters that may not appear literally in a manifest file path.
+func bagItPath(p string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(p)
+}
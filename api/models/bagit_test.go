@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBagItTagFiles(t *testing.T) {
+	manifest := testWorkspaceManifest()
+	manifest.Files = append(manifest.Files,
+		ManifestDTO{
+			PackageNodeId: "N:package:3",
+			PackageName:   "100%.txt",
+			FileName:      NullString{sql.NullString{String: "100%.txt", Valid: true}},
+			Path:          "a/b",
+			Size:          NullInt{sql.NullInt64{Int64: 8, Valid: true}},
+			CheckSum:      NullString{sql.NullString{String: `{"checksum": "1F5B2EC688D2D64EA03176FEA4E45E8F4206153053DEF5CE2111E8B18B1B36DA", "chunkSize": 5242880}`, Valid: true}},
+		},
+		ManifestDTO{
+			PackageNodeId: "N:package:4",
+			PackageName:   "unusable.txt",
+			FileName:      NullString{sql.NullString{String: "unusable.txt", Valid: true}},
+			Size:          NullInt{sql.NullInt64{Int64: 100, Valid: true}},
+			CheckSum:      NullString{sql.NullString{String: `{"checksum": "abc"}`, Valid: true}},
+		},
+		ManifestDTO{
+			PackageNodeId: "N:package:5",
+			PackageName:   "chunked.bin",
+			FileName:      NullString{sql.NullString{String: "chunked.bin", Valid: true}},
+			Size:          NullInt{sql.NullInt64{Int64: 6000000, Valid: true}},
+			CheckSum:      NullString{sql.NullString{String: `{"checksum": "2f5b2ec688d2d64ea03176fea4e45e8f4206153053def5ce2111e8b18b1b36da", "chunkSize": 5242880}`, Valid: true}},
+		},
+	)
+
+	tagFiles := manifest.BagItTagFiles()
+	if assert.Len(t, tagFiles, 3) {
+		for i, name := range BagItTagFileNames {
+			assert.Equal(t, name, tagFiles[i].Name)
+		}
+		assert.Equal(t, `External-Identifier: N:dataset:1234
+Title: Test Dataset
+External-Description: first line
+  second line
+License: N/A
+Contributor: Ada
+Contributor: Grace
+Bagging-Date: 2023-05-17
+Payload-Oxum: 8.1
+`, string(tagFiles[0].Content))
+		// the checksums of the other files are not SHA-256 digests of their content
+		assert.Equal(t, "1f5b2ec688d2d64ea03176fea4e45e8f4206153053def5ce2111e8b18b1b36da  data/a/b/100%25.txt\n", string(tagFiles[1].Content))
+		assert.Equal(t, "BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n", string(tagFiles[2].Content))
+	}
+}
+
+func TestSha256Checksum(t *testing.T) {
+	const sha = "1f5b2ec688d2d64ea03176fea4e45e8f4206153053def5ce2111e8b18b1b36da"
+	size := func(n int64) NullInt { return NullInt{sql.NullInt64{Int64: n, Valid: true}} }
+	for tName, tData := range map[string]struct {
+		checksum string
+		size     NullInt
+		expected string
+	}{
+		"one chunk":      {`{"checksum": "` + sha + `", "chunkSize": 1024}`, size(1024), sha},
+		"several chunks": {`{"checksum": "` + sha + `", "chunkSize": 1024}`, size(1025), ""},
+		"unknown size":   {`{"checksum": "` + sha + `", "chunkSize": 1024}`, NullInt{}, ""},
+		"no chunk size":  {`{"checksum": "` + sha + `"}`, size(1025), sha},
+		"plain":          {strings.ToUpper(sha), size(1025), sha},
+		"not SHA-256":    {`{"checksum": "abc", "chunkSize": 1024}`, size(10), ""},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			checksum, ok := sha256Checksum(NullString{sql.NullString{String: tData.checksum, Valid: true}}, tData.size)
+			assert.Equal(t, tData.expected, checksum)
+			assert.Equal(t, len(tData.expected) > 0, ok)
+		})
+	}
+}
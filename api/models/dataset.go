@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
+	"time"
+)
+
+// DatasetDTO is the public representation of a dataset. Columns that are only meaningful inside the platform,
+// like the permission bit and role, are left out so that they can change without breaking clients. Absent
+// optional values are null.
+type DatasetDTO struct {
+	ID                 string    `json:"id"`
+	IntId              int64     `json:"intId"`
+	Name               string    `json:"name"`
+	Description        *string   `json:"description"`
+	State              string    `json:"state"`
+	Status             string    `json:"status"`
+	License            *string   `json:"license"`
+	Tags               []string  `json:"tags"`
+	Contributors       []string  `json:"contributors"`
+	Size               *int64    `json:"size"`
+	ReadmeId           *string   `json:"readmeId"`
+	BannerId           *string   `json:"bannerId"`
+	ChangelogId        *string   `json:"changelogId"`
+	DataUseAgreementId *int32    `json:"dataUseAgreementId"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+func NewDatasetDTO(ds *pgdb.Dataset) DatasetDTO {
+	dto := DatasetDTO{
+		ID:           ds.NodeId.String,
+		IntId:        ds.Id,
+		Name:         ds.Name,
+		State:        ds.State,
+		Status:       ds.Status,
+		Tags:         append([]string{}, ds.Tags...),
+		Contributors: append([]string{}, ds.Contributors...),
+		ReadmeId:     uuidOrNil(ds.ReadmeId),
+		BannerId:     uuidOrNil(ds.BannerId),
+		CreatedAt:    ds.CreatedAt,
+		UpdatedAt:    ds.UpdatedAt,
+	}
+	if ds.Description.Valid {
+		dto.Description = &ds.Description.String
+	}
+	if ds.License.Valid {
+		dto.License = &ds.License.String
+	}
+	if ds.Size.Valid {
+		dto.Size = &ds.Size.Int64
+	}
+	if ds.ChangelogId.Valid {
+		dto.ChangelogId = uuidOrNil(ds.ChangelogId.UUID)
+	}
+	if ds.DataUseAgreementId.Valid {
+		dto.DataUseAgreementId = &ds.DataUseAgreementId.Int32
+	}
+	return dto
+}
+
+// DatasetETag returns the entity tag of the dataset's DTO. It is derived from the etag column, which the
+// database updates whenever the dataset changes, and from updated_at, which TouchDataset sets without changing
+// the etag column.
+func DatasetETag(ds *pgdb.Dataset) string {
+	return fmt.Sprintf(`"%d-%d-%d"`, ds.Id, ds.ETag.UnixMicro(), ds.UpdatedAt.UnixMicro())
+}
+
+func uuidOrNil(id uuid.UUID) *string {
+	if id == uuid.Nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
@@ -0,0 +1,34 @@
+package models
+
+// DatasetStats summarizes the packages and files of a dataset, for example to size jobs that process it.
+// Everything except PackageStates and TrashedCount only counts live packages, that is those that are not in the
+// trashcan and not below a folder in the trashcan.
+type DatasetStats struct {
+	DatasetId    string `json:"datasetId"`
+	PackageCount int    `json:"packageCount"`
+	FileCount    int    `json:"fileCount"`
+	// TotalSize is the sum of the sizes of the files in bytes
+	TotalSize    int64                       `json:"totalSize"`
+	PackageTypes map[string]PackageTypeStats `json:"packageTypes"`
+	// PackageStates counts all packages that have not been purged, including those in the trashcan, by state
+	PackageStates map[string]int `json:"packageStates"`
+	// TrashedCount is the number of packages in the trashcan: those that are DELETED or DELETING and those below them
+	TrashedCount int `json:"trashedCount"`
+	// MaxFolderDepth is the number of nested folders on the deepest path, 0 if there are no folders
+	MaxFolderDepth       int           `json:"maxFolderDepth"`
+	FilesWithoutChecksum int           `json:"filesWithoutChecksum"`
+	LargestFiles         []LargestFile `json:"largestFiles"`
+}
+
+type PackageTypeStats struct {
+	Count int `json:"count"`
+	// Size is the sum of the sizes of the files of packages of the type in bytes
+	Size int64 `json:"size"`
+}
+
+type LargestFile struct {
+	PackageNodeId string `json:"packageNodeId"`
+	PackageName   string `json:"packageName"`
+	FileName      string `json:"fileName"`
+	Size          int64  `json:"size"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDatasetDTONulls(t *testing.T) {
+	ds := &pgdb.Dataset{
+		Id:            1,
+		Name:          "Minimal",
+		NodeId:        sql.NullString{String: "N:dataset:1", Valid: true},
+		PermissionBit: sql.NullInt32{Int32: 7, Valid: true},
+	}
+	body, err := json.Marshal(NewDatasetDTO(ds))
+	if assert.NoError(t, err) {
+		var fields map[string]any
+		if assert.NoError(t, json.Unmarshal(body, &fields)) {
+			assert.Equal(t, "N:dataset:1", fields["id"])
+			assert.Equal(t, []any{}, fields["tags"])
+			assert.Equal(t, []any{}, fields["contributors"])
+			for _, name := range []string{"description", "license", "size", "readmeId", "bannerId", "changelogId", "dataUseAgreementId"} {
+				value, ok := fields[name]
+				assert.True(t, ok, name)
+				assert.Nil(t, value, name)
+			}
+			assert.NotContains(t, fields, "permissionBit")
+		}
+	}
+}
+
+func TestDatasetETag(t *testing.T) {
+	ds := &pgdb.Dataset{Id: 1, ETag: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	etag := DatasetETag(ds)
+	assert.Regexp(t, `^"1-\d+-\d+"$`, etag)
+
+	ds.ETag = ds.ETag.Add(time.Microsecond)
+	changedETag := DatasetETag(ds)
+	assert.NotEqual(t, etag, changedETag)
+
+	// TouchDataset only changes updated_at
+	ds.UpdatedAt = ds.UpdatedAt.Add(time.Microsecond)
+	assert.NotEqual(t, changedETag, DatasetETag(ds))
+}
@@ -37,3 +37,99 @@ func (e FolderNotFoundError) Error() string {
 	}
 	return fmt.Sprintf("folder with node id %q not found in dataset %s, workspace %d (actual type %s)", e.NodeId, e.DatasetId, e.OrgId, e.ActualType)
 }
+
+type PackageNotDeletedError struct {
+	OrgId     int
+	Id        PackageId
+	DatasetId DatasetId
+}
+
+func (e PackageNotDeletedError) Error() string {
+	return fmt.Sprintf("package with node id %q in dataset %s, workspace %d is not in the trashcan", e.Id, e.DatasetId, e.OrgId)
+}
+
+type ManifestJobNotFoundError struct {
+	JobId         string
+	DatasetNodeId string
+}
+
+func (e ManifestJobNotFoundError) Error() string {
+	return fmt.Sprintf("manifest job %q not found for dataset %s", e.JobId, e.DatasetNodeId)
+}
+
+type ManifestNotFoundError struct {
+	S3Key         string
+	DatasetNodeId string
+}
+
+func (e ManifestNotFoundError) Error() string {
+	return fmt.Sprintf("manifest %q not found for dataset %s", e.S3Key, e.DatasetNodeId)
+}
+
+// ValidationError is returned when a request parameter or body is invalid. Details, if set, is included in the
+// error response as is.
+type ValidationError struct {
+	Message string
+	Details any
+}
+
+func (e ValidationError) Error() string {
+	return e.Message
+}
+
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e UnauthorizedError) Error() string {
+	if len(e.Message) == 0 {
+		return "unauthorized"
+	}
+	return e.Message
+}
+
+type MethodNotAllowedError struct {
+	Method string
+	// Allowed are the methods the resource supports
+	Allowed []string
+}
+
+func (e MethodNotAllowedError) Error() string {
+	return "method not allowed: " + e.Method
+}
+
+type ResourceNotFoundError struct {
+	Path string
+}
+
+func (e ResourceNotFoundError) Error() string {
+	return "resource not found: " + e.Path
+}
+
+// ErrorCode is a stable, machine-readable identifier for the kind of error in an ErrorResponse.
+type ErrorCode string
+
+const (
+	ErrorCodeValidation          ErrorCode = "VALIDATION_ERROR"
+	ErrorCodeUnauthorized        ErrorCode = "UNAUTHORIZED"
+	ErrorCodeMethodNotAllowed    ErrorCode = "METHOD_NOT_ALLOWED"
+	ErrorCodeResourceNotFound    ErrorCode = "RESOURCE_NOT_FOUND"
+	ErrorCodeDatasetNotFound     ErrorCode = "DATASET_NOT_FOUND"
+	ErrorCodePackageNotFound     ErrorCode = "PACKAGE_NOT_FOUND"
+	ErrorCodeFolderNotFound      ErrorCode = "FOLDER_NOT_FOUND"
+	ErrorCodePackageNotDeleted   ErrorCode = "PACKAGE_NOT_DELETED"
+	ErrorCodeManifestJobNotFound ErrorCode = "MANIFEST_JOB_NOT_FOUND"
+	ErrorCodeManifestNotFound    ErrorCode = "MANIFEST_NOT_FOUND"
+	ErrorCodeConflict            ErrorCode = "CONFLICT"
+	ErrorCodeInternal            ErrorCode = "INTERNAL_ERROR"
+	ErrorCodeUnavailable         ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrorCodeTimeout             ErrorCode = "TIMEOUT"
+)
+
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Message   string    `json:"message"`
+	Code      ErrorCode `json:"code"`
+	RequestId string    `json:"requestId"`
+	Details   any       `json:"details,omitempty"`
+}
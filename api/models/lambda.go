@@ -1,6 +1,38 @@
 package models
 
+import "time"
+
+// ManifestWorkerInput is published to the manifest topic to have the worker build a manifest
+// and write it to ManifestS3Key. JobId identifies the job whose status the worker updates.
 type ManifestWorkerInput struct {
-	OrgIntId      int    `json:"org_int_id"`
-	DatasetNodeId string `json:"dataset_node_id"`
+	OrgIntId      int            `json:"org_int_id"`
+	DatasetNodeId string         `json:"dataset_node_id"`
+	JobId         string         `json:"job_id"`
+	ManifestS3Key string         `json:"manifest_s3_key"`
+	Format        ManifestFormat `json:"format,omitempty"`
+	RootNodeId    string         `json:"root_node_id,omitempty"`
+	AbsolutePaths bool           `json:"absolute_paths,omitempty"`
+	IncludeUrls   bool           `json:"include_urls,omitempty"`
+	// UrlExpirySeconds is how long the presigned URLs of the files stay valid
+	UrlExpirySeconds int64 `json:"url_expiry_seconds,omitempty"`
+}
+
+// ManifestOptions returns the options the manifest was requested with.
+func (i ManifestWorkerInput) ManifestOptions() ManifestOptions {
+	return ManifestOptions{
+		Format:        i.Format,
+		RootNodeId:    i.RootNodeId,
+		AbsolutePaths: i.AbsolutePaths,
+		IncludeUrls:   i.IncludeUrls,
+		UrlExpiry:     time.Duration(i.UrlExpirySeconds) * time.Second,
+	}
+}
+
+// PurgeWorkerInput is published to the purge topic after trashcan packages have been marked for permanent deletion.
+// The purge worker removes the files of the listed packages from storage, along with their rows.
+type PurgeWorkerInput struct {
+	OrgIntId      int     `json:"org_int_id"`
+	DatasetIntId  int64   `json:"dataset_int_id"`
+	DatasetNodeId string  `json:"dataset_node_id"`
+	PackageIds    []int64 `json:"package_ids"`
 }
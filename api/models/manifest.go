@@ -9,8 +9,9 @@ import (
 )
 
 type HandlerVars struct {
-	S3Bucket string
-	SnsTopic string
+	S3Bucket      string
+	SnsTopic      string
+	PurgeSnsTopic string
 }
 
 type WriteManifestOutput struct {
@@ -28,7 +29,17 @@ type WorkspaceManifest struct {
 	License       string            `json:"license"`
 	Contributors  pgdb.Contributors `json:"contributors"`
 	Tags          pgdb.Tags         `json:"tags"`
-	Files         []ManifestDTO     `json:"files"`
+	// RootNodeId is the collection the manifest is limited to, if any. AbsolutePaths is true if file paths start
+	// at the dataset root rather than at that collection.
+	RootNodeId    string `json:"rootNodeId,omitempty"`
+	AbsolutePaths bool   `json:"absolutePaths,omitempty"`
+	// FileUrlsExpireAt is when the presigned URLs of the files stop working, if the manifest has them
+	FileUrlsExpireAt *time.Time    `json:"fileUrlsExpireAt,omitempty"`
+	Files            []ManifestDTO `json:"files"`
+	// Collections are the paths of the dataset's collections. Only the RO-Crate format lists them.
+	Collections []string `json:"-"`
+	// FileUrls is true if the Files have presigned download URLs
+	FileUrls bool `json:"-"`
 }
 
 type ManifestDTO struct {
@@ -39,6 +50,8 @@ type ManifestDTO struct {
 	Path          string     `json:"path"`
 	Size          NullInt    `json:"size,omitempty"`
 	CheckSum      NullString `json:"checksum,omitempty"`
+	// Url is a presigned URL to download the file, only set if the manifest was requested with URLs
+	Url string `json:"url,omitempty"`
 }
 
 type DatasetManifest struct {
@@ -50,6 +63,8 @@ type DatasetManifest struct {
 	PackageNodeId string          `json:"package_node_id"`
 	Size          NullInt         `json:"size,omitempty"`
 	CheckSum      NullString      `json:"checksum,omitempty"`
+	S3Bucket      NullString      `json:"s3_bucket,omitempty"`
+	S3Key         NullString      `json:"s3_key,omitempty"`
 }
 
 type JSONDate time.Time
@@ -83,6 +98,10 @@ func (x *NullString) MarshalJSON() ([]byte, error) {
 }
 
 func (x *NullString) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*x = NullString{}
+		return nil
+	}
 	var s string
 	if err := json.Unmarshal(data, &s); err != nil {
 		return err
@@ -106,6 +125,10 @@ func (x *NullInt) MarshalJSON() ([]byte, error) {
 }
 
 func (x *NullInt) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*x = NullInt{}
+		return nil
+	}
 	var s int64
 	if err := json.Unmarshal(data, &s); err != nil {
 		return err
@@ -116,8 +139,60 @@ func (x *NullInt) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+type ManifestStatus string
+
+const (
+	CREATING ManifestStatus = "CREATING"
+	READY    ManifestStatus = "READY"
+	FAILED   ManifestStatus = "FAILED"
+)
+
+// ManifestOptions are the choices a caller can make about a manifest.
+type ManifestOptions struct {
+	Format ManifestFormat
+	// Force regenerates the manifest even if one already exists for the current version of the dataset
+	Force bool
+	// RootNodeId limits the manifest to the files below this collection. Empty means the whole dataset.
+	RootNodeId string
+	// AbsolutePaths keeps the path of RootNodeId in front of file paths instead of making them relative to it
+	AbsolutePaths bool
+	// IncludeUrls adds a presigned download URL, valid for UrlExpiry, to each file
+	IncludeUrls bool
+	UrlExpiry   time.Duration
+}
+
+const (
+	DefaultManifestUrlExpiry = time.Hour
+	// MaxManifestUrlExpiry is the longest a presigned URL can be valid for. URLs stop working when the temporary
+	// credentials that signed them expire, and the credentials of an IAM role last 12 hours at most.
+	MaxManifestUrlExpiry = 12 * time.Hour
+)
+
 type ManifestResult struct {
-	Url      string `json:"url"`
-	S3Bucket string `json:"s3_bucket"`
-	S3Key    string `json:"s3_key"`
+	Url      string         `json:"url,omitempty"`
+	S3Bucket string         `json:"s3_bucket"`
+	S3Key    string         `json:"s3_key"`
+	JobId    string         `json:"job_id,omitempty"`
+	Status   ManifestStatus `json:"status,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	// TagFiles holds the presigned URLs of the files of a BagIt manifest, which has no single Url
+	TagFiles []TagFileUrl `json:"tag_files,omitempty"`
+}
+
+type TagFileUrl struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+// ManifestJob tracks an asynchronous manifest generation. It is stored next to the manifests
+// in the manifest bucket and updated by the worker when the manifest is done.
+type ManifestJob struct {
+	JobId         string         `json:"job_id"`
+	DatasetNodeId string         `json:"dataset_node_id"`
+	S3Key         string         `json:"s3_key"`
+	Format        ManifestFormat `json:"format,omitempty"`
+	Status        ManifestStatus `json:"status"`
+	Error         string         `json:"error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
 }
@@ -0,0 +1,295 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// LiveManifest is the version a manifest diff uses for the current contents of the database.
+const LiveManifest = "live"
+
+// ManifestDiff lists the files that differ between two versions of a dataset's manifest.
+type ManifestDiff struct {
+	// From and To are the S3 keys of the compared manifests, or LiveManifest
+	From    string               `json:"from"`
+	To      string               `json:"to"`
+	Added   []ManifestDTO        `json:"added"`
+	Removed []ManifestDTO        `json:"removed"`
+	Moved   []ManifestFileChange `json:"moved"`
+	Changed []ManifestFileChange `json:"changed"`
+}
+
+// ManifestFileChange is a file in both versions of a manifest.
+type ManifestFileChange struct {
+	From ManifestDTO `json:"from"`
+	To   ManifestDTO `json:"to"`
+}
+
+// DiffManifests compares the files of two versions of a manifest. A file is moved if its file UUID is in both
+// versions with different paths, and changed if its path is in both versions with different checksums. Other
+// files are added or removed. Packages without a file are ignored.
+func DiffManifests(from []ManifestDTO, to []ManifestDTO) ManifestDiff {
+	diff := ManifestDiff{Added: []ManifestDTO{}, Removed: []ManifestDTO{}, Moved: []ManifestFileChange{}, Changed: []ManifestFileChange{}}
+
+	fromByFileId := map[string]int{}
+	fromByPath := map[string]int{}
+	for i, file := range from {
+		if !file.FileName.Valid {
+			continue
+		}
+		if file.FileNodeId.Valid {
+			fromByFileId[file.FileNodeId.String] = i
+		}
+		fromByPath[file.fullPath()] = i
+	}
+
+	// A file matched by its UUID cannot also be matched by its path, so UUIDs are matched first.
+	matchedFrom := map[int]bool{}
+	matchedTo := map[int]bool{}
+	for i, file := range to {
+		if !file.FileName.Valid || !file.FileNodeId.Valid {
+			continue
+		}
+		if j, ok := fromByFileId[file.FileNodeId.String]; ok {
+			matchedFrom[j], matchedTo[i] = true, true
+			if from[j].fullPath() != file.fullPath() {
+				diff.Moved = append(diff.Moved, ManifestFileChange{From: from[j], To: file})
+			}
+		}
+	}
+	for i, file := range to {
+		if !file.FileName.Valid || matchedTo[i] {
+			continue
+		}
+		if j, ok := fromByPath[file.fullPath()]; ok && !matchedFrom[j] {
+			matchedFrom[j] = true
+			if !sameChecksum(from[j], file) {
+				diff.Changed = append(diff.Changed, ManifestFileChange{From: from[j], To: file})
+			}
+			continue
+		}
+		diff.Added = append(diff.Added, file)
+	}
+	for i, file := range from {
+		if file.FileName.Valid && !matchedFrom[i] {
+			diff.Removed = append(diff.Removed, file)
+		}
+	}
+	return diff
+}
+
+// ErrManifestNotInPathOrder is returned by a ManifestDiffer if the files of a manifest do not come in the order of
+// CompareManifestFiles. Manifests written before files were ordered by path are like this.
+var ErrManifestNotInPathOrder = errors.New("manifest files are not in path order")
+
+// CompareManifestFiles orders manifest files by path, one folder at a time, and then by file name. This is the order
+// in which manifests list their files.
+func CompareManifestFiles(a ManifestDTO, b ManifestDTO) int {
+	if c := slices.Compare(pathElements(a.Path), pathElements(b.Path)); c != 0 {
+		return c
+	}
+	return strings.Compare(a.FileName.String, b.FileName.String)
+}
+
+func pathElements(p string) []string {
+	if len(p) == 0 {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// ManifestDiffer compares two versions of a manifest without holding either in memory. Both versions must list
+// their files in the order of CompareManifestFiles: the files of the newer version are passed to Add one at a
+// time, and the older version is read alongside them. Only files that are not in both versions, at the same path
+// with the same file UUID, are kept until Finish diffs them with DiffManifests.
+type ManifestDiffer struct {
+	from     func() (*ManifestDTO, error)
+	nextFrom *ManifestDTO
+	lastFrom *ManifestDTO
+	lastTo   *ManifestDTO
+	// fromDone is true once from has returned its last file
+	fromDone    bool
+	pendingFrom []ManifestDTO
+	pendingTo   []ManifestDTO
+}
+
+// NewManifestDiffer returns a ManifestDiffer reading the older version of the manifest from from, which returns
+// nil after the last file.
+func NewManifestDiffer(from func() (*ManifestDTO, error)) *ManifestDiffer {
+	return &ManifestDiffer{from: from}
+}
+
+// Add compares the next file of the newer version.
+func (d *ManifestDiffer) Add(to ManifestDTO) error {
+	if !to.FileName.Valid {
+		return nil
+	}
+	if d.lastTo != nil && CompareManifestFiles(*d.lastTo, to) > 0 {
+		return ErrManifestNotInPathOrder
+	}
+	d.lastTo = &to
+	for {
+		from, err := d.peekFrom()
+		if err != nil {
+			return err
+		}
+		if from == nil {
+			break
+		}
+		c := CompareManifestFiles(*from, to)
+		if c > 0 {
+			break
+		}
+		d.nextFrom = nil
+		if c == 0 && sameFile(*from, to) {
+			return nil
+		}
+		d.pendingFrom = append(d.pendingFrom, *from)
+		if c == 0 {
+			break
+		}
+	}
+	d.pendingTo = append(d.pendingTo, to)
+	return nil
+}
+
+// Finish reads the rest of the older version and returns the diff.
+func (d *ManifestDiffer) Finish() (ManifestDiff, error) {
+	for {
+		from, err := d.peekFrom()
+		if err != nil {
+			return ManifestDiff{}, err
+		}
+		if from == nil {
+			return DiffManifests(d.pendingFrom, d.pendingTo), nil
+		}
+		d.nextFrom = nil
+		d.pendingFrom = append(d.pendingFrom, *from)
+	}
+}
+
+// peekFrom returns the next file of the older version without consuming it, or nil after the last one.
+func (d *ManifestDiffer) peekFrom() (*ManifestDTO, error) {
+	for d.nextFrom == nil && !d.fromDone {
+		from, err := d.from()
+		if err != nil {
+			return nil, err
+		}
+		if from == nil {
+			d.fromDone = true
+			break
+		}
+		if !from.FileName.Valid {
+			continue
+		}
+		if d.lastFrom != nil && CompareManifestFiles(*d.lastFrom, *from) > 0 {
+			return nil, ErrManifestNotInPathOrder
+		}
+		d.lastFrom, d.nextFrom = from, from
+	}
+	return d.nextFrom, nil
+}
+
+// sameFile is true if a and b have the same file UUID, which DiffManifests reports as neither moved nor changed
+// when they also have the same path.
+func sameFile(a ManifestDTO, b ManifestDTO) bool {
+	return a.FileNodeId.Valid && b.FileNodeId.Valid && a.FileNodeId.String == b.FileNodeId.String
+}
+
+// ManifestReader reads a manifest in the JSON format one file at a time. The other fields of the manifest are read
+// by NewManifestReader, so they must come before the files, as they do in manifests written by NewEncoder.
+type ManifestReader struct {
+	// Manifest holds the fields of the manifest other than its files
+	Manifest WorkspaceManifest
+	body     io.ReadCloser
+	decoder  *json.Decoder
+	done     bool
+}
+
+// NewManifestReader reads the manifest up to its first file. Closing the reader closes body.
+func NewManifestReader(body io.ReadCloser) (*ManifestReader, error) {
+	r := &ManifestReader{body: body, decoder: json.NewDecoder(body), done: true}
+	if err := r.expectDelim('{'); err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	for r.decoder.More() {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if token == "files" {
+			if token, err = r.decoder.Token(); err != nil {
+				return nil, err
+			}
+			// The files may be null
+			r.done = token != json.Delim('[')
+			break
+		}
+		var value json.RawMessage
+		if err := r.decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields[fmt.Sprint(token)] = value
+	}
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(encoded, &r.Manifest); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Next returns the next file of the manifest, or nil after the last one.
+func (r *ManifestReader) Next() (*ManifestDTO, error) {
+	if r.done {
+		return nil, nil
+	}
+	if !r.decoder.More() {
+		r.done = true
+		return nil, r.expectDelim(']')
+	}
+	var file ManifestDTO
+	if err := r.decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (r *ManifestReader) Close() error {
+	return r.body.Close()
+}
+
+func (r *ManifestReader) expectDelim(delim json.Delim) error {
+	token, err := r.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("invalid manifest: expected %q, found %v", delim, token)
+	}
+	return nil
+}
+
+// fullPath is the path of the file in the dataset, including its name.
+func (f ManifestDTO) fullPath() string {
+	return path.Join(f.Path, f.FileName.String)
+}
+
+// sameChecksum compares the SHA-256 of the content of two files if both have one, since the chunk size stored with
+// it does not change the content. Other checksums are compared as they are.
+func sameChecksum(a ManifestDTO, b ManifestDTO) bool {
+	aSum, aOk := sha256Checksum(a.CheckSum, a.Size)
+	bSum, bOk := sha256Checksum(b.CheckSum, b.Size)
+	if aOk && bOk {
+		return aSum == bSum
+	}
+	return a.CheckSum.Valid == b.CheckSum.Valid && a.CheckSum.String == b.CheckSum.String
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffManifests(t *testing.T) {
+	file := func(fileId string, dir string, name string, checksum string) ManifestDTO {
+		return ManifestDTO{
+			PackageNodeId: "N:package:" + fileId,
+			FileNodeId:    NullString{sql.NullString{String: fileId, Valid: len(fileId) > 0}},
+			FileName:      NullString{sql.NullString{String: name, Valid: true}},
+			Path:          dir,
+			CheckSum:      NullString{sql.NullString{String: checksum, Valid: len(checksum) > 0}},
+		}
+	}
+	sha := func(hex string, chunkSize string) string {
+		return `{"checksum": "` + hex + `", "chunkSize": ` + chunkSize + `}`
+	}
+	const sha1 = "1f5b2ec688d2d64ea03176fea4e45e8f4206153053def5ce2111e8b18b1b36da"
+	const sha2 = "2f5b2ec688d2d64ea03176fea4e45e8f4206153053def5ce2111e8b18b1b36da"
+
+	unchanged := file("u1", "a", "same.txt", sha(sha1, "5242880"))
+	// the chunk size does not change the content
+	rechunked := file("u1", "a", "same.txt", sha(sha1, "1024"))
+	movedFrom := file("u2", "a", "moved.txt", "x")
+	movedTo := file("u2", "b", "moved.txt", "x")
+	changedFrom := file("u3", "a", "changed.txt", sha(sha1, "1024"))
+	// re-uploaded, so a new file UUID at the same path
+	changedTo := file("u4", "a", "changed.txt", sha(sha2, "1024"))
+	removed := file("u5", "", "removed.txt", "")
+	added := file("u6", "", "added.txt", "")
+	// taking the path of a moved file makes it a different file
+	replacement := file("u7", "a", "moved.txt", "x")
+	noFile := ManifestDTO{PackageNodeId: "N:package:empty"}
+
+	diff := DiffManifests(
+		[]ManifestDTO{unchanged, movedFrom, changedFrom, removed, noFile},
+		[]ManifestDTO{rechunked, movedTo, changedTo, added, replacement, noFile},
+	)
+	assert.Equal(t, ManifestDiff{
+		Added:   []ManifestDTO{added, replacement},
+		Removed: []ManifestDTO{removed},
+		Moved:   []ManifestFileChange{{From: movedFrom, To: movedTo}},
+		Changed: []ManifestFileChange{{From: changedFrom, To: changedTo}},
+	}, diff)
+}
+
+func TestManifestDiffer(t *testing.T) {
+	file := func(fileId string, dir string, name string, checksum string) ManifestDTO {
+		return ManifestDTO{
+			PackageNodeId: "N:package:" + fileId,
+			FileNodeId:    NullString{sql.NullString{String: fileId, Valid: true}},
+			FileName:      NullString{sql.NullString{String: name, Valid: true}},
+			Path:          dir,
+			CheckSum:      NullString{sql.NullString{String: checksum, Valid: true}},
+		}
+	}
+	unchanged := file("u1", "a", "same.txt", "x")
+	movedFrom := file("u2", "a", "moved.txt", "x")
+	movedTo := file("u2", "a/b", "moved.txt", "x")
+	changedFrom := file("u3", "a", "x.txt", "1")
+	changedTo := file("u4", "a", "x.txt", "2")
+	removed := file("u5", "", "removed.txt", "x")
+	added := file("u6", "a-b", "added.txt", "x")
+	noFile := ManifestDTO{PackageNodeId: "N:package:empty"}
+
+	from := []ManifestDTO{removed, noFile, movedFrom, unchanged, changedFrom}
+	to := []ManifestDTO{unchanged, changedTo, movedTo, added}
+	differ := NewManifestDiffer(sliceFiles(from))
+	for _, file := range to {
+		assert.NoError(t, differ.Add(file))
+	}
+	diff, err := differ.Finish()
+	if assert.NoError(t, err) {
+		assert.Equal(t, DiffManifests(from, to), diff)
+		assert.Equal(t, ManifestDiff{
+			Added:   []ManifestDTO{added},
+			Removed: []ManifestDTO{removed},
+			Moved:   []ManifestFileChange{{From: movedFrom, To: movedTo}},
+			Changed: []ManifestFileChange{{From: changedFrom, To: changedTo}},
+		}, diff)
+	}
+
+	t.Run("from not in path order", func(t *testing.T) {
+		differ := NewManifestDiffer(sliceFiles([]ManifestDTO{unchanged, removed}))
+		_, err := differ.Finish()
+		assert.ErrorIs(t, err, ErrManifestNotInPathOrder)
+	})
+	t.Run("to not in path order", func(t *testing.T) {
+		differ := NewManifestDiffer(sliceFiles(nil))
+		assert.NoError(t, differ.Add(unchanged))
+		assert.ErrorIs(t, differ.Add(removed), ErrManifestNotInPathOrder)
+	})
+}
+
+func sliceFiles(files []ManifestDTO) func() (*ManifestDTO, error) {
+	return func() (*ManifestDTO, error) {
+		if len(files) == 0 {
+			return nil, nil
+		}
+		file := files[0]
+		files = files[1:]
+		return &file, nil
+	}
+}
+
+func TestManifestReader(t *testing.T) {
+	files := []ManifestDTO{
+		{PackageNodeId: "N:package:1", PackageName: "a.txt", Path: "folder"},
+		{PackageNodeId: "N:package:2", PackageName: "b.txt", Path: "folder"},
+	}
+	for name, manifest := range map[string]WorkspaceManifest{
+		"files":    {Name: "Test Dataset", RootNodeId: "N:collection:1", AbsolutePaths: true, Files: files},
+		"no files": {Name: "Test Dataset"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var encoded bytes.Buffer
+			if !assert.NoError(t, manifest.Encode(&encoded, JSONManifest)) {
+				return
+			}
+			reader, err := NewManifestReader(io.NopCloser(&encoded))
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer reader.Close()
+			var actual []ManifestDTO
+			for {
+				file, err := reader.Next()
+				if !assert.NoError(t, err) || file == nil {
+					break
+				}
+				actual = append(actual, *file)
+			}
+			assert.Equal(t, manifest.Files, actual)
+			manifest.Files = nil
+			assert.Equal(t, manifest, reader.Manifest)
+		})
+	}
+
+	t.Run("null files", func(t *testing.T) {
+		reader, err := NewManifestReader(io.NopCloser(bytes.NewBufferString(`{"name": "Test Dataset", "files": null}`)))
+		if assert.NoError(t, err) {
+			file, err := reader.Next()
+			assert.NoError(t, err)
+			assert.Nil(t, file)
+		}
+	})
+	t.Run("not a manifest", func(t *testing.T) {
+		_, err := NewManifestReader(io.NopCloser(bytes.NewBufferString(`[]`)))
+		assert.Error(t, err)
+	})
+}
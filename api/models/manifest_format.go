@@ -0,0 +1,246 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ManifestFormat is the file format a WorkspaceManifest is written in.
+type ManifestFormat string
+
+const (
+	JSONManifest ManifestFormat = "json"
+	CSVManifest  ManifestFormat = "csv"
+	TSVManifest  ManifestFormat = "tsv"
+	// BagItManifest is written as the tag files of a BagIt bag under the manifest's S3 key. See BagItEncoder.
+	BagItManifest ManifestFormat = "bagit"
+	// ROCrateManifest is an RO-Crate ro-crate-metadata.json JSON-LD document. See WorkspaceManifest.ROCrate.
+	ROCrateManifest ManifestFormat = "ro-crate"
+)
+
+// ParseManifestFormat returns the ManifestFormat named by s. The empty string is JSONManifest.
+func ParseManifestFormat(s string) (ManifestFormat, error) {
+	switch format := ManifestFormat(strings.ToLower(s)); format {
+	case "":
+		return JSONManifest, nil
+	case JSONManifest, CSVManifest, TSVManifest, BagItManifest, ROCrateManifest:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported manifest format %q", s)
+	}
+}
+
+// Extension is the file extension, without the dot, of manifests in this format.
+func (f ManifestFormat) Extension() string {
+	switch f {
+	case "":
+		return string(JSONManifest)
+	case ROCrateManifest:
+		// RO-Crate consumers look for the metadata file by name
+		return ROCrateMetadataFileName
+	}
+	return string(f)
+}
+
+func (f ManifestFormat) ContentType() string {
+	switch f {
+	case CSVManifest:
+		return "text/csv"
+	case TSVManifest:
+		return "text/tab-separated-values"
+	case BagItManifest:
+		return "text/plain; charset=utf-8"
+	case ROCrateManifest:
+		return "application/ld+json"
+	default:
+		return "application/json"
+	}
+}
+
+// manifestColumns is the header row of CSV and TSV manifests. Each ManifestDTO is one row. Manifests with
+// FileUrls have an additional url column.
+var manifestColumns = []string{"packageId", "packageName", "fileId", "fileName", "path", "size", "checksum"}
+
+// ManifestEncoder writes a manifest one collection or file at a time, so that the files of a dataset never
+// have to be held in memory together.
+type ManifestEncoder interface {
+	WriteCollection(path string) error
+	WriteFile(file ManifestDTO) error
+	// Close writes the end of the manifest. It does not close the underlying writer.
+	Close() error
+}
+
+// NewEncoder returns an encoder writing a manifest in the given format to w. The Files and Collections of m are
+// ignored, only its dataset metadata is used. A BagItManifest encoder writes the payload manifest of the bag,
+// see BagItEncoder.
+func (m WorkspaceManifest) NewEncoder(w io.Writer, format ManifestFormat) ManifestEncoder {
+	switch format {
+	case CSVManifest:
+		return newDelimitedEncoder(w, m, ',')
+	case TSVManifest:
+		return newDelimitedEncoder(w, m, '\t')
+	case BagItManifest:
+		return &BagItEncoder{manifest: m, w: w}
+	case ROCrateManifest:
+		return newROCrateEncoder(w, m)
+	default:
+		return &jsonEncoder{manifest: m, w: w}
+	}
+}
+
+// Encode writes the manifest to w in the given format. CSV and TSV manifests start with a header block of
+// '#' comment lines holding the dataset metadata, followed by a table with one row per file.
+func (m WorkspaceManifest) Encode(w io.Writer, format ManifestFormat) error {
+	encoder := m.NewEncoder(w, format)
+	for _, collection := range m.Collections {
+		if err := encoder.WriteCollection(collection); err != nil {
+			return err
+		}
+	}
+	for _, file := range m.Files {
+		if err := encoder.WriteFile(file); err != nil {
+			return err
+		}
+	}
+	return encoder.Close()
+}
+
+// jsonEncoder writes the same document as encoding WorkspaceManifest with a json.Encoder indenting by four
+// spaces, without building the files array first.
+type jsonEncoder struct {
+	manifest WorkspaceManifest
+	w        io.Writer
+	files    int
+}
+
+func (e *jsonEncoder) WriteCollection(string) error {
+	return nil
+}
+
+func (e *jsonEncoder) WriteFile(file ManifestDTO) error {
+	if e.files == 0 {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	encoded, err := json.MarshalIndent(&file, "        ", "    ")
+	if err != nil {
+		return err
+	}
+	separator := ",\n        "
+	if e.files == 0 {
+		separator = "\n        "
+	}
+	e.files++
+	if _, err = io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(encoded)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	if e.files == 0 {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+		_, err := io.WriteString(e.w, "]\n}\n")
+		return err
+	}
+	_, err := io.WriteString(e.w, "\n    ]\n}\n")
+	return err
+}
+
+// writeHeader writes everything up to and including the opening bracket of the files array.
+func (e *jsonEncoder) writeHeader() error {
+	header := e.manifest
+	header.Files = []ManifestDTO{}
+	encoded, err := json.MarshalIndent(header, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(bytes.TrimSuffix(encoded, []byte("]\n}")))
+	return err
+}
+
+type delimitedEncoder struct {
+	manifest      WorkspaceManifest
+	w             io.Writer
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func newDelimitedEncoder(w io.Writer, m WorkspaceManifest, delimiter rune) *delimitedEncoder {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	return &delimitedEncoder{manifest: m, w: w, writer: writer}
+}
+
+func (e *delimitedEncoder) WriteCollection(string) error {
+	return nil
+}
+
+func (e *delimitedEncoder) WriteFile(file ManifestDTO) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	size := ""
+	if file.Size.Valid {
+		size = strconv.FormatInt(file.Size.Int64, 10)
+	}
+	row := []string{file.PackageNodeId, file.PackageName, file.FileNodeId.String, file.FileName.String, file.Path, size, file.CheckSum.String}
+	if e.manifest.FileUrls {
+		row = append(row, file.Url)
+	}
+	return e.writer.Write(row)
+}
+
+func (e *delimitedEncoder) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// writeHeader writes the metadata comment lines and the column names unless they have been written already.
+func (e *delimitedEncoder) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+	m := e.manifest
+	header := [][2]string{
+		{"manifestCreatedOn", time.Time(m.Date).Format(time.ANSIC)},
+		{"datasetId", strconv.FormatInt(m.DatasetId, 10)},
+		{"datasetNodeId", m.DatasetNodeId},
+		{"name", m.Name},
+		{"description", m.Description},
+		{"license", m.License},
+		{"contributors", strings.Join(m.Contributors, "; ")},
+		{"tags", strings.Join(m.Tags, "; ")},
+	}
+	if m.FileUrlsExpireAt != nil {
+		header = append(header, [2]string{"fileUrlsExpireAt", m.FileUrlsExpireAt.Format(time.RFC3339)})
+	}
+	for _, field := range header {
+		if _, err := fmt.Fprintf(e.w, "# %s: %s\n", field[0], singleLine(field[1])); err != nil {
+			return err
+		}
+	}
+	if m.FileUrls {
+		return e.writer.Write(append(manifestColumns[:len(manifestColumns):len(manifestColumns)], "url"))
+	}
+	return e.writer.Write(manifestColumns)
+}
+
+// singleLine collapses line breaks and other whitespace so that a value cannot end the comment line it is written on.
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
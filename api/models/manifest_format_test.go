@@ -0,0 +1,117 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseManifestFormat(t *testing.T) {
+	for input, expected := range map[string]ManifestFormat{"": JSONManifest, "json": JSONManifest, "CSV": CSVManifest, "tsv": TSVManifest, "bagit": BagItManifest, "RO-Crate": ROCrateManifest} {
+		format, err := ParseManifestFormat(input)
+		if assert.NoError(t, err, input) {
+			assert.Equal(t, expected, format)
+		}
+	}
+	_, err := ParseManifestFormat("xlsx")
+	assert.ErrorContains(t, err, "xlsx")
+}
+
+func testWorkspaceManifest() WorkspaceManifest {
+	return WorkspaceManifest{
+		Date:          JSONDate(time.Date(2023, 5, 17, 10, 4, 32, 0, time.UTC)),
+		DatasetId:     13,
+		DatasetNodeId: "N:dataset:1234",
+		Name:          "Test Dataset",
+		Description:   "first line\nsecond line",
+		License:       "N/A",
+		Contributors:  []string{"Ada", "Grace"},
+		Tags:          []string{"eeg"},
+		Files: []ManifestDTO{
+			{
+				PackageNodeId: "N:package:1",
+				PackageName:   "data, final.csv",
+				FileNodeId:    NullString{sql.NullString{String: "f1", Valid: true}},
+				FileName:      NullString{sql.NullString{String: "data.csv", Valid: true}},
+				Path:          "folder",
+				Size:          NullInt{sql.NullInt64{Int64: 42, Valid: true}},
+				CheckSum:      NullString{sql.NullString{String: "abc", Valid: true}},
+			},
+			{PackageNodeId: "N:package:2", PackageName: "empty"},
+		},
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if assert.NoError(t, testWorkspaceManifest().Encode(&buf, CSVManifest)) {
+		assert.Equal(t, `# manifestCreatedOn: Wed May 17 10:04:32 2023
+# datasetId: 13
+# datasetNodeId: N:dataset:1234
+# name: Test Dataset
+# description: first line second line
+# license: N/A
+# contributors: Ada; Grace
+# tags: eeg
+packageId,packageName,fileId,fileName,path,size,checksum
+N:package:1,"data, final.csv",f1,data.csv,folder,42,abc
+N:package:2,empty,,,,,
+`, buf.String())
+	}
+}
+
+func TestEncodeTSV(t *testing.T) {
+	var buf bytes.Buffer
+	if assert.NoError(t, testWorkspaceManifest().Encode(&buf, TSVManifest)) {
+		assert.Contains(t, buf.String(), "packageId\tpackageName\tfileId\tfileName\tpath\tsize\tchecksum\n")
+		assert.Contains(t, buf.String(), "N:package:1\tdata, final.csv\tf1\tdata.csv\tfolder\t42\tabc\n")
+	}
+}
+
+func TestEncodeJSONStreamed(t *testing.T) {
+	// the streamed document is the one encoding/json writes for the whole manifest
+	for _, manifest := range []WorkspaceManifest{testWorkspaceManifest(), {DatasetNodeId: "N:dataset:1234"}} {
+		var expected bytes.Buffer
+		encoder := json.NewEncoder(&expected)
+		encoder.SetIndent("", "    ")
+		if manifest.Files == nil {
+			manifest.Files = []ManifestDTO{}
+		}
+		if assert.NoError(t, encoder.Encode(manifest)) {
+			var buf bytes.Buffer
+			if assert.NoError(t, manifest.Encode(&buf, JSONManifest)) {
+				assert.Equal(t, expected.String(), buf.String())
+			}
+		}
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if assert.NoError(t, testWorkspaceManifest().Encode(&buf, JSONManifest)) {
+		var decoded WorkspaceManifest
+		if assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded)) {
+			assert.Equal(t, "N:dataset:1234", decoded.DatasetNodeId)
+			assert.Len(t, decoded.Files, 2)
+		}
+	}
+}
+
+func TestEncodeCSVWithUrls(t *testing.T) {
+	manifest := testWorkspaceManifest()
+	manifest.FileUrls = true
+	manifest.Files[0].Url = "https://storage-bucket/data.csv?X-Amz-Signature=abc"
+	var buf bytes.Buffer
+	if assert.NoError(t, manifest.Encode(&buf, CSVManifest)) {
+		assert.Contains(t, buf.String(), `packageId,packageName,fileId,fileName,path,size,checksum,url
+N:package:1,"data, final.csv",f1,data.csv,folder,42,abc,https://storage-bucket/data.csv?X-Amz-Signature=abc
+N:package:2,empty,,,,,,
+`)
+	}
+	// the column list is shared with manifests without URLs
+	assert.Equal(t, []string{"packageId", "packageName", "fileId", "fileName", "path", "size", "checksum"}, manifestColumns)
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// PackagesPage is a page of the live, that is not deleted, contents of a dataset folder.
+type PackagesPage struct {
+	Limit      int           `json:"limit"`
+	Offset     int           `json:"offset"`
+	TotalCount int           `json:"totalCount"`
+	Packages   []PackageItem `json:"packages"`
+}
+
+type PackageItem struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	NodeId    string    `json:"node_id"`
+	Type      string    `json:"type"`
+	State     string    `json:"state"`
+	Size      *int64    `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	// ChildCount is the number of live packages in a collection. It is absent for other packages.
+	ChildCount *int `json:"child_count,omitempty"`
+}
+
+// PackageSortField is a property packages can be sorted by.
+type PackageSortField string
+
+const (
+	SortByName      PackageSortField = "name"
+	SortBySize      PackageSortField = "size"
+	SortByCreatedAt PackageSortField = "created_at"
+	SortByType      PackageSortField = "type"
+)
+
+// PackageSort is the order of a page of packages.
+type PackageSort = Sort[PackageSortField]
+
+// DefaultPackageSort sorts packages by name, like a file browser.
+var DefaultPackageSort = PackageSort{Field: SortByName, Order: Ascending}
+
+var packageSortFields = []sortField[PackageSortField]{
+	{SortByName, Ascending},
+	{SortBySize, Ascending},
+	{SortByCreatedAt, Ascending},
+	{SortByType, Ascending},
+}
+
+// ParsePackageSort returns the PackageSort named by field and order. Empty values are those of DefaultPackageSort.
+func ParsePackageSort(field string, order string) (PackageSort, error) {
+	return parseSort(field, order, DefaultPackageSort, packageSortFields)
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePackageSort(t *testing.T) {
+	for tName, tData := range map[string]struct {
+		field    string
+		order    string
+		expected PackageSort
+	}{
+		"defaults":         {"", "", DefaultPackageSort},
+		"field only":       {"size", "", PackageSort{Field: SortBySize, Order: Ascending}},
+		"order only":       {"", "desc", PackageSort{Field: SortByName, Order: Descending}},
+		"case insensitive": {"Created_At", "DESC", PackageSort{Field: SortByCreatedAt, Order: Descending}},
+		"type":             {"type", "asc", PackageSort{Field: SortByType, Order: Ascending}},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			sort, err := ParsePackageSort(tData.field, tData.order)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tData.expected, sort)
+			}
+		})
+	}
+	_, err := ParsePackageSort("owner_id", "")
+	assert.ErrorContains(t, err, "owner_id")
+	assert.ErrorContains(t, err, "must be one of name, size, created_at or type")
+	_, err = ParsePackageSort("name", "sideways")
+	assert.ErrorContains(t, err, "sideways")
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Paging selects a page of results, either by Offset or, if After is set, as the results that follow the item
+// encoded by the cursor After. Cursors are not affected by rows that are added or removed between pages the way
+// offsets are.
+type Paging[C any] struct {
+	Limit  int
+	Offset int
+	After  *C
+	// SkipTotal leaves out the total count of the results, which needs a scan of all of them.
+	SkipTotal bool
+}
+
+// CountTotal reports whether the total count of the results should be returned. It is never returned for a page
+// after a cursor, since only the results following the cursor would be counted.
+func (p Paging[C]) CountTotal() bool {
+	return p.After == nil && !p.SkipTotal
+}
+
+// TrashcanCursor is the position of a TrashcanItem in a TrashcanPage sorted by Field and Order. Only the value of
+// Field is set, along with the package id, which orders items that are equal in Field.
+type TrashcanCursor struct {
+	Field TrashcanSortField `json:"f"`
+	Order SortOrder         `json:"o"`
+	// Name is the name the package had before it was deleted, as returned by OriginalPackageName
+	Name      string     `json:"n,omitempty"`
+	Type      string     `json:"t,omitempty"`
+	DeletedAt *time.Time `json:"d,omitempty"`
+	Size      *int64     `json:"s,omitempty"`
+	Id        int64      `json:"i"`
+}
+
+// NewTrashcanCursor returns the cursor of a package with the given properties in a page sorted by sort.
+func NewTrashcanCursor(sort TrashcanSort, id int64, name string, packageType string, deletedAt time.Time, size *int64) TrashcanCursor {
+	cursor := TrashcanCursor{Field: sort.Field, Order: sort.Order, Id: id}
+	switch sort.Field {
+	case TrashcanSortByName:
+		cursor.Name = name
+	case TrashcanSortByType:
+		cursor.Type = packageType
+	case TrashcanSortByDeletedAt:
+		cursor.DeletedAt = &deletedAt
+	case TrashcanSortBySize:
+		cursor.Size = size
+	}
+	return cursor
+}
+
+// ParseTrashcanCursor decodes a cursor returned in TrashcanPage.NextCursor. The cursor must have been returned for
+// a page sorted by sort.
+func ParseTrashcanCursor(s string, sort TrashcanSort) (*TrashcanCursor, error) {
+	var cursor TrashcanCursor
+	if err := decodeCursor(s, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.Field != sort.Field || cursor.Order != sort.Order {
+		return nil, fmt.Errorf("cursor is for sort %s %s, not %s %s", cursor.Field, cursor.Order, sort.Field, sort.Order)
+	}
+	return &cursor, nil
+}
+
+// SharedDatasetsCursor is the position of a SharedDatasetItem in a SharedDatasetsPage, which is sorted by
+// UpdatedAt descending and then by Name and NodeId.
+type SharedDatasetsCursor struct {
+	UpdatedAt time.Time `json:"u"`
+	Name      string    `json:"n"`
+	NodeId    string    `json:"i"`
+}
+
+// ParseSharedDatasetsCursor decodes a cursor returned in SharedDatasetsPage.NextCursor.
+func ParseSharedDatasetsCursor(s string) (*SharedDatasetsCursor, error) {
+	var cursor SharedDatasetsCursor
+	if err := decodeCursor(s, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// EncodeCursor returns the opaque string clients pass back to get the next page.
+func EncodeCursor(cursor any) string {
+	// the cursor types only hold values that marshal without error
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, cursor any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(b, cursor)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid cursor %q", s)
+	}
+	return nil
+}
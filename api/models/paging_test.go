@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashcanCursorRoundTrip(t *testing.T) {
+	sort := TrashcanSort{Field: TrashcanSortByDeletedAt, Order: Descending}
+	deletedAt := time.Date(2023, 2, 3, 4, 5, 6, 7000, time.UTC)
+	cursor := NewTrashcanCursor(sort, 42, "scan.png", "Image", deletedAt, nil)
+	assert.Equal(t, TrashcanCursor{Field: TrashcanSortByDeletedAt, Order: Descending, DeletedAt: &deletedAt, Id: 42}, cursor)
+
+	parsed, err := ParseTrashcanCursor(EncodeCursor(cursor), sort)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &cursor, parsed)
+	}
+
+	_, err = ParseTrashcanCursor(EncodeCursor(cursor), DefaultTrashcanSort)
+	assert.ErrorContains(t, err, "cursor is for sort deleted_at desc")
+}
+
+func TestSharedDatasetsCursorRoundTrip(t *testing.T) {
+	cursor := SharedDatasetsCursor{UpdatedAt: time.Date(2023, 1, 2, 13, 0, 0, 0, time.UTC), Name: "Beta Dataset 2", NodeId: "N:dataset:beta2"}
+	parsed, err := ParseSharedDatasetsCursor(EncodeCursor(cursor))
+	if assert.NoError(t, err) {
+		assert.Equal(t, &cursor, parsed)
+	}
+}
+
+func TestParseInvalidCursor(t *testing.T) {
+	for _, s := range []string{"not base64!", EncodeCursor("a string"), ""} {
+		_, err := ParseSharedDatasetsCursor(s)
+		assert.ErrorContains(t, err, "invalid cursor")
+	}
+}
+
+func TestPagingCountTotal(t *testing.T) {
+	assert.True(t, Paging[TrashcanCursor]{Limit: 10}.CountTotal())
+	assert.False(t, Paging[TrashcanCursor]{Limit: 10, SkipTotal: true}.CountTotal())
+	assert.False(t, Paging[TrashcanCursor]{Limit: 10, After: &TrashcanCursor{}}.CountTotal())
+}
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ROCrateMetadataFileName is the name RO-Crate consumers expect the metadata file to have.
+const ROCrateMetadataFileName = "ro-crate-metadata.json"
+
+// ROCrate is an RO-Crate 1.1 metadata document (https://w3id.org/ro/crate/1.1).
+type ROCrate struct {
+	Context string          `json:"@context"`
+	Graph   []ROCrateEntity `json:"@graph"`
+}
+
+// ROCrateEntity is one node of the RO-Crate graph. Only the properties used by dataset manifests are defined.
+type ROCrateEntity struct {
+	Id            string       `json:"@id"`
+	Type          string       `json:"@type"`
+	ConformsTo    *ROCrateRef  `json:"conformsTo,omitempty"`
+	About         *ROCrateRef  `json:"about,omitempty"`
+	Identifier    string       `json:"identifier,omitempty"`
+	Name          string       `json:"name,omitempty"`
+	Description   string       `json:"description,omitempty"`
+	License       string       `json:"license,omitempty"`
+	Keywords      []string     `json:"keywords,omitempty"`
+	DatePublished string       `json:"datePublished,omitempty"`
+	Author        []ROCrateRef `json:"author,omitempty"`
+	ContentSize   string       `json:"contentSize,omitempty"`
+	ContentUrl    string       `json:"contentUrl,omitempty"`
+	HasPart       []ROCrateRef `json:"hasPart,omitempty"`
+}
+
+// ROCrateRef links to another entity of the graph by its @id.
+type ROCrateRef struct {
+	Id string `json:"@id"`
+}
+
+// ROCrate returns the manifest as an RO-Crate: the dataset is the root Dataset entity, each contributor a Person
+// author, each collection a Dataset entity and each file a File entity identified by its SHA-256 checksum.
+// Collections and files are linked to their parents with hasPart.
+func (m WorkspaceManifest) ROCrate() ROCrate {
+	builder := newROCrateBuilder(m)
+	for _, c := range m.Collections {
+		builder.collection(c)
+	}
+	var files []ROCrateEntity
+	for _, file := range m.Files {
+		if entity, ok := builder.file(file); ok {
+			files = append(files, entity)
+		}
+	}
+	return ROCrate{Context: roCrateContext, Graph: append(builder.entities(), files...)}
+}
+
+const roCrateContext = "https://w3id.org/ro/crate/1.1/context"
+
+// roCrateBuilder builds the entities of an RO-Crate other than the files, which only need to be seen once.
+type roCrateBuilder struct {
+	root    ROCrateEntity
+	authors []ROCrateEntity
+	// collections are kept in the order they are first seen so that the graph is stable
+	collections     []*ROCrateEntity
+	collectionsById map[string]*ROCrateEntity
+	fileIds         map[string]bool
+}
+
+func newROCrateBuilder(m WorkspaceManifest) *roCrateBuilder {
+	b := &roCrateBuilder{
+		root: ROCrateEntity{
+			Id:            "./",
+			Type:          "Dataset",
+			Identifier:    m.DatasetNodeId,
+			Name:          m.Name,
+			Description:   m.Description,
+			License:       m.License,
+			Keywords:      m.Tags,
+			DatePublished: time.Time(m.Date).Format(time.RFC3339),
+		},
+		collectionsById: map[string]*ROCrateEntity{},
+		fileIds:         map[string]bool{},
+	}
+	for i, contributor := range m.Contributors {
+		author := ROCrateEntity{Id: fmt.Sprintf("#contributor-%d", i+1), Type: "Person", Name: contributor}
+		b.root.Author = append(b.root.Author, ROCrateRef{Id: author.Id})
+		b.authors = append(b.authors, author)
+	}
+	return b
+}
+
+// collection returns the Dataset entity of the collection at p, adding it and its parents if they are new.
+func (b *roCrateBuilder) collection(p string) *ROCrateEntity {
+	if p == "." || p == "/" || p == "" {
+		return &b.root
+	}
+	id := roCrateId(p) + "/"
+	if entity, ok := b.collectionsById[id]; ok {
+		return entity
+	}
+	parent := b.collection(path.Dir(p))
+	entity := &ROCrateEntity{Id: id, Type: "Dataset", Name: path.Base(p)}
+	parent.HasPart = append(parent.HasPart, ROCrateRef{Id: id})
+	b.collections = append(b.collections, entity)
+	b.collectionsById[id] = entity
+	return entity
+}
+
+// file returns the File entity of the file and links it to its collection. It returns false for packages
+// without a file and for files whose path has been seen already.
+func (b *roCrateBuilder) file(file ManifestDTO) (ROCrateEntity, bool) {
+	if !file.FileName.Valid {
+		return ROCrateEntity{}, false
+	}
+	filePath := path.Join(file.Path, file.FileName.String)
+	id := roCrateId(filePath)
+	if b.fileIds[id] {
+		return ROCrateEntity{}, false
+	}
+	b.fileIds[id] = true
+	entity := ROCrateEntity{Id: id, Type: "File", Name: file.FileName.String}
+	if file.Size.Valid {
+		entity.ContentSize = strconv.FormatInt(file.Size.Int64, 10)
+	}
+	if checksum, ok := sha256Checksum(file.CheckSum, file.Size); ok {
+		entity.Identifier = "sha256:" + checksum
+	}
+	entity.ContentUrl = file.Url
+	parent := b.collection(path.Dir(filePath))
+	parent.HasPart = append(parent.HasPart, ROCrateRef{Id: id})
+	return entity, true
+}
+
+// entities returns the metadata descriptor, the root, the authors and the collections.
+func (b *roCrateBuilder) entities() []ROCrateEntity {
+	graph := []ROCrateEntity{
+		{
+			Id:         ROCrateMetadataFileName,
+			Type:       "CreativeWork",
+			ConformsTo: &ROCrateRef{Id: "https://w3id.org/ro/crate/1.1"},
+			About:      &ROCrateRef{Id: b.root.Id},
+		},
+		b.root,
+	}
+	graph = append(graph, b.authors...)
+	for _, collection := range b.collections {
+		graph = append(graph, *collection)
+	}
+	return graph
+}
+
+// roCrateEncoder writes File entities as they come. The hasPart links of the collections are only complete
+// once every file has been seen, so the other entities are written by Close. Unlike the other formats, the
+// memory used grows with the number of files, by the @id of each.
+type roCrateEncoder struct {
+	w        io.Writer
+	builder  *roCrateBuilder
+	entities int
+}
+
+func newROCrateEncoder(w io.Writer, m WorkspaceManifest) *roCrateEncoder {
+	return &roCrateEncoder{w: w, builder: newROCrateBuilder(m)}
+}
+
+func (e *roCrateEncoder) WriteCollection(p string) error {
+	e.builder.collection(p)
+	return nil
+}
+
+func (e *roCrateEncoder) WriteFile(file ManifestDTO) error {
+	if entity, ok := e.builder.file(file); ok {
+		return e.writeEntity(entity)
+	}
+	return nil
+}
+
+func (e *roCrateEncoder) Close() error {
+	for _, entity := range e.builder.entities() {
+		if err := e.writeEntity(entity); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(e.w, "\n    ]\n}\n")
+	return err
+}
+
+func (e *roCrateEncoder) writeEntity(entity ROCrateEntity) error {
+	if e.entities == 0 {
+		context, err := json.Marshal(roCrateContext)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(e.w, "{\n    \"@context\": %s,\n    \"@graph\": [\n        ", context); err != nil {
+			return err
+		}
+	} else if _, err := io.WriteString(e.w, ",\n        "); err != nil {
+		return err
+	}
+	e.entities++
+	encoded, err := json.MarshalIndent(entity, "        ", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(encoded)
+	return err
+}
+
+// roCrateId returns the relative URI path used as the @id of the file or collection at p.
+func roCrateId(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
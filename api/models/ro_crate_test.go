@@ -0,0 +1,68 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestROCrate(t *testing.T) {
+	manifest := testWorkspaceManifest()
+	manifest.Collections = []string{"folder", "empty folder"}
+	manifest.Files = append(manifest.Files, ManifestDTO{
+		PackageNodeId: "N:package:3",
+		PackageName:   "b.txt",
+		FileName:      NullString{sql.NullString{String: "b.txt", Valid: true}},
+		Path:          "folder/sub",
+		Size:          NullInt{sql.NullInt64{Int64: 8, Valid: true}},
+		CheckSum:      NullString{sql.NullString{String: `{"checksum": "1f5b2ec688d2d64ea03176fea4e45e8f4206153053def5ce2111e8b18b1b36da", "chunkSize": 5242880}`, Valid: true}},
+	})
+
+	crate := manifest.ROCrate()
+	assert.Equal(t, "https://w3id.org/ro/crate/1.1/context", crate.Context)
+	assert.Equal(t, []ROCrateEntity{
+		{Id: "ro-crate-metadata.json", Type: "CreativeWork", ConformsTo: &ROCrateRef{Id: "https://w3id.org/ro/crate/1.1"}, About: &ROCrateRef{Id: "./"}},
+		{
+			Id:            "./",
+			Type:          "Dataset",
+			Identifier:    "N:dataset:1234",
+			Name:          "Test Dataset",
+			Description:   "first line\nsecond line",
+			License:       "N/A",
+			Keywords:      []string{"eeg"},
+			DatePublished: "2023-05-17T10:04:32Z",
+			Author:        []ROCrateRef{{Id: "#contributor-1"}, {Id: "#contributor-2"}},
+			HasPart:       []ROCrateRef{{Id: "folder/"}, {Id: "empty%20folder/"}},
+		},
+		{Id: "#contributor-1", Type: "Person", Name: "Ada"},
+		{Id: "#contributor-2", Type: "Person", Name: "Grace"},
+		{Id: "folder/", Type: "Dataset", Name: "folder", HasPart: []ROCrateRef{{Id: "folder/data.csv"}, {Id: "folder/sub/"}}},
+		{Id: "empty%20folder/", Type: "Dataset", Name: "empty folder"},
+		{Id: "folder/sub/", Type: "Dataset", Name: "sub", HasPart: []ROCrateRef{{Id: "folder/sub/b.txt"}}},
+		// the checksum of data.csv is not a SHA-256 digest
+		{Id: "folder/data.csv", Type: "File", Name: "data.csv", ContentSize: "42"},
+		{Id: "folder/sub/b.txt", Type: "File", Name: "b.txt", ContentSize: "8", Identifier: "sha256:1f5b2ec688d2d64ea03176fea4e45e8f4206153053def5ce2111e8b18b1b36da"},
+	}, crate.Graph)
+}
+
+func TestEncodeROCrate(t *testing.T) {
+	var buf bytes.Buffer
+	if assert.NoError(t, testWorkspaceManifest().Encode(&buf, ROCrateManifest)) {
+		var decoded map[string]any
+		if assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded)) {
+			assert.Contains(t, decoded, "@context")
+			assert.Len(t, decoded["@graph"], 6)
+		}
+		var crate ROCrate
+		if assert.NoError(t, json.Unmarshal(buf.Bytes(), &crate)) {
+			expected := testWorkspaceManifest().ROCrate()
+			// the streamed graph has the files first
+			assert.ElementsMatch(t, expected.Graph, crate.Graph)
+		}
+	}
+	assert.Equal(t, "ro-crate-metadata.json", ROCrateManifest.Extension())
+	assert.Equal(t, "application/ld+json", ROCrateManifest.ContentType())
+}
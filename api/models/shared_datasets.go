@@ -4,10 +4,13 @@ import "time"
 
 // SharedDatasetsPage represents a paginated response of shared datasets
 type SharedDatasetsPage struct {
-	Limit      int                   `json:"limit"`
-	Offset     int                   `json:"offset"`
-	TotalCount int                   `json:"totalCount"`
-	Datasets   []SharedDatasetItem   `json:"datasets"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	// TotalCount is left out of pages after a cursor, or if it was not requested
+	TotalCount *int                `json:"totalCount,omitempty"`
+	Datasets   []SharedDatasetItem `json:"datasets"`
+	// NextCursor gets the page after this one. It is left out of the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // SharedDatasetItem represents a single shared dataset in the response
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type SortOrder string
+
+const (
+	Ascending  SortOrder = "asc"
+	Descending SortOrder = "desc"
+)
+
+// Sort is the order of a page of packages or trashcan items. Items that are equal in Field are ordered by id.
+type Sort[F ~string] struct {
+	Field F
+	Order SortOrder
+}
+
+// sortField is a field a Sort can use, and the order it sorts in if none is given.
+type sortField[F ~string] struct {
+	field        F
+	defaultOrder SortOrder
+}
+
+// parseSort returns the Sort named by field and order. The field must be one of fields, and an empty field is that
+// of defaultSort. An empty order is the default order of the field, or that of defaultSort for an empty field.
+func parseSort[F ~string](field string, order string, defaultSort Sort[F], fields []sortField[F]) (Sort[F], error) {
+	sort := defaultSort
+	if len(field) > 0 {
+		f := F(strings.ToLower(field))
+		i := slices.IndexFunc(fields, func(s sortField[F]) bool { return s.field == f })
+		if i < 0 {
+			return sort, fmt.Errorf("unsupported sort field %q: must be one of %s", field, sortFieldNames(fields))
+		}
+		sort = Sort[F]{Field: f, Order: fields[i].defaultOrder}
+	}
+	switch o := SortOrder(strings.ToLower(order)); o {
+	case "":
+	case Ascending, Descending:
+		sort.Order = o
+	default:
+		return sort, fmt.Errorf("unsupported sort order %q: must be %s or %s", order, Ascending, Descending)
+	}
+	return sort, nil
+}
+
+// sortFieldNames lists the names of fields as "a, b or c".
+func sortFieldNames[F ~string](fields []sortField[F]) string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = string(f.field)
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
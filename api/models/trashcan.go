@@ -1,11 +1,21 @@
 package models
 
+import (
+	"fmt"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/packageInfo/packageState"
+	"strings"
+	"time"
+)
+
 type TrashcanPage struct {
-	Limit      int            `json:"limit"`
-	Offset     int            `json:"offset"`
-	TotalCount int            `json:"totalCount"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	// TotalCount is left out of pages after a cursor, or if it was not requested
+	TotalCount *int           `json:"totalCount,omitempty"`
 	Packages   []TrashcanItem `json:"packages"`
 	Messages   []string       `json:"messages"`
+	// NextCursor gets the page after this one. It is left out of the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 type TrashcanItem struct {
@@ -14,4 +24,157 @@ type TrashcanItem struct {
 	NodeId string `json:"node_id"`
 	Type   string `json:"type"`
 	State  string `json:"state"`
+	Size   *int64 `json:"size"`
+	// DeletedAt is the package's updated_at, which is set when it is deleted. It is left out for live folders that
+	// are only in the trashcan because there are deleted packages below them.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Owner is the package owner. It is left out if the owner's account no longer exists.
+	Owner *TrashcanUser `json:"owner,omitempty"`
+	// ParentPath is the names of the folders the package was in, separated by '/', or empty at the dataset root
+	ParentPath string `json:"parent_path"`
+}
+
+type TrashcanUser struct {
+	NodeId string `json:"node_id"`
+	Name   string `json:"name"`
+}
+
+// DeletedNamePrefix is prepended, along with the package node id and an underscore, to the name of a package when
+// it is deleted.
+const DeletedNamePrefix = "__DELETED__"
+
+// OriginalPackageName strips the prefix added to the name of the package with the given node id when it was deleted,
+// if present.
+func OriginalPackageName(name string, nodeId string) string {
+	return strings.TrimPrefix(name, DeletedNamePrefix+nodeId+"_")
+}
+
+// TrashcanFilter limits the items of a TrashcanPage. Zero values do not filter.
+type TrashcanFilter struct {
+	// Name matches package names case-insensitively anywhere in the name, or only at its start if NamePrefix is set
+	Name       string
+	NamePrefix bool
+	// Type matches package types case-insensitively
+	Type string
+	// State is packageState.Deleted or packageState.Deleting
+	State string
+	// DeletedAfter and DeletedBefore limit the deletion time, which is taken from the package's updated_at
+	DeletedAfter  time.Time
+	DeletedBefore time.Time
+	// Flat lists the deleted packages at any depth below the folder instead of the items at the folder's level
+	Flat bool
+}
+
+// ParseTrashcanState returns the package state named by s if it is a state of packages in the trashcan.
+func ParseTrashcanState(s string) (string, error) {
+	switch state := strings.ToUpper(s); state {
+	case packageState.Deleted.String(), packageState.Deleting.String():
+		return state, nil
+	default:
+		return "", fmt.Errorf("unsupported trashcan state %q: must be %s or %s", s, packageState.Deleted, packageState.Deleting)
+	}
+}
+
+// TrashcanSortField is a property trashcan items can be sorted by.
+type TrashcanSortField string
+
+const (
+	TrashcanSortByName      TrashcanSortField = "name"
+	TrashcanSortByDeletedAt TrashcanSortField = "deleted_at"
+	TrashcanSortBySize      TrashcanSortField = "size"
+	TrashcanSortByType      TrashcanSortField = "type"
+)
+
+// TrashcanSort is the order of a TrashcanPage.
+type TrashcanSort = Sort[TrashcanSortField]
+
+// DefaultTrashcanSort sorts trashcan items by name, like a file browser.
+var DefaultTrashcanSort = TrashcanSort{Field: TrashcanSortByName, Order: Ascending}
+
+// trashcanSortFields sorts by deletion time descending by default, so that the most recently deleted items come first
+// as in a recycle bin.
+var trashcanSortFields = []sortField[TrashcanSortField]{
+	{TrashcanSortByName, Ascending},
+	{TrashcanSortByDeletedAt, Descending},
+	{TrashcanSortBySize, Ascending},
+	{TrashcanSortByType, Ascending},
+}
+
+// ParseTrashcanSort returns the TrashcanSort named by field and order. An empty field is that of
+// DefaultTrashcanSort. An empty order is descending for TrashcanSortByDeletedAt and ascending otherwise.
+func ParseTrashcanSort(field string, order string) (TrashcanSort, error) {
+	return parseSort(field, order, DefaultTrashcanSort, trashcanSortFields)
+}
+
+// TrashcanExport is the report of a whole trashcan written by an export. Its items are the deleted packages of the
+// dataset at any depth, including inside deleted folders, and the folders leading to them, nested as in the dataset.
+type TrashcanExport struct {
+	DatasetNodeId string    `json:"dataset_node_id"`
+	ExportedAt    time.Time `json:"exported_at"`
+	// DeletedCount is the number of DELETED or DELETING items in the report
+	DeletedCount int                   `json:"deleted_count"`
+	Items        []*TrashcanExportItem `json:"items"`
+}
+
+type TrashcanExportItem struct {
+	NodeId string `json:"node_id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	State  string `json:"state"`
+	// Path is the names from the dataset root down to and including the item, separated by '/'
+	Path string `json:"path"`
+	Size *int64 `json:"size"`
+	// Deleted is false for folders that are only in the report because there are deleted items below them.
+	// DeletedAt is only set for deleted items, as in TrashcanItem.
+	Deleted   bool                  `json:"deleted"`
+	DeletedAt *time.Time            `json:"deleted_at,omitempty"`
+	Owner     *TrashcanUser         `json:"owner,omitempty"`
+	Children  []*TrashcanExportItem `json:"children,omitempty"`
+}
+
+// TrashcanExportResult locates a TrashcanExport written to S3.
+type TrashcanExportResult struct {
+	Url          string `json:"url"`
+	S3Bucket     string `json:"s3_bucket"`
+	S3Key        string `json:"s3_key"`
+	DeletedCount int    `json:"deleted_count"`
+}
+
+type RestoreRequest struct {
+	NodeIds []string `json:"nodeIds"`
+}
+
+// RestoreResponse reports the outcome of each requested node id separately. Restoring one item
+// can fail without affecting the others.
+type RestoreResponse struct {
+	Success  []RestoredItem   `json:"success"`
+	Failures []RestoreFailure `json:"failures"`
+}
+
+type RestoredItem struct {
+	NodeId string `json:"node_id"`
+	Name   string `json:"name"`
+}
+
+type RestoreFailure struct {
+	NodeId string `json:"node_id"`
+	Error  string `json:"error"`
+}
+
+type TrashcanPurgeResult struct {
+	PackageCount int `json:"packageCount"`
+}
+
+// TrashcanSummary totals the trashcan, or the part of it below a folder. It covers everything purging the trashcan
+// would delete, including the contents of deleted folders.
+type TrashcanSummary struct {
+	// PackageCount is the number of packages, including folders
+	PackageCount int `json:"packageCount"`
+	FolderCount  int `json:"folderCount"`
+	// FileCount is the number of stored files, of which a package can have several
+	FileCount int `json:"fileCount"`
+	// TotalSize is the sum of the sizes of the files in bytes
+	TotalSize       int64      `json:"totalSize"`
+	OldestDeletedAt *time.Time `json:"oldestDeletedAt"`
+	NewestDeletedAt *time.Time `json:"newestDeletedAt"`
 }
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTrashcanSort(t *testing.T) {
+	for tName, tData := range map[string]struct {
+		field    string
+		order    string
+		expected TrashcanSort
+	}{
+		"defaults":                {"", "", DefaultTrashcanSort},
+		"field only":              {"size", "", TrashcanSort{Field: TrashcanSortBySize, Order: Ascending}},
+		"order only":              {"", "desc", TrashcanSort{Field: TrashcanSortByName, Order: Descending}},
+		"deleted_at newest first": {"deleted_at", "", TrashcanSort{Field: TrashcanSortByDeletedAt, Order: Descending}},
+		"deleted_at oldest first": {"Deleted_At", "ASC", TrashcanSort{Field: TrashcanSortByDeletedAt, Order: Ascending}},
+		"type":                    {"type", "desc", TrashcanSort{Field: TrashcanSortByType, Order: Descending}},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			sort, err := ParseTrashcanSort(tData.field, tData.order)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tData.expected, sort)
+			}
+		})
+	}
+	_, err := ParseTrashcanSort("created_at", "")
+	assert.ErrorContains(t, err, "created_at")
+	_, err = ParseTrashcanSort("name", "sideways")
+	assert.ErrorContains(t, err, "sideways")
+}
+
+func TestOriginalPackageName(t *testing.T) {
+	assert.Equal(t, "data.csv", OriginalPackageName("__DELETED__N:package:1_data.csv", "N:package:1"))
+	assert.Equal(t, "data.csv", OriginalPackageName("data.csv", "N:package:1"))
+	// only the prefix for the package's own node id is stripped
+	assert.Equal(t, "__DELETED__N:package:2_data.csv", OriginalPackageName("__DELETED__N:package:2_data.csv", "N:package:1"))
+}
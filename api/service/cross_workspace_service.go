@@ -9,7 +9,7 @@ import (
 
 // CrossWorkspaceDatasetsService provides methods for operations that span multiple workspaces
 type CrossWorkspaceDatasetsService interface {
-	GetSharedDatasetsPage(ctx context.Context, userId int, limit int, offset int) (*models.SharedDatasetsPage, error)
+	GetSharedDatasetsPage(ctx context.Context, userId int, paging models.Paging[models.SharedDatasetsCursor]) (*models.SharedDatasetsPage, error)
 }
 
 // crossWorkspaceDatasetsService implements CrossWorkspaceDatasetsService
@@ -28,8 +28,8 @@ func NewCrossWorkspaceDatasetsService(db *sql.DB) CrossWorkspaceDatasetsService
 
 // GetSharedDatasetsPage returns a paginated list of datasets shared with the user
 // from workspaces where the user is not a contributor within the workspace.
-func (s *crossWorkspaceDatasetsService) GetSharedDatasetsPage(ctx context.Context, userId int, limit int, offset int) (*models.SharedDatasetsPage, error) {
+func (s *crossWorkspaceDatasetsService) GetSharedDatasetsPage(ctx context.Context, userId int, paging models.Paging[models.SharedDatasetsCursor]) (*models.SharedDatasetsPage, error) {
 	// Use the cross-org store to fetch shared datasets
 	crossOrgStore := s.CrossOrgStoreFactory.NewCrossOrgStore()
-	return crossOrgStore.GetSharedDatasetsForUser(ctx, userId, limit, offset)
+	return crossOrgStore.GetSharedDatasetsForUser(ctx, userId, paging)
 }
\ No newline at end of file
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/pennsieve/datasets-service/api/models"
 	"github.com/pennsieve/datasets-service/api/store"
 	"github.com/stretchr/testify/assert"
 )
@@ -94,13 +95,13 @@ func TestGetSharedDatasetsPage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			page, err := service.GetSharedDatasetsPage(context.Background(), tt.userId, tt.limit, tt.offset)
+			page, err := service.GetSharedDatasetsPage(context.Background(), tt.userId, models.Paging[models.SharedDatasetsCursor]{Limit: tt.limit, Offset: tt.offset})
 			
 			assert.NoError(t, err)
 			assert.NotNil(t, page)
 			assert.Equal(t, tt.limit, page.Limit)
 			assert.Equal(t, tt.offset, page.Offset)
-			assert.Equal(t, tt.expectedTotal, page.TotalCount)
+			assert.Equal(t, &tt.expectedTotal, page.TotalCount)
 			assert.Len(t, page.Datasets, tt.expectedCount)
 
 			if len(tt.expectedNames) > 0 {
@@ -153,24 +154,67 @@ func TestGetSharedDatasetsPageEdgeCases(t *testing.T) {
 	service := NewCrossWorkspaceDatasetsService(db.DB)
 
 	t.Run("negative limit should be handled gracefully", func(t *testing.T) {
-		page, err := service.GetSharedDatasetsPage(context.Background(), 9001, -1, 0)
+		page, err := service.GetSharedDatasetsPage(context.Background(), 9001, models.Paging[models.SharedDatasetsCursor]{Limit: -1})
 		// The service should handle this gracefully, likely treating it as 0 or a default value
 		assert.NoError(t, err)
 		assert.NotNil(t, page)
 	})
 
 	t.Run("negative offset should be handled gracefully", func(t *testing.T) {
-		page, err := service.GetSharedDatasetsPage(context.Background(), 9001, 10, -1)
+		page, err := service.GetSharedDatasetsPage(context.Background(), 9001, models.Paging[models.SharedDatasetsCursor]{Limit: 10, Offset: -1})
 		// The service should handle this gracefully, likely treating it as 0
 		assert.NoError(t, err)
 		assert.NotNil(t, page)
 	})
 
 	t.Run("large offset beyond results", func(t *testing.T) {
-		page, err := service.GetSharedDatasetsPage(context.Background(), 9001, 10, 1000)
+		page, err := service.GetSharedDatasetsPage(context.Background(), 9001, models.Paging[models.SharedDatasetsCursor]{Limit: 10, Offset: 1000})
 		assert.NoError(t, err)
 		assert.NotNil(t, page)
-		assert.Equal(t, 4, page.TotalCount) // Still knows total count
+		assert.Equal(t, 4, *page.TotalCount) // Still knows total count
 		assert.Len(t, page.Datasets, 0)     // But no results in this page
 	})
-}
\ No newline at end of file
+}
+
+func TestGetSharedDatasetsPageCursor(t *testing.T) {
+	db := store.OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("shared-datasets-test.sql")
+	defer func() {
+		db.Truncate(100, "dataset_user")
+		db.Truncate(100, "datasets")
+		db.Truncate(101, "dataset_user")
+		db.Truncate(101, "datasets")
+		db.TruncatePennsieve("organization_user")
+		db.TruncatePennsieve("organizations")
+		db.TruncatePennsieve("users")
+	}()
+
+	service := NewCrossWorkspaceDatasetsService(db.DB)
+
+	var actualNames []string
+	paging := models.Paging[models.SharedDatasetsCursor]{Limit: 3}
+	for pages := 0; pages < 3; pages++ {
+		page, err := service.GetSharedDatasetsPage(context.Background(), 9001, paging)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if pages == 0 {
+			assert.Equal(t, 4, *page.TotalCount)
+		} else {
+			assert.Nil(t, page.TotalCount)
+		}
+		for _, dataset := range page.Datasets {
+			actualNames = append(actualNames, dataset.Content.Name)
+		}
+		if len(page.NextCursor) == 0 {
+			break
+		}
+		paging.After, err = models.ParseSharedDatasetsCursor(page.NextCursor)
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+	assert.Equal(t, []string{"Beta Dataset 2", "Beta Dataset 1", "Alpha Dataset 2", "Alpha Dataset 1"}, actualNames)
+}
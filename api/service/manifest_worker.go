@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/datasets-service/api/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultManifestMaxAttempts = 3
+	defaultManifestRetryDelay  = 2 * time.Second
+)
+
+// ManifestWorker processes the manifest jobs published by TriggerAsyncGetManifest.
+type ManifestWorker struct {
+	StoreFactory    store.DatasetsStoreFactory
+	S3StoreFactory  store.S3StoreFactory
+	SnsStoreFactory store.SnsStoreFactory
+	Options         *models.HandlerVars
+	// MaxAttempts is the number of times a job is tried before it is marked FAILED
+	MaxAttempts int
+	// RetryDelay is multiplied by the attempt number to get the wait before the next attempt
+	RetryDelay time.Duration
+}
+
+func NewManifestWorkerWithFactory(factory store.DatasetsStoreFactory, s3factory store.S3StoreFactory, snsFactory store.SnsStoreFactory, options *models.HandlerVars) *ManifestWorker {
+	return &ManifestWorker{
+		StoreFactory:    factory,
+		S3StoreFactory:  s3factory,
+		SnsStoreFactory: snsFactory,
+		Options:         options,
+		MaxAttempts:     defaultManifestMaxAttempts,
+		RetryDelay:      defaultManifestRetryDelay,
+	}
+}
+
+func NewManifestWorker(db *sql.DB, s3Client *s3.Client, snsClient models.SnsAPI, options *models.HandlerVars) *ManifestWorker {
+	return NewManifestWorkerWithFactory(store.NewPostgresStoreFactory(db), store.NewS3StoreFactory(s3Client), store.NewSnsStoreFactory(snsClient), options)
+}
+
+// HandleMessage processes the manifest job in an SNS message body. Poison messages, ones that can never succeed
+// such as an undecodable body or a job for a dataset that no longer exists, are logged, their job marked
+// FAILED if possible, and dropped by returning nil. Other errors are retried up to MaxAttempts times. If the
+// job still fails it is marked FAILED and the last error is returned so that the message ends up in the
+// dead letter queue.
+func (w *ManifestWorker) HandleMessage(ctx context.Context, message string) error {
+	var input models.ManifestWorkerInput
+	if err := json.Unmarshal([]byte(message), &input); err != nil {
+		log.Errorf("dropping manifest message that could not be decoded: %v: %q", err, message)
+		return nil
+	}
+	logger := log.WithFields(log.Fields{
+		"orgId":         input.OrgIntId,
+		"datasetNodeId": input.DatasetNodeId,
+		"jobId":         input.JobId,
+	})
+	if len(input.JobId) == 0 {
+		logger.Errorf("dropping manifest message without a job id: %q", message)
+		return nil
+	}
+
+	srv := NewDatasetsServiceWithFactory(w.StoreFactory, w.S3StoreFactory, w.SnsStoreFactory, w.Options, input.OrgIntId)
+	if err := validateManifestWorkerInput(input); err != nil {
+		logger.Errorf("dropping invalid manifest message: %v", err)
+		w.failJob(ctx, logger, srv, input.JobId, err)
+		return nil
+	}
+
+	var err error
+	for attempt := 1; attempt <= w.MaxAttempts; attempt++ {
+		if err = srv.ProcessManifestJob(ctx, input); err == nil {
+			logger.Info("manifest job done")
+			return nil
+		}
+		if isPermanentManifestError(err) {
+			logger.Errorf("dropping manifest job that cannot succeed: %v", err)
+			w.failJob(ctx, logger, srv, input.JobId, err)
+			return nil
+		}
+		logger.Warnf("manifest job attempt %d of %d failed: %v", attempt, w.MaxAttempts, err)
+		if attempt < w.MaxAttempts {
+			if waitErr := wait(ctx, time.Duration(attempt)*w.RetryDelay); waitErr != nil {
+				break
+			}
+		}
+	}
+	w.failJob(ctx, logger, srv, input.JobId, err)
+	return err
+}
+
+// failJob marks the job FAILED. Errors are only logged since the job may be the reason the message failed.
+func (w *ManifestWorker) failJob(ctx context.Context, logger *log.Entry, srv DatasetsService, jobId string, cause error) {
+	if err := srv.FailManifestJob(ctx, jobId, cause.Error()); err != nil {
+		logger.Errorf("unable to mark manifest job as failed: %v", err)
+	}
+}
+
+// wait blocks for d or until ctx is done, in which case it returns the context's error.
+func wait(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+func validateManifestWorkerInput(input models.ManifestWorkerInput) error {
+	switch {
+	case input.OrgIntId <= 0:
+		return fmt.Errorf("invalid org id %d", input.OrgIntId)
+	case len(input.DatasetNodeId) == 0:
+		return errors.New("missing dataset node id")
+	case len(input.ManifestS3Key) == 0:
+		return errors.New("missing manifest S3 key")
+	}
+	return nil
+}
+
+// isPermanentManifestError returns true for errors that retrying will not fix.
+func isPermanentManifestError(err error) bool {
+	switch err.(type) {
+	case models.DatasetNotFoundError, models.ManifestJobNotFoundError, models.PackageNotFoundError, models.FolderNotFoundError:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestManifestWorker(mockStore *MockDatasetsStore, mockS3Store *MockS3Store) (*ManifestWorker, *MockFactory) {
+	mockFactory := MockFactory{mockStore, -1}
+	worker := NewManifestWorkerWithFactory(&mockFactory, &MockS3Factory{mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{S3Bucket: "manifest-bucket"})
+	worker.RetryDelay = 0
+	return worker, &mockFactory
+}
+
+func manifestWorkerMessage(t *testing.T, input models.ManifestWorkerInput) string {
+	message, err := json.Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(message)
+}
+
+func TestManifestWorkerHandleMessage(t *testing.T) {
+	input := models.ManifestWorkerInput{OrgIntId: 7, DatasetNodeId: "N:dataset:1234", JobId: "job-1", ManifestS3Key: "1234/a.json"}
+	mockStore := MockDatasetsStore{GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}}}
+	mockS3Store := MockS3Store{
+		Jobs:                map[string]models.ManifestJob{"job-1": {JobId: "job-1", Status: models.CREATING}},
+		WriteManifestErrors: []error{errors.New("transient S3 error")},
+	}
+	worker, mockFactory := newTestManifestWorker(&mockStore, &mockS3Store)
+
+	err := worker.HandleMessage(context.Background(), manifestWorkerMessage(t, input))
+	if assert.NoError(t, err) {
+		assert.Equal(t, input.OrgIntId, mockFactory.orgId)
+		assert.Contains(t, mockS3Store.Manifests, input.ManifestS3Key)
+		assert.Equal(t, models.READY, mockS3Store.Jobs["job-1"].Status)
+	}
+}
+
+func TestManifestWorkerRetriesExhausted(t *testing.T) {
+	input := models.ManifestWorkerInput{OrgIntId: 7, DatasetNodeId: "N:dataset:1234", JobId: "job-1", ManifestS3Key: "1234/a.json"}
+	s3Err := errors.New("transient S3 error")
+	mockStore := MockDatasetsStore{GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}}}
+	mockS3Store := MockS3Store{
+		Jobs:                map[string]models.ManifestJob{"job-1": {JobId: "job-1", Status: models.CREATING}},
+		WriteManifestErrors: []error{s3Err, s3Err, s3Err},
+	}
+	worker, _ := newTestManifestWorker(&mockStore, &mockS3Store)
+
+	err := worker.HandleMessage(context.Background(), manifestWorkerMessage(t, input))
+	assert.Equal(t, s3Err, err)
+	assert.Empty(t, mockS3Store.WriteManifestErrors, "expected one attempt per queued error")
+	assert.Empty(t, mockS3Store.Manifests)
+	assert.Equal(t, models.FAILED, mockS3Store.Jobs["job-1"].Status)
+	assert.Equal(t, s3Err.Error(), mockS3Store.Jobs["job-1"].Error)
+}
+
+func TestManifestWorkerPoisonMessages(t *testing.T) {
+	valid := models.ManifestWorkerInput{OrgIntId: 7, DatasetNodeId: "N:dataset:1234", JobId: "job-1", ManifestS3Key: "1234/a.json"}
+	for tName, tData := range map[string]struct {
+		message         string
+		mockStore       MockDatasetsStore
+		expectedJobFail bool
+	}{
+		"undecodable body": {
+			message: "{not json",
+		},
+		"missing job id": {
+			message: manifestWorkerMessage(t, models.ManifestWorkerInput{OrgIntId: 7, DatasetNodeId: "N:dataset:1234", ManifestS3Key: "1234/a.json"}),
+		},
+		"missing dataset": {
+			message:         manifestWorkerMessage(t, models.ManifestWorkerInput{OrgIntId: 7, JobId: "job-1", ManifestS3Key: "1234/a.json"}),
+			expectedJobFail: true,
+		},
+		"dataset not found": {
+			message:         manifestWorkerMessage(t, valid),
+			mockStore:       MockDatasetsStore{GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Error: models.DatasetNotFoundError{OrgId: 7, Id: models.DatasetNodeId("N:dataset:1234")}}},
+			expectedJobFail: true,
+		},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			mockS3Store := MockS3Store{Jobs: map[string]models.ManifestJob{"job-1": {JobId: "job-1", Status: models.CREATING}}}
+			worker, _ := newTestManifestWorker(&tData.mockStore, &mockS3Store)
+
+			err := worker.HandleMessage(context.Background(), tData.message)
+			if assert.NoError(t, err) {
+				assert.Empty(t, mockS3Store.Manifests)
+				if tData.expectedJobFail {
+					assert.Equal(t, models.FAILED, mockS3Store.Jobs["job-1"].Status)
+					assert.NotEmpty(t, mockS3Store.Jobs["job-1"].Error)
+				} else {
+					assert.Equal(t, models.CREATING, mockS3Store.Jobs["job-1"].Status)
+				}
+			}
+		})
+	}
+}
+
+func TestManifestWorkerMissingJob(t *testing.T) {
+	input := models.ManifestWorkerInput{OrgIntId: 7, DatasetNodeId: "N:dataset:1234", JobId: "job-1", ManifestS3Key: "1234/a.json"}
+	mockS3Store := MockS3Store{}
+	worker, _ := newTestManifestWorker(&MockDatasetsStore{}, &mockS3Store)
+
+	// a job that does not exist cannot be retried into existence
+	assert.NoError(t, worker.HandleMessage(context.Background(), manifestWorkerMessage(t, input)))
+	assert.Empty(t, mockS3Store.Manifests)
+}
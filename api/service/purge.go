@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/datasets-service/api/store"
+)
+
+// purgeBatchSize is the maximum number of package ids sent in a single purge event, keeping each
+// message well under the SNS size limit.
+const purgeBatchSize = 1000
+
+// PurgeTrashcan permanently deletes the contents of a dataset's trashcan, or only the part below the
+// folder rootNodeId if it is not empty. The packages are marked for deletion in the database and then
+// handed to the purge worker through purge events, which removes their files from storage.
+func (s *datasetsService) PurgeTrashcan(ctx context.Context, datasetNodeId string, rootNodeId string) (*models.TrashcanPurgeResult, error) {
+	var datasetId int64
+	var purgedIds []int64
+	err := s.StoreFactory.ExecStoreTx(ctx, s.OrgId, func(q store.DatasetsStore) error {
+		dataset, err := q.GetDatasetByNodeId(ctx, datasetNodeId)
+		if err != nil {
+			return err
+		}
+		datasetId = dataset.Id
+		rootPckg, err := s.getRootCollection(ctx, q, datasetNodeId, dataset.Id, rootNodeId)
+		if err != nil {
+			return err
+		}
+		var rootId sql.NullInt64
+		if rootPckg != nil {
+			rootId = sql.NullInt64{Int64: rootPckg.Id, Valid: true}
+		}
+		purgedIds, err = q.MarkTrashForPurge(ctx, dataset.Id, rootId)
+		if err != nil || len(purgedIds) == 0 {
+			return err
+		}
+		return q.TouchDataset(ctx, dataset.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Only publish once the transaction has committed, so the worker never removes objects
+	// of packages whose purge was rolled back. If publishing fails, a retried purge marks
+	// and publishes the same packages again.
+	sns := s.SnsStoreFactory.NewSimpleStore(s.PurgeSnsTopic)
+	for start := 0; start < len(purgedIds); start += purgeBatchSize {
+		end := min(start+purgeBatchSize, len(purgedIds))
+		if err := sns.TriggerPurgeWorker(ctx, models.PurgeWorkerInput{
+			OrgIntId:      s.OrgId,
+			DatasetIntId:  datasetId,
+			DatasetNodeId: datasetNodeId,
+			PackageIds:    purgedIds[start:end],
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return &models.TrashcanPurgeResult{PackageCount: len(purgedIds)}, nil
+}
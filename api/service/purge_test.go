@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/packageInfo/packageType"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeTrashcan(t *testing.T) {
+	orgId := 7
+	datasetNodeId := "N:dataset:1234"
+	purgedIds := make([]int64, purgeBatchSize+5)
+	for i := range purgedIds {
+		purgedIds[i] = int64(i + 1)
+	}
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		MarkTrashForPurgeReturn:  MockReturn[[]int64]{Value: purgedIds},
+	}
+	mockSnsStore := MockSnsStore{}
+	mockFactory := MockFactory{&mockStore, -1}
+	service := NewDatasetsServiceWithFactory(&mockFactory, &MockS3Factory{}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{PurgeSnsTopic: "purge-topic"}, orgId)
+
+	result, err := service.PurgeTrashcan(context.Background(), datasetNodeId, "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, orgId, mockFactory.orgId)
+		assert.Equal(t, len(purgedIds), result.PackageCount)
+		assert.Equal(t, []int64{13}, mockStore.TouchedDatasets)
+		if assert.Len(t, mockSnsStore.PurgeInputs, 2) {
+			assert.Equal(t, models.PurgeWorkerInput{OrgIntId: orgId, DatasetIntId: 13, DatasetNodeId: datasetNodeId, PackageIds: purgedIds[:purgeBatchSize]}, mockSnsStore.PurgeInputs[0])
+			assert.Equal(t, purgedIds[purgeBatchSize:], mockSnsStore.PurgeInputs[1].PackageIds)
+		}
+	}
+}
+
+func TestPurgeTrashcanEmpty(t *testing.T) {
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 57, PackageType: packageType.Collection}},
+	}
+	mockSnsStore := MockSnsStore{}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{}, 7)
+
+	result, err := service.PurgeTrashcan(context.Background(), "N:dataset:1234", "N:collection:57")
+	if assert.NoError(t, err) {
+		assert.Zero(t, result.PackageCount)
+		assert.Empty(t, mockSnsStore.PurgeInputs)
+		assert.Empty(t, mockStore.TouchedDatasets)
+	}
+}
+
+func TestPurgeTrashcanErrors(t *testing.T) {
+	orgId := 7
+	for tName, tData := range map[string]struct {
+		rootNodeId    string
+		mockStore     MockDatasetsStore
+		mockSnsStore  MockSnsStore
+		expectedError error
+	}{
+		"dataset not found error": {
+			rootNodeId:    "",
+			mockStore:     MockDatasetsStore{GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Error: models.DatasetNotFoundError{OrgId: orgId, Id: models.DatasetNodeId("N:dataset:1234")}}},
+			expectedError: models.DatasetNotFoundError{OrgId: orgId, Id: models.DatasetNodeId("N:dataset:1234")},
+		},
+		"root not a folder": {
+			rootNodeId: "N:package:57",
+			mockStore: MockDatasetsStore{
+				GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+				GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 57, PackageType: packageType.CSV}},
+			},
+			expectedError: models.FolderNotFoundError{OrgId: orgId, NodeId: "N:package:57", DatasetId: models.DatasetNodeId("N:dataset:1234"), ActualType: packageType.CSV},
+		},
+		"unexpected mark error": {
+			mockStore: MockDatasetsStore{
+				GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+				MarkTrashForPurgeReturn:  MockReturn[[]int64]{Error: errors.New("unexpected mark error")},
+			},
+			expectedError: errors.New("unexpected mark error"),
+		},
+		"publish error": {
+			mockStore: MockDatasetsStore{
+				GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+				MarkTrashForPurgeReturn:  MockReturn[[]int64]{Value: []int64{1, 2}},
+			},
+			mockSnsStore:  MockSnsStore{PurgeError: errors.New("publish error")},
+			expectedError: errors.New("publish error"),
+		},
+	} {
+		tData := tData
+		service := NewDatasetsServiceWithFactory(&MockFactory{&tData.mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{&tData.mockSnsStore}, &models.HandlerVars{}, orgId)
+		t.Run(tName, func(t *testing.T) {
+			_, err := service.PurgeTrashcan(context.Background(), "N:dataset:1234", tData.rootNodeId)
+			assert.Equal(t, tData.expectedError, err)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/datasets-service/api/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// PurgeWorker processes the purge events published by PurgeTrashcan.
+type PurgeWorker struct {
+	StoreFactory   store.DatasetsStoreFactory
+	S3StoreFactory store.S3StoreFactory
+}
+
+func NewPurgeWorkerWithFactory(factory store.DatasetsStoreFactory, s3factory store.S3StoreFactory) *PurgeWorker {
+	return &PurgeWorker{StoreFactory: factory, S3StoreFactory: s3factory}
+}
+
+func NewPurgeWorker(db *sql.DB, s3Client *s3.Client) *PurgeWorker {
+	return NewPurgeWorkerWithFactory(store.NewPostgresStoreFactory(db), store.NewS3StoreFactory(s3Client))
+}
+
+// HandleMessage removes the files of the purged packages in an SNS message body from storage, and then their rows.
+// Messages that can never succeed, such as an undecodable body, are logged and dropped by returning nil. Removing
+// files is idempotent, so other errors are returned for the message to be retried.
+func (w *PurgeWorker) HandleMessage(ctx context.Context, message string) error {
+	var input models.PurgeWorkerInput
+	if err := json.Unmarshal([]byte(message), &input); err != nil {
+		log.Errorf("dropping purge message that could not be decoded: %v: %q", err, message)
+		return nil
+	}
+	logger := log.WithFields(log.Fields{
+		"orgId":         input.OrgIntId,
+		"datasetNodeId": input.DatasetNodeId,
+	})
+	if err := validatePurgeWorkerInput(input); err != nil {
+		logger.Errorf("dropping invalid purge message: %v", err)
+		return nil
+	}
+
+	q := w.StoreFactory.NewSimpleStore(input.OrgIntId)
+	files, err := q.GetPurgedFiles(ctx, input.DatasetIntId, input.PackageIds)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		logger.Infof("no files left for %d purged packages", len(input.PackageIds))
+		return nil
+	}
+	var buckets []string
+	keysByBucket := map[string][]string{}
+	fileIds := make([]int64, len(files))
+	for i, f := range files {
+		if _, ok := keysByBucket[f.S3Bucket]; !ok {
+			buckets = append(buckets, f.S3Bucket)
+		}
+		keysByBucket[f.S3Bucket] = append(keysByBucket[f.S3Bucket], f.S3Key)
+		fileIds[i] = f.Id
+	}
+	// Rows are only deleted once every object is gone, so a retried message finds the files that are left.
+	for _, bucket := range buckets {
+		if err := w.S3StoreFactory.NewSimpleStore(bucket).DeleteObjects(ctx, keysByBucket[bucket]); err != nil {
+			return err
+		}
+	}
+	if err := q.DeleteFiles(ctx, fileIds); err != nil {
+		return err
+	}
+	logger.Infof("removed %d files of %d purged packages", len(files), len(input.PackageIds))
+	return nil
+}
+
+func validatePurgeWorkerInput(input models.PurgeWorkerInput) error {
+	switch {
+	case input.OrgIntId <= 0:
+		return fmt.Errorf("invalid org id %d", input.OrgIntId)
+	case input.DatasetIntId <= 0:
+		return fmt.Errorf("invalid dataset id %d", input.DatasetIntId)
+	case len(input.PackageIds) == 0:
+		return errors.New("missing package ids")
+	}
+	return nil
+}
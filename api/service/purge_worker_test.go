@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pennsieve/datasets-service/api/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// bucketS3Factory returns the mock store of each bucket.
+type bucketS3Factory map[string]*MockS3Store
+
+func (f bucketS3Factory) NewSimpleStore(bucket string) store.S3Store {
+	if _, ok := f[bucket]; !ok {
+		f[bucket] = &MockS3Store{}
+	}
+	return f[bucket]
+}
+
+func TestPurgeWorkerHandleMessage(t *testing.T) {
+	mockStore := MockDatasetsStore{GetPurgedFilesReturn: MockReturn[[]store.PurgedFile]{Value: []store.PurgedFile{
+		{Id: 1, S3Bucket: "storage-use1", S3Key: "o1/a"},
+		{Id: 2, S3Bucket: "storage-afs1", S3Key: "o1/b"},
+		{Id: 3, S3Bucket: "storage-use1", S3Key: "o1/c"},
+	}}}
+	mockFactory := MockFactory{&mockStore, -1}
+	s3Factory := bucketS3Factory{}
+	worker := NewPurgeWorkerWithFactory(&mockFactory, s3Factory)
+
+	err := worker.HandleMessage(context.Background(), `{"org_int_id": 7, "dataset_int_id": 13, "dataset_node_id": "N:dataset:1234", "package_ids": [4, 5]}`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 7, mockFactory.orgId)
+		assert.Equal(t, []string{"o1/a", "o1/c"}, s3Factory["storage-use1"].DeletedObjects)
+		assert.Equal(t, []string{"o1/b"}, s3Factory["storage-afs1"].DeletedObjects)
+		assert.Equal(t, []int64{1, 2, 3}, mockStore.DeletedFileIds)
+	}
+}
+
+func TestPurgeWorkerHandleMessageErrors(t *testing.T) {
+	message := `{"org_int_id": 7, "dataset_int_id": 13, "package_ids": [4]}`
+	files := MockReturn[[]store.PurgedFile]{Value: []store.PurgedFile{{Id: 1, S3Bucket: "storage-use1", S3Key: "o1/a"}}}
+	for tName, tData := range map[string]struct {
+		message       string
+		mockStore     MockDatasetsStore
+		s3Error       error
+		expectedError bool
+	}{
+		"undecodable message is dropped": {message: `{"org_int_id": `},
+		"invalid org is dropped":         {message: `{"org_int_id": 0, "dataset_int_id": 13, "package_ids": [4]}`},
+		"missing packages are dropped":   {message: `{"org_int_id": 7, "dataset_int_id": 13}`},
+		"no files left":                  {message: message},
+		"store error is retried": {message: message, expectedError: true,
+			mockStore: MockDatasetsStore{GetPurgedFilesReturn: MockReturn[[]store.PurgedFile]{Error: errors.New("unexpected files error")}}},
+		"S3 error keeps the rows": {message: message, expectedError: true, mockStore: MockDatasetsStore{GetPurgedFilesReturn: files},
+			s3Error: errors.New("unexpected S3 error")},
+		"delete error is retried": {message: message, expectedError: true,
+			mockStore: MockDatasetsStore{GetPurgedFilesReturn: files, DeleteFilesError: errors.New("unexpected delete error")}},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			mockStore := tData.mockStore
+			worker := NewPurgeWorkerWithFactory(&MockFactory{&mockStore, -1}, bucketS3Factory{"storage-use1": &MockS3Store{DeleteObjectsError: tData.s3Error}})
+			err := worker.HandleMessage(context.Background(), tData.message)
+			if tData.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Empty(t, mockStore.DeletedFileIds)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/datasets-service/api/store"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/packageInfo/packageState"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/packageInfo/packageType"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
+)
+
+// RestorePackages moves the packages with the given node ids out of the trashcan. Any deleted ancestors of a package
+// are restored as well so that its path is valid again, and restoring a collection restores everything
+// deleted below it. All restores happen in one transaction, but each node id gets its own entry in
+// the response: a missing package or one that is not in the trashcan is reported as a failure
+// without affecting the others.
+func (s *datasetsService) RestorePackages(ctx context.Context, datasetNodeId string, nodeIds []string) (*models.RestoreResponse, error) {
+	response := models.RestoreResponse{Success: []models.RestoredItem{}, Failures: []models.RestoreFailure{}}
+	err := s.StoreFactory.ExecStoreTx(ctx, s.OrgId, func(q store.DatasetsStore) error {
+		dataset, err := q.GetDatasetByNodeId(ctx, datasetNodeId)
+		if err != nil {
+			return err
+		}
+		r := restorer{q: q, orgId: s.OrgId, datasetId: dataset.Id, restored: map[int64]string{}}
+		for _, nodeId := range nodeIds {
+			name, err := r.restore(ctx, nodeId)
+			if err == nil {
+				response.Success = append(response.Success, models.RestoredItem{NodeId: nodeId, Name: name})
+				continue
+			}
+			switch err.(type) {
+			case models.PackageNotFoundError, models.PackageNotDeletedError:
+				response.Failures = append(response.Failures, models.RestoreFailure{NodeId: nodeId, Error: err.Error()})
+			default:
+				return err
+			}
+		}
+		if len(response.Success) == 0 {
+			return nil
+		}
+		return q.TouchDataset(ctx, dataset.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// restorer holds the state of a single RestorePackages call.
+type restorer struct {
+	q         store.DatasetsStore
+	orgId     int
+	datasetId int64
+	// restored maps the id of each package restored so far to its restored name
+	restored map[int64]string
+}
+
+func (r *restorer) restore(ctx context.Context, nodeId string) (string, error) {
+	pckg, err := r.q.GetDatasetPackageByNodeId(ctx, r.datasetId, nodeId)
+	if err != nil {
+		return "", err
+	}
+	if name, ok := r.restored[pckg.Id]; ok {
+		// already restored earlier in this call as an ancestor or descendant of another node id
+		return name, nil
+	}
+	purged, err := r.q.IsPackagePurged(ctx, pckg.Id)
+	if err != nil {
+		return "", err
+	}
+	if purged {
+		// being removed permanently, so no longer in the trashcan
+		return "", models.PackageNotDeletedError{OrgId: r.orgId, Id: models.PackageNodeId(nodeId), DatasetId: models.DatasetIntId(r.datasetId)}
+	}
+	ancestors, err := r.q.GetPackageAncestors(ctx, r.datasetId, pckg.Id)
+	if err != nil {
+		return "", err
+	}
+	var deletedAncestors []pgdb.Package
+	for _, a := range ancestors {
+		if isDeleted(a.PackageState) {
+			deletedAncestors = append(deletedAncestors, a)
+		}
+	}
+	if !isDeleted(pckg.PackageState) && len(deletedAncestors) == 0 {
+		return "", models.PackageNotDeletedError{OrgId: r.orgId, Id: models.PackageNodeId(nodeId), DatasetId: models.DatasetIntId(r.datasetId)}
+	}
+	for i := range deletedAncestors {
+		if _, err := r.restoreOne(ctx, &deletedAncestors[i]); err != nil {
+			return "", err
+		}
+	}
+	name := pckg.Name
+	if isDeleted(pckg.PackageState) {
+		if name, err = r.restoreOne(ctx, pckg); err != nil {
+			return "", err
+		}
+	}
+	if pckg.PackageType == packageType.Collection {
+		descendants, err := r.q.GetDeletedDescendants(ctx, r.datasetId, pckg.Id)
+		if err != nil {
+			return "", err
+		}
+		for i := range descendants {
+			if _, err := r.restoreOne(ctx, &descendants[i]); err != nil {
+				return "", err
+			}
+		}
+	}
+	return name, nil
+}
+
+// restoreOne gives the package its original name back, renamed if necessary to avoid a live sibling with the
+// same name, and sets it to a live state.
+func (r *restorer) restoreOne(ctx context.Context, pckg *pgdb.Package) (string, error) {
+	if name, ok := r.restored[pckg.Id]; ok {
+		return name, nil
+	}
+	name, err := r.availableName(ctx, pckg.ParentId, models.OriginalPackageName(pckg.Name, pckg.NodeId), pckg.PackageType == packageType.Collection)
+	if err != nil {
+		return "", err
+	}
+	state := packageState.Uploaded
+	if pckg.PackageType == packageType.Collection {
+		state = packageState.Ready
+	}
+	if err := r.q.UpdatePackageNameAndState(ctx, pckg.Id, name, state); err != nil {
+		return "", err
+	}
+	r.restored[pckg.Id] = name
+	return name, nil
+}
+
+// availableName returns name if no live package in the parent folder already uses it. Otherwise it returns
+// the first of "name (1)", "name (2)", ... that is free. For files the number goes before the extension.
+func (r *restorer) availableName(ctx context.Context, parentId sql.NullInt64, name string, isCollection bool) (string, error) {
+	base, ext := name, ""
+	if !isCollection {
+		ext = path.Ext(name)
+		base = strings.TrimSuffix(name, ext)
+	}
+	candidate := name
+	for i := 1; ; i++ {
+		count, err := r.q.CountLivePackagesByName(ctx, r.datasetId, parentId, candidate)
+		if err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+}
+
+func isDeleted(state packageState.State) bool {
+	return state == packageState.Deleted || state == packageState.Deleting
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/packageInfo/packageState"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/packageInfo/packageType"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestorePackages(t *testing.T) {
+	orgId := 7
+	deletedFolder := pgdb.Package{
+		Id:           4,
+		Name:         "__DELETED__N:collection:4_folder",
+		NodeId:       "N:collection:4",
+		PackageType:  packageType.Collection,
+		PackageState: packageState.Deleted,
+	}
+	liveFolder := pgdb.Package{Id: 2, Name: "live", NodeId: "N:collection:2", PackageType: packageType.Collection, PackageState: packageState.Ready}
+	deletedFile := pgdb.Package{
+		Id:           9,
+		Name:         "__DELETED__N:package:9_data.csv",
+		NodeId:       "N:package:9",
+		ParentId:     sql.NullInt64{Int64: 4, Valid: true},
+		PackageType:  packageType.CSV,
+		PackageState: packageState.Deleting,
+	}
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &deletedFile},
+		GetPackageAncestorsReturn:       MockReturn[[]pgdb.Package]{Value: []pgdb.Package{liveFolder, deletedFolder}},
+		LivePackageNames:                []string{"folder", "data.csv", "data (1).csv"},
+	}
+	mockFactory := MockFactory{&mockStore, -1}
+	service := NewDatasetsServiceWithFactory(&mockFactory, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, orgId)
+
+	response, err := service.RestorePackages(context.Background(), "N:dataset:1234", []string{deletedFile.NodeId})
+	if assert.NoError(t, err) {
+		assert.Equal(t, orgId, mockFactory.orgId)
+		assert.Empty(t, response.Failures)
+		assert.Equal(t, []models.RestoredItem{{NodeId: deletedFile.NodeId, Name: "data (2).csv"}}, response.Success)
+		assert.Equal(t, []UpdatedPackage{
+			{Id: deletedFolder.Id, Name: "folder (1)", State: packageState.Ready},
+			{Id: deletedFile.Id, Name: "data (2).csv", State: packageState.Uploaded},
+		}, mockStore.UpdatedPackages)
+		assert.Equal(t, []int64{13}, mockStore.TouchedDatasets)
+	}
+}
+
+func TestRestorePackagesCollection(t *testing.T) {
+	deletedFolder := pgdb.Package{Id: 4, Name: "folder", NodeId: "N:collection:4", PackageType: packageType.Collection, PackageState: packageState.Deleted}
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &deletedFolder},
+		GetDeletedDescendantsReturn: MockReturn[[]pgdb.Package]{Value: []pgdb.Package{
+			{Id: 5, Name: "__DELETED__N:collection:5_sub", NodeId: "N:collection:5", PackageType: packageType.Collection, PackageState: packageState.Deleted},
+			{Id: 6, Name: "__DELETED__N:package:6_image.png", NodeId: "N:package:6", PackageType: packageType.Image, PackageState: packageState.Deleted},
+		}},
+	}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+
+	// Asking for the folder twice should not restore it twice
+	response, err := service.RestorePackages(context.Background(), "N:dataset:1234", []string{deletedFolder.NodeId, deletedFolder.NodeId})
+	if assert.NoError(t, err) {
+		assert.Empty(t, response.Failures)
+		assert.Len(t, response.Success, 2)
+		assert.Equal(t, []UpdatedPackage{
+			{Id: 4, Name: "folder", State: packageState.Ready},
+			{Id: 5, Name: "sub", State: packageState.Ready},
+			{Id: 6, Name: "image.png", State: packageState.Uploaded},
+		}, mockStore.UpdatedPackages)
+	}
+}
+
+func TestRestorePackagesFailures(t *testing.T) {
+	orgId := 7
+	for tName, mockStore := range map[string]MockDatasetsStore{
+		"package not found": {
+			GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Error: models.PackageNotFoundError{OrgId: orgId, Id: models.PackageNodeId("N:package:1"), DatasetId: models.DatasetIntId(13)}},
+		},
+		"package not deleted": {
+			GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 1, NodeId: "N:package:1", PackageState: packageState.Ready}},
+			GetPackageAncestorsReturn:       MockReturn[[]pgdb.Package]{Value: []pgdb.Package{{Id: 2, PackageType: packageType.Collection, PackageState: packageState.Ready}}},
+		},
+		"package purged": {
+			GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 1, Name: "__DELETED__N:package:1_data.csv", NodeId: "N:package:1", PackageState: packageState.Deleting}},
+			PurgedPackageIds:                []int64{1},
+		},
+	} {
+		mockStore := mockStore
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, orgId)
+		t.Run(tName, func(t *testing.T) {
+			response, err := service.RestorePackages(context.Background(), "N:dataset:1234", []string{"N:package:1"})
+			if assert.NoError(t, err) {
+				assert.Empty(t, response.Success)
+				if assert.Len(t, response.Failures, 1) {
+					assert.Equal(t, "N:package:1", response.Failures[0].NodeId)
+				}
+				assert.Empty(t, mockStore.UpdatedPackages)
+				assert.Empty(t, mockStore.TouchedDatasets)
+			}
+		})
+	}
+}
+
+func TestRestorePackagesErrors(t *testing.T) {
+	for tName, mockStore := range map[string]MockDatasetsStore{
+		"dataset not found": {
+			GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Error: models.DatasetNotFoundError{OrgId: 7, Id: models.DatasetNodeId("N:dataset:1234")}},
+		},
+		"unexpected ancestors error": {
+			GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 1, PackageState: packageState.Deleted}},
+			GetPackageAncestorsReturn:       MockReturn[[]pgdb.Package]{Error: errors.New("unexpected ancestors error")},
+		},
+	} {
+		mockStore := mockStore
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+		t.Run(tName, func(t *testing.T) {
+			_, err := service.RestorePackages(context.Background(), "N:dataset:1234", []string{"N:package:1"})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -3,21 +3,36 @@ package service
 import (
     "context"
     "database/sql"
+    "encoding/json"
+    "errors"
     "fmt"
+    "io"
     "github.com/aws/aws-sdk-go-v2/service/s3"
+    "github.com/google/uuid"
     "github.com/pennsieve/datasets-service/api/models"
     "github.com/pennsieve/datasets-service/api/store"
     "github.com/pennsieve/pennsieve-go-core/pkg/models/packageInfo/packageState"
     "github.com/pennsieve/pennsieve-go-core/pkg/models/packageInfo/packageType"
     "github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
+    log "github.com/sirupsen/logrus"
     "strings"
     "time"
 )
 
 type DatasetsService interface {
     GetDataset(ctx context.Context, datasetNodeId string) (*pgdb.Dataset, error)
-    GetTrashcanPage(ctx context.Context, datasetNodeId string, rootNodeId string, limit int, offset int) (*models.TrashcanPage, error)
-    GetManifest(ctx context.Context, datasetNodeId string) (*models.ManifestResult, error)
+    GetTrashcanPage(ctx context.Context, datasetNodeId string, rootNodeId string, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor]) (*models.TrashcanPage, error)
+    GetPackagesPage(ctx context.Context, datasetNodeId string, rootNodeId string, sort models.PackageSort, limit int, offset int) (*models.PackagesPage, error)
+    TriggerAsyncGetManifest(ctx context.Context, datasetNodeId string, options models.ManifestOptions) (*models.ManifestResult, error)
+    GetManifestJobStatus(ctx context.Context, datasetNodeId string, jobId string) (*models.ManifestResult, error)
+    GetManifestDiff(ctx context.Context, datasetNodeId string, fromS3Key string, toS3Key string) (*models.ManifestDiff, error)
+    ProcessManifestJob(ctx context.Context, input models.ManifestWorkerInput) error
+    FailManifestJob(ctx context.Context, jobId string, reason string) error
+    RestorePackages(ctx context.Context, datasetNodeId string, nodeIds []string) (*models.RestoreResponse, error)
+    PurgeTrashcan(ctx context.Context, datasetNodeId string, rootNodeId string) (*models.TrashcanPurgeResult, error)
+    GetDatasetStats(ctx context.Context, datasetNodeId string) (*models.DatasetStats, error)
+    GetTrashcanSummary(ctx context.Context, datasetNodeId string, rootNodeId string) (*models.TrashcanSummary, error)
+    ExportTrashcan(ctx context.Context, datasetNodeId string) (*models.TrashcanExportResult, error)
 }
 
 type datasetsService struct {
@@ -27,6 +42,7 @@ type datasetsService struct {
     OrgId            int
     S3ManifestBucket string
     SnsTopic         string
+    PurgeSnsTopic    string
 }
 
 func NewDatasetsServiceWithFactory(factory store.DatasetsStoreFactory, s3factory store.S3StoreFactory, snsFactory store.SnsStoreFactory, options *models.HandlerVars, orgId int) DatasetsService {
@@ -36,7 +52,8 @@ func NewDatasetsServiceWithFactory(factory store.DatasetsStoreFactory, s3factory
         SnsStoreFactory:  snsFactory,
         S3ManifestBucket: options.S3Bucket,
         OrgId:            orgId,
-        SnsTopic:         options.SnsTopic}
+        SnsTopic:         options.SnsTopic,
+        PurgeSnsTopic:    options.PurgeSnsTopic}
 }
 
 func NewDatasetsService(db *sql.DB, s3Client *s3.Client, snsClient models.SnsAPI, options *models.HandlerVars, orgId int) DatasetsService {
@@ -49,8 +66,14 @@ func NewDatasetsService(db *sql.DB, s3Client *s3.Client, snsClient models.SnsAPI
 }
 
 
-func (s *datasetsService) GetTrashcanPage(ctx context.Context, datasetId string, rootNodeId string, limit int, offset int) (*models.TrashcanPage, error) {
-    trashcan := models.TrashcanPage{Limit: limit, Offset: offset, Packages: []models.TrashcanItem{}, Messages: []string{}}
+// GetTrashcanPage returns a page of the trashcan directly inside the folder rootNodeId, or the dataset root if
+// rootNodeId is empty, or at any depth below it if filter.Flat is set. The page has a NextCursor unless it is the last.
+func (s *datasetsService) GetTrashcanPage(ctx context.Context, datasetId string, rootNodeId string, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor]) (*models.TrashcanPage, error) {
+    limit := paging.Limit
+    trashcan := models.TrashcanPage{Limit: limit, Offset: paging.Offset, Packages: []models.TrashcanItem{}, Messages: []string{}}
+    if paging.CountTotal() {
+        trashcan.TotalCount = new(int)
+    }
     err := s.StoreFactory.ExecStoreTx(ctx, s.OrgId, func(q store.DatasetsStore) error {
         dataset, err := q.GetDatasetByNodeId(ctx, datasetId)
         if err != nil {
@@ -60,127 +83,609 @@ func (s *datasetsService) GetTrashcanPage(ctx context.Context, datasetId string,
         if err != nil || deletedCount == 0 {
             return err
         }
+        rootPckg, err := s.getRootCollection(ctx, q, datasetId, dataset.Id, rootNodeId)
+        if err != nil {
+            return err
+        }
+        // one more package than the limit is fetched to tell whether there is a next page
+        storePaging := paging
+        storePaging.Limit = limit + 1
         var page *store.PackagePage
-        if len(rootNodeId) == 0 {
-            page, err = q.GetTrashcanRootPaginated(ctx, dataset.Id, limit, offset)
+        if rootPckg == nil {
+            page, err = q.GetTrashcanRootPaginated(ctx, dataset.Id, filter, sort, storePaging)
         } else {
-            rootPckg, pckgErr := q.GetDatasetPackageByNodeId(ctx, dataset.Id, rootNodeId)
-            if pckgErr != nil {
-                return pckgErr
+            page, err = q.GetTrashcanPaginated(ctx, dataset.Id, rootPckg.Id, filter, sort, storePaging)
+        }
+        if err != nil {
+            return err
+        }
+        if len(page.Packages) > limit {
+            page.Packages = page.Packages[:limit]
+            if limit > 0 {
+                last := page.Packages[limit-1]
+                trashcan.NextCursor = models.EncodeCursor(models.NewTrashcanCursor(sort, last.Id, models.OriginalPackageName(last.Name, last.NodeId), last.PackageType.String(), last.UpdatedAt, packageSize(last.Package)))
             }
-            if rootPckg.PackageType != packageType.Collection {
-                return models.FolderNotFoundError{OrgId: s.OrgId, NodeId: rootNodeId, DatasetId: models.DatasetNodeId(datasetId), ActualType: rootPckg.PackageType}
+        }
+        var parentIds []int64
+        seenParents := map[int64]bool{}
+        for _, p := range page.Packages {
+            if p.ParentId.Valid && !seenParents[p.ParentId.Int64] {
+                seenParents[p.ParentId.Int64] = true
+                parentIds = append(parentIds, p.ParentId.Int64)
             }
-            page, err = q.GetTrashcanPaginated(ctx, dataset.Id, rootPckg.Id, limit, offset)
         }
+        parentPaths, err := q.GetPackagePaths(ctx, dataset.Id, parentIds)
         if err != nil {
             return err
         }
         packages := make([]models.TrashcanItem, len(page.Packages))
         for i, p := range page.Packages {
             packages[i] = models.TrashcanItem{
-                ID:     p.Id,
-                Name:   p.Name,
-                NodeId: p.NodeId,
-                Type:   p.PackageType.String(),
-                State:  p.PackageState.String(),
+                ID:        p.Id,
+                Name:      models.OriginalPackageName(p.Name, p.NodeId),
+                NodeId:    p.NodeId,
+                Type:      p.PackageType.String(),
+                State:     p.PackageState.String(),
+                Size:      packageSize(p.Package),
+                Owner:     trashcanUser(p),
+            }
+            if isDeleted(p.PackageState) {
+                deletedAt := p.UpdatedAt
+                packages[i].DeletedAt = &deletedAt
+            }
+            if p.ParentId.Valid {
+                packages[i].ParentPath = parentPaths[p.ParentId.Int64]
             }
         }
-        trashcan.TotalCount = page.TotalCount
+        if trashcan.TotalCount != nil {
+            *trashcan.TotalCount = page.TotalCount
+        }
         trashcan.Packages = packages
         return nil
     })
     return &trashcan, err
 }
 
+func packageSize(p pgdb.Package) *int64 {
+    if !p.Size.Valid {
+        return nil
+    }
+    size := p.Size.Int64
+    return &size
+}
+
+// trashcanUser returns the owner of a package in the trashcan, or nil if their account no longer exists.
+func trashcanUser(p store.TrashcanPackage) *models.TrashcanUser {
+    if !p.OwnerNodeId.Valid {
+        return nil
+    }
+    name := strings.TrimSpace(p.OwnerFirstName.String + " " + p.OwnerLastName.String)
+    return &models.TrashcanUser{NodeId: p.OwnerNodeId.String, Name: name}
+}
+
+// GetTrashcanSummary totals the trashcan below the folder rootNodeId, or the whole trashcan if rootNodeId is empty.
+// It covers the same packages as PurgeTrashcan, so that users can see what a purge would free.
+func (s *datasetsService) GetTrashcanSummary(ctx context.Context, datasetNodeId string, rootNodeId string) (*models.TrashcanSummary, error) {
+    var summary *models.TrashcanSummary
+    err := s.StoreFactory.ExecStoreTx(ctx, s.OrgId, func(q store.DatasetsStore) error {
+        dataset, err := q.GetDatasetByNodeId(ctx, datasetNodeId)
+        if err != nil {
+            return err
+        }
+        rootPckg, err := s.getRootCollection(ctx, q, datasetNodeId, dataset.Id, rootNodeId)
+        if err != nil {
+            return err
+        }
+        var rootId sql.NullInt64
+        if rootPckg != nil {
+            rootId = sql.NullInt64{Int64: rootPckg.Id, Valid: true}
+        }
+        summary, err = q.GetTrashcanSummary(ctx, dataset.Id, rootId)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    return summary, nil
+}
+
+// ExportTrashcan writes a models.TrashcanExport of the dataset's whole trashcan to the manifest bucket and returns a
+// presigned URL for it. Every export is written to a new key, since the trashcan changes without the dataset's
+// updated_at changing.
+func (s *datasetsService) ExportTrashcan(ctx context.Context, datasetNodeId string) (*models.TrashcanExportResult, error) {
+    q := s.StoreFactory.NewSimpleStore(s.OrgId)
+    s3 := s.S3StoreFactory.NewSimpleStore(s.S3ManifestBucket)
+
+    ds, err := q.GetDatasetByNodeId(ctx, datasetNodeId)
+    if err != nil {
+        return nil, err
+    }
+    packages, err := q.GetTrashcanTree(ctx, ds.Id)
+    if err != nil {
+        return nil, err
+    }
+    export := trashcanExport(datasetNodeId, packages, time.Now().UTC())
+    content, err := json.Marshal(export)
+    if err != nil {
+        return nil, err
+    }
+    s3Key := trashcanExportS3Key(datasetNodeId, export.ExportedAt)
+    if err := s3.WriteObject(ctx, s3Key, "application/json", content); err != nil {
+        return nil, err
+    }
+    presignedUrl, err := s3.GetPresignedUrl(ctx, s.S3ManifestBucket, s3Key)
+    if err != nil {
+        return nil, err
+    }
+    return &models.TrashcanExportResult{
+        Url:          presignedUrl.String(),
+        S3Bucket:     s.S3ManifestBucket,
+        S3Key:        s3Key,
+        DeletedCount: export.DeletedCount,
+    }, nil
+}
+
+// trashcanExport nests the packages returned by GetTrashcanTree under their parents. Each package comes after its
+// parent, so the path of the parent is always known. Names are the ones packages had before they were deleted.
+func trashcanExport(datasetNodeId string, packages []store.TrashcanPackage, exportedAt time.Time) models.TrashcanExport {
+    export := models.TrashcanExport{DatasetNodeId: datasetNodeId, ExportedAt: exportedAt, Items: []*models.TrashcanExportItem{}}
+    items := make(map[int64]*models.TrashcanExportItem, len(packages))
+    for _, p := range packages {
+        name := models.OriginalPackageName(p.Name, p.NodeId)
+        item := &models.TrashcanExportItem{
+            NodeId: p.NodeId,
+            Name:   name,
+            Type:   p.PackageType.String(),
+            State:  p.PackageState.String(),
+            Path:   name,
+            Size:   packageSize(p.Package),
+            Owner:  trashcanUser(p),
+        }
+        if isDeleted(p.PackageState) {
+            deletedAt := p.UpdatedAt
+            item.Deleted = true
+            item.DeletedAt = &deletedAt
+            export.DeletedCount++
+        }
+        items[p.Id] = item
+        if parent, ok := items[p.ParentId.Int64]; ok && p.ParentId.Valid {
+            item.Path = parent.Path + "/" + name
+            parent.Children = append(parent.Children, item)
+        } else {
+            export.Items = append(export.Items, item)
+        }
+    }
+    return export
+}
+
+// trashcanExportS3Key returns the S3 key of a trashcan export (format: "datasetID/trashcan/datasetID_exportedAt.json").
+func trashcanExportS3Key(datasetNodeId string, exportedAt time.Time) string {
+    datasetId := strings.Replace(datasetNodeId, "N:dataset:", "", -1)
+    return fmt.Sprintf("%s/trashcan/%s_%s.json",
+        datasetId,
+        datasetId,
+        strings.Replace(exportedAt.Format(time.RFC3339Nano), ":", "_", -1))
+}
+
+// GetPackagesPage returns a page of the packages that are not deleted directly inside the folder rootNodeId, or
+// the dataset root if rootNodeId is empty. It is the live counterpart of GetTrashcanPage.
+func (s *datasetsService) GetPackagesPage(ctx context.Context, datasetNodeId string, rootNodeId string, sort models.PackageSort, limit int, offset int) (*models.PackagesPage, error) {
+    page := models.PackagesPage{Limit: limit, Offset: offset, Packages: []models.PackageItem{}}
+    err := s.StoreFactory.ExecStoreTx(ctx, s.OrgId, func(q store.DatasetsStore) error {
+        dataset, err := q.GetDatasetByNodeId(ctx, datasetNodeId)
+        if err != nil {
+            return err
+        }
+        rootPckg, err := s.getLiveRootCollection(ctx, q, datasetNodeId, dataset.Id, rootNodeId)
+        if err != nil {
+            return err
+        }
+        var parentId sql.NullInt64
+        if rootPckg != nil {
+            parentId = sql.NullInt64{Int64: rootPckg.Id, Valid: true}
+        }
+        livePage, err := q.GetLivePackagesPaginated(ctx, dataset.Id, parentId, sort, limit, offset)
+        if err != nil {
+            return err
+        }
+        packages := make([]models.PackageItem, len(livePage.Packages))
+        for i, p := range livePage.Packages {
+            packages[i] = models.PackageItem{
+                ID:        p.Id,
+                Name:      p.Name,
+                NodeId:    p.NodeId,
+                Type:      p.PackageType.String(),
+                State:     p.PackageState.String(),
+                CreatedAt: p.CreatedAt,
+            }
+            if p.Size.Valid {
+                packages[i].Size = &p.Size.Int64
+            }
+            if p.PackageType == packageType.Collection {
+                packages[i].ChildCount = &p.ChildCount
+            }
+        }
+        page.TotalCount = livePage.TotalCount
+        page.Packages = packages
+        return nil
+    })
+    return &page, err
+}
+
+// getRootCollection returns the collection a trashcan or manifest operation starts from, or nil if rootNodeId is
+// empty, meaning the dataset root.
+func (s *datasetsService) getRootCollection(ctx context.Context, q store.DatasetsStore, datasetNodeId string, datasetId int64, rootNodeId string) (*pgdb.Package, error) {
+    if len(rootNodeId) == 0 {
+        return nil, nil
+    }
+    rootPckg, err := q.GetDatasetPackageByNodeId(ctx, datasetId, rootNodeId)
+    if err != nil {
+        return nil, err
+    }
+    if rootPckg.PackageType != packageType.Collection {
+        return nil, models.FolderNotFoundError{OrgId: s.OrgId, NodeId: rootNodeId, DatasetId: models.DatasetNodeId(datasetNodeId), ActualType: rootPckg.PackageType}
+    }
+    return rootPckg, nil
+}
+
+// getLiveRootCollection returns the collection a manifest or package listing is limited to, or nil if rootNodeId
+// is empty. Collections in the trashcan are not found.
+func (s *datasetsService) getLiveRootCollection(ctx context.Context, q store.DatasetsStore, datasetNodeId string, datasetId int64, rootNodeId string) (*pgdb.Package, error) {
+    root, err := s.getRootCollection(ctx, q, datasetNodeId, datasetId, rootNodeId)
+    if err != nil || root == nil {
+        return nil, err
+    }
+    notFound := models.PackageNotFoundError{Id: models.PackageNodeId(rootNodeId), OrgId: s.OrgId, DatasetId: models.DatasetNodeId(datasetNodeId)}
+    if isDeleted(root.PackageState) {
+        return nil, notFound
+    }
+    // A live collection below a deleted one is in the trashcan too
+    ancestors, err := q.GetPackageAncestors(ctx, datasetId, root.Id)
+    if err != nil {
+        return nil, err
+    }
+    for _, ancestor := range ancestors {
+        if isDeleted(ancestor.PackageState) {
+            return nil, notFound
+        }
+    }
+    return root, nil
+}
+
 func (s *datasetsService) GetDataset(ctx context.Context, datasetId string) (*pgdb.Dataset, error) {
     q := s.StoreFactory.NewSimpleStore(s.OrgId)
     return q.GetDatasetByNodeId(ctx, datasetId)
 }
 
-//// TriggerAsyncGetManifest is used to signal the worker Lambda to generate the manifest.
-//func (s *datasetsService) TriggerAsyncGetManifest(ctx context.Context, datasetNodeId string) (*models.ManifestResult, error) {
-//	q := s.StoreFactory.NewSimpleStore(s.OrgId)
-//	ds, _ := q.GetDatasetByNodeId(ctx, datasetNodeId)
-//	s3 := s.S3StoreFactory.NewSimpleStore(s.S3ManifestBucket)
-//
-//	// Define S3Key for Manifest (format: "datasetID/datasetID_lastUpdated.json"
-//	manifestFileName := fmt.Sprintf("%s/%s_%s.json",
-//		strings.Replace(datasetNodeId, "N:dataset:", "", -1),
-//		strings.Replace(datasetNodeId, "N:dataset:", "", -1),
-//		strings.Replace(ds.UpdatedAt.Format(time.RFC3339), ":", "_", -1))
-//
-//	sns := s.SnsStoreFactory.NewSimpleStore(s.SnsTopic)
-//
-//	sns.TriggerWorkerLambda(ctx, models.ManifestWorkerInput{
-//		OrgIntId:      s.OrgId,
-//		DatasetNodeId: datasetNodeId,
-//		ManifestS3Key: manifestFileName,
-//	})
-//
-//	// Create Presigned URL for file on S3
-//	presignedUrl, err := s3.GetPresignedUrl(ctx, s.S3ManifestBucket, manifestFileName)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	result := models.ManifestResult{
-//		Url:      presignedUrl.String(),
-//		S3Bucket: s.S3ManifestBucket,
-//		S3Key:    manifestFileName,
-//		Status:   models.CREATING,
-//	}
-//	return &result, nil
-//
-//}
-
-// GetManifest is used by the manifest Worker to generate the manifest and store on S3.
-func (s *datasetsService) GetManifest(ctx context.Context, datasetNodeId string) (*models.ManifestResult, error) {
+// TriggerAsyncGetManifest records a new manifest job and signals the worker Lambda to generate the manifest.
+// The returned result carries the job id that can be passed to GetManifestJobStatus. If the manifest for the
+// current version of the dataset already exists, and options.Force is false, a READY result for it is returned instead.
+// If a job is already creating the same manifest, its result is returned rather than starting another.
+func (s *datasetsService) TriggerAsyncGetManifest(ctx context.Context, datasetNodeId string, options models.ManifestOptions) (*models.ManifestResult, error) {
     q := s.StoreFactory.NewSimpleStore(s.OrgId)
+    ds, err := q.GetDatasetByNodeId(ctx, datasetNodeId)
+    if err != nil {
+        return nil, err
+    }
+    if _, err := s.getLiveRootCollection(ctx, q, datasetNodeId, ds.Id, options.RootNodeId); err != nil {
+        return nil, err
+    }
     s3 := s.S3StoreFactory.NewSimpleStore(s.S3ManifestBucket)
+    s3Key := manifestS3Key(datasetNodeId, ds, options)
 
-    ds, _ := q.GetDatasetByNodeId(ctx, datasetNodeId)
+    // Presigned URLs in an existing manifest may have expired
+    if !options.Force && !options.IncludeUrls {
+        if existing, err := s.existingManifest(ctx, s3, s3Key, options.Format); err != nil || existing != nil {
+            return existing, err
+        }
+    }
 
-    // Define S3Key for Manifest (format: "datasetID/datasetID_lastUpdated.json"
-    s3Key := fmt.Sprintf("%s/%s_%s.json",
-        strings.Replace(datasetNodeId, "N:dataset:", "", -1),
-        strings.Replace(datasetNodeId, "N:dataset:", "", -1),
-        strings.Replace(ds.UpdatedAt.Format(time.RFC3339), ":", "_", -1))
+    inFlight, err := s3.GetManifestJobByS3Key(ctx, s3Key)
+    if err != nil {
+        if _, notFound := err.(models.ManifestJobNotFoundError); !notFound {
+            return nil, err
+        }
+    } else if inFlight.Status == models.CREATING {
+        return &models.ManifestResult{
+            S3Bucket: s.S3ManifestBucket,
+            S3Key:    inFlight.S3Key,
+            JobId:    inFlight.JobId,
+            Status:   inFlight.Status,
+        }, nil
+    }
 
-    manifest, err := q.GetDatasetManifest(ctx, ds.Id)
+    now := time.Now()
+    job := models.ManifestJob{
+        JobId:         uuid.NewString(),
+        DatasetNodeId: datasetNodeId,
+        S3Key:         s3Key,
+        Format:        options.Format,
+        Status:        models.CREATING,
+        CreatedAt:     now,
+        UpdatedAt:     now,
+    }
+    if err := s3.WriteManifestJob(ctx, job); err != nil {
+        return nil, err
+    }
 
-    // Map each entry to packageID for lookup to create path
-    manifestMap := make(map[int]models.DatasetManifest)
-    for i := 0; i < len(manifest); i++ {
-        manifestMap[manifest[i].PackageId] = manifest[i]
+    sns := s.SnsStoreFactory.NewSimpleStore(s.SnsTopic)
+    err = sns.TriggerWorkerLambda(ctx, models.ManifestWorkerInput{
+        OrgIntId:         s.OrgId,
+        DatasetNodeId:    datasetNodeId,
+        JobId:            job.JobId,
+        ManifestS3Key:    job.S3Key,
+        Format:           options.Format,
+        RootNodeId:       options.RootNodeId,
+        AbsolutePaths:    options.AbsolutePaths,
+        IncludeUrls:      options.IncludeUrls,
+        UrlExpirySeconds: int64(options.UrlExpiry / time.Second),
+    })
+    if err != nil {
+        // Don't leave a job behind that no worker will ever finish
+        job.Status = models.FAILED
+        job.Error = err.Error()
+        job.UpdatedAt = time.Now()
+        if writeErr := s3.WriteManifestJob(ctx, job); writeErr != nil {
+            log.Errorf("unable to mark manifest job %s as failed: %v", job.JobId, writeErr)
+        }
+        return nil, err
     }
 
-    // Generate ManifestDTO which includes full path for files
-    var results []models.ManifestDTO
-    for i, _ := range manifest {
+    result := models.ManifestResult{
+        S3Bucket: s.S3ManifestBucket,
+        S3Key:    job.S3Key,
+        JobId:    job.JobId,
+        Status:   job.Status,
+    }
+    return &result, nil
+}
 
-        if !strings.HasPrefix(manifest[i].PackageNodeId, "N:collection") {
-            var sb strings.Builder
-            for pathIndex, _ := range manifest[i].Path {
-                if manifest[i].Path[pathIndex].Valid {
-                    if pathIndex > 1 {
-                        sb.WriteString("/")
-                    }
-                    sb.WriteString(manifestMap[int(manifest[i].Path[pathIndex].Int64)].PackageName)
-                }
-            }
+// GetManifestJobStatus reports the status of a manifest job started by TriggerAsyncGetManifest.
+// Once the job is READY the result includes presigned URLs for the manifest.
+func (s *datasetsService) GetManifestJobStatus(ctx context.Context, datasetNodeId string, jobId string) (*models.ManifestResult, error) {
+    s3 := s.S3StoreFactory.NewSimpleStore(s.S3ManifestBucket)
+    job, err := s3.GetManifestJob(ctx, jobId)
+    if err != nil {
+        if notFound, ok := err.(models.ManifestJobNotFoundError); ok {
+            notFound.DatasetNodeId = datasetNodeId
+            return nil, notFound
+        }
+        return nil, err
+    }
+    if job.DatasetNodeId != datasetNodeId {
+        return nil, models.ManifestJobNotFoundError{JobId: jobId, DatasetNodeId: datasetNodeId}
+    }
+
+    result := models.ManifestResult{
+        S3Bucket: s.S3ManifestBucket,
+        S3Key:    job.S3Key,
+        JobId:    job.JobId,
+        Status:   job.Status,
+        Error:    job.Error,
+    }
+    if job.Status == models.READY {
+        if err := s.presignManifest(ctx, s3, &result, job.Format); err != nil {
+            return nil, err
+        }
+    }
+    return &result, nil
+}
+
+// GetManifestDiff compares the JSON manifest stored at fromS3Key with the one at toS3Key, or with the live
+// contents of the dataset if toS3Key is empty or models.LiveManifest. Keys must be those of the dataset's manifests.
+// The live contents are those of the collection the stored manifest is limited to, with paths written the same way.
+// Both versions are read one file at a time in path order, so only the files that differ are held in memory.
+func (s *datasetsService) GetManifestDiff(ctx context.Context, datasetNodeId string, fromS3Key string, toS3Key string) (*models.ManifestDiff, error) {
+    q := s.StoreFactory.NewSimpleStore(s.OrgId)
+    ds, err := q.GetDatasetByNodeId(ctx, datasetNodeId)
+    if err != nil {
+        return nil, err
+    }
+    s3 := s.S3StoreFactory.NewSimpleStore(s.S3ManifestBucket)
+
+    from, err := s.openManifest(ctx, s3, datasetNodeId, fromS3Key)
+    if err != nil {
+        return nil, err
+    }
+    defer from.Close()
+    differ := models.NewManifestDiffer(from.Next)
+
+    if len(toS3Key) == 0 || toS3Key == models.LiveManifest {
+        toS3Key = models.LiveManifest
+        rootId, rootPath, err := s.manifestRoot(ctx, q, datasetNodeId, ds.Id, from.Manifest.RootNodeId, from.Manifest.AbsolutePaths)
+        if err != nil {
+            return nil, err
+        }
+        err = streamManifest(ctx, q, ds.Id, rootId, rootPath, nil, diffEncoder{differ})
+    } else {
+        err = s.diffStoredManifest(ctx, s3, datasetNodeId, toS3Key, differ)
+    }
+    diff, finishErr := differ.Finish()
+    if err == nil {
+        err = finishErr
+    }
+    if errors.Is(err, models.ErrManifestNotInPathOrder) {
+        return nil, models.ValidationError{Message: "manifests written before their files were ordered by path cannot be compared, generate them again with force=true"}
+    }
+    if err != nil {
+        return nil, err
+    }
+    diff.From = fromS3Key
+    diff.To = toS3Key
+    return &diff, nil
+}
+
+// diffStoredManifest passes each file of the JSON manifest stored at s3Key to differ.
+func (s *datasetsService) diffStoredManifest(ctx context.Context, s3 store.S3Store, datasetNodeId string, s3Key string, differ *models.ManifestDiffer) error {
+    to, err := s.openManifest(ctx, s3, datasetNodeId, s3Key)
+    if err != nil {
+        return err
+    }
+    defer to.Close()
+    for {
+        file, err := to.Next()
+        if err != nil || file == nil {
+            return err
+        }
+        if err := differ.Add(*file); err != nil {
+            return err
+        }
+    }
+}
+
+// openManifest opens a JSON manifest of the dataset. Keys outside the dataset's folder, or of other formats, are
+// reported as not found.
+func (s *datasetsService) openManifest(ctx context.Context, s3 store.S3Store, datasetNodeId string, s3Key string) (*models.ManifestReader, error) {
+    datasetFolder := strings.Replace(datasetNodeId, "N:dataset:", "", -1) + "/"
+    isJSON := strings.HasSuffix(s3Key, "."+models.JSONManifest.Extension()) && !strings.HasSuffix(s3Key, "."+models.ROCrateManifest.Extension())
+    if !strings.HasPrefix(s3Key, datasetFolder) || !isJSON {
+        return nil, models.ManifestNotFoundError{S3Key: s3Key, DatasetNodeId: datasetNodeId}
+    }
+    manifest, err := s3.OpenManifest(ctx, s3Key)
+    if notFound, ok := err.(models.ManifestNotFoundError); ok {
+        notFound.DatasetNodeId = datasetNodeId
+        return nil, notFound
+    }
+    return manifest, err
+}
+
+// diffEncoder is a models.ManifestEncoder that passes the files to a models.ManifestDiffer instead of writing them.
+type diffEncoder struct {
+    differ *models.ManifestDiffer
+}
+
+func (e diffEncoder) WriteCollection(string) error {
+    return nil
+}
+
+func (e diffEncoder) WriteFile(file models.ManifestDTO) error {
+    return e.differ.Add(file)
+}
+
+func (e diffEncoder) Close() error {
+    return nil
+}
+
+// ProcessManifestJob is used by the manifest worker to generate the manifest requested by input and
+// mark the job READY. A failed attempt leaves the job untouched so that it can be retried; use
+// FailManifestJob once the job is given up on.
+func (s *datasetsService) ProcessManifestJob(ctx context.Context, input models.ManifestWorkerInput) error {
+    s3 := s.S3StoreFactory.NewSimpleStore(s.S3ManifestBucket)
+    job, err := s3.GetManifestJob(ctx, input.JobId)
+    if err != nil {
+        return err
+    }
+
+    q := s.StoreFactory.NewSimpleStore(s.OrgId)
+    if err := s.writeManifest(ctx, q, s3, input.DatasetNodeId, input.ManifestS3Key, input.ManifestOptions()); err != nil {
+        return err
+    }
+
+    job.Status = models.READY
+    job.Error = ""
+    job.UpdatedAt = time.Now()
+    return s3.WriteManifestJob(ctx, *job)
+}
+
+// FailManifestJob marks the job FAILED with the given reason.
+func (s *datasetsService) FailManifestJob(ctx context.Context, jobId string, reason string) error {
+    s3 := s.S3StoreFactory.NewSimpleStore(s.S3ManifestBucket)
+    job, err := s3.GetManifestJob(ctx, jobId)
+    if err != nil {
+        return err
+    }
+    job.Status = models.FAILED
+    job.Error = reason
+    job.UpdatedAt = time.Now()
+    return s3.WriteManifestJob(ctx, *job)
+}
+
+// existingManifest returns a READY result with a fresh presigned URL if the manifest at s3Key has already
+// been generated, and nil otherwise. Since the key includes the dataset's last update time, an existing
+// manifest is up to date.
+func (s *datasetsService) existingManifest(ctx context.Context, s3 store.S3Store, s3Key string, format models.ManifestFormat) (*models.ManifestResult, error) {
+    markerKey := s3Key
+    if format == models.BagItManifest {
+        // the declaration is written last, so the bag is complete if it exists
+        markerKey = bagItKey(s3Key, models.BagItDeclaration)
+    }
+    exists, err := s3.ObjectExists(ctx, markerKey)
+    if err != nil || !exists {
+        return nil, err
+    }
+    result := models.ManifestResult{
+        S3Bucket: s.S3ManifestBucket,
+        S3Key:    s3Key,
+        Status:   models.READY,
+    }
+    if err := s.presignManifest(ctx, s3, &result, format); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+// presignManifest adds presigned URLs for the manifest at result.S3Key to the result: Url for single file
+// formats and one TagFiles entry per tag file for BagIt.
+func (s *datasetsService) presignManifest(ctx context.Context, s3 store.S3Store, result *models.ManifestResult, format models.ManifestFormat) error {
+    if format != models.BagItManifest {
+        presignedUrl, err := s3.GetPresignedUrl(ctx, s.S3ManifestBucket, result.S3Key)
+        if err != nil {
+            return err
+        }
+        result.Url = presignedUrl.String()
+        return nil
+    }
+    for _, name := range models.BagItTagFileNames {
+        presignedUrl, err := s3.GetPresignedUrl(ctx, s.S3ManifestBucket, bagItKey(result.S3Key, name))
+        if err != nil {
+            return err
+        }
+        result.TagFiles = append(result.TagFiles, models.TagFileUrl{Name: name, Url: presignedUrl.String()})
+    }
+    return nil
+}
+
+// bagItKey is the S3 key of the named tag file of the BagIt manifest at s3Key.
+func bagItKey(s3Key string, name string) string {
+    return s3Key + "/" + name
+}
 
-            results = append(results, models.ManifestDTO{
-                PackageName:   manifest[i].PackageName,
-                FileNodeId:    manifest[i].FileUUID,
-                FileName:      manifest[i].FileName,
-                Path:          sb.String(),
-                PackageNodeId: manifest[i].PackageNodeId,
-                Size:          manifest[i].Size,
-                CheckSum:      manifest[i].CheckSum,
-            })
+// manifestS3Key returns the S3Key for the manifest of the dataset (format: "datasetID/datasetID_lastUpdated.extension").
+// The manifest of a collection has the collection ID, and "_absolute" if it has absolute paths, after lastUpdated,
+// followed by "_urls" if the manifest has presigned file URLs.
+func manifestS3Key(datasetNodeId string, ds *pgdb.Dataset, options models.ManifestOptions) string {
+    subtree := ""
+    if len(options.RootNodeId) > 0 {
+        subtree = "_" + strings.Replace(options.RootNodeId, "N:collection:", "", -1)
+        if options.AbsolutePaths {
+            subtree += "_absolute"
         }
+    }
+    if options.IncludeUrls {
+        subtree += "_urls"
+    }
+    return fmt.Sprintf("%s/%s_%s%s.%s",
+        strings.Replace(datasetNodeId, "N:dataset:", "", -1),
+        strings.Replace(datasetNodeId, "N:dataset:", "", -1),
+        strings.Replace(ds.UpdatedAt.Format(time.RFC3339), ":", "_", -1),
+        subtree,
+        options.Format.Extension())
+}
 
+// writeManifest builds the manifest of the dataset, or of the collection options.RootNodeId, and writes it to s3Key
+// in the given format. The manifest is streamed: rows are read from the database, encoded and uploaded as they
+// come, through a pipe between the encoder and the S3 upload, so memory use is bounded by the collections of the
+// dataset, not its files.
+func (s *datasetsService) writeManifest(ctx context.Context, q store.DatasetsStore, s3 store.S3Store, datasetNodeId string, s3Key string, options models.ManifestOptions) error {
+    format := options.Format
+    ds, err := q.GetDatasetByNodeId(ctx, datasetNodeId)
+    if err != nil {
+        return err
+    }
+
+    rootId, rootPath, err := s.manifestRoot(ctx, q, datasetNodeId, ds.Id, options.RootNodeId, options.AbsolutePaths)
+    if err != nil {
+        return err
     }
 
     license := "N/A"
@@ -193,6 +698,7 @@ func (s *datasetsService) GetManifest(ctx context.Context, datasetNodeId string)
         description = ds.Description.String
     }
 
+    // The files of the manifest are written by the encoder, not held here.
     workspaceManifest := models.WorkspaceManifest{
         Date:          models.JSONDate(time.Now()),
         DatasetId:     ds.Id,
@@ -202,28 +708,142 @@ func (s *datasetsService) GetManifest(ctx context.Context, datasetNodeId string)
         License:       license,
         Contributors:  ds.Contributors,
         Tags:          ds.Tags,
-        Files:         results,
+        RootNodeId:    options.RootNodeId,
+        AbsolutePaths: rootId.Valid && options.AbsolutePaths,
+        FileUrls:      options.IncludeUrls,
     }
 
-    // Write JSON file to S3
-    _, err = s3.WriteManifestToS3(ctx, datasetNodeId, s3Key, workspaceManifest)
-    if err != nil {
-        return nil, err
+    var fileUrl func(models.DatasetManifest) (string, error)
+    if options.IncludeUrls {
+        expiry := options.UrlExpiry
+        if expiry <= 0 {
+            expiry = models.DefaultManifestUrlExpiry
+        }
+        presigner, err := s3.NewFilePresigner(ctx, expiry)
+        if err != nil {
+            return err
+        }
+        expiresAt := presigner.ExpiresAt()
+        workspaceManifest.FileUrlsExpireAt = &expiresAt
+        fileUrl = func(row models.DatasetManifest) (string, error) {
+            if !row.S3Bucket.Valid || !row.S3Key.Valid {
+                return "", nil
+            }
+            return presigner.PresignUrl(row.S3Bucket.String, row.S3Key.String)
+        }
     }
 
-    // Create Presigned URL for file on S3
-    presignedUrl, err := s3.GetPresignedUrl(ctx, s.S3ManifestBucket, s3Key)
+    // A BagIt bag is written as its tag files. The payload manifest is streamed, the others are small and
+    // depend on the whole payload.
+    uploadKey := s3Key
+    if format == models.BagItManifest {
+        uploadKey = bagItKey(s3Key, models.BagItPayloadManifest)
+    }
+
+    reader, writer := io.Pipe()
+    uploaded := make(chan error, 1)
+    go func() {
+        _, err := s3.WriteManifestToS3(ctx, uploadKey, format, reader)
+        // Stops the encoder if the upload ends before the manifest does
+        reader.CloseWithError(err)
+        uploaded <- err
+    }()
+
+    encoder := workspaceManifest.NewEncoder(writer, format)
+    err = streamManifest(ctx, q, ds.Id, rootId, rootPath, fileUrl, encoder)
+    if err == nil {
+        err = encoder.Close()
+    }
+    // Ends the upload, which is aborted if the manifest is incomplete
+    writer.CloseWithError(err)
+    if uploadErr := <-uploaded; err == nil {
+        err = uploadErr
+    }
     if err != nil {
-        return nil, err
+        return err
     }
 
-    result := models.ManifestResult{
-        Url:      presignedUrl.String(),
-        S3Bucket: s.S3ManifestBucket,
-        S3Key:    s3Key,
+    if bagIt, ok := encoder.(*models.BagItEncoder); ok {
+        for _, tagFile := range bagIt.TagFiles() {
+            if err := s3.WriteObject(ctx, bagItKey(s3Key, tagFile.Name), format.ContentType(), tagFile.Content); err != nil {
+                return err
+            }
+        }
     }
+    return nil
+}
 
-    return &result, nil
+// manifestRoot returns the id of the live collection rootNodeId, which a manifest is limited to, and the path its
+// file paths start at. Both are empty for a manifest of the whole dataset.
+func (s *datasetsService) manifestRoot(ctx context.Context, q store.DatasetsStore, datasetNodeId string, datasetId int64, rootNodeId string, absolutePaths bool) (sql.NullInt64, string, error) {
+    root, err := s.getLiveRootCollection(ctx, q, datasetNodeId, datasetId, rootNodeId)
+    if err != nil || root == nil {
+        return sql.NullInt64{}, "", err
+    }
+    rootPath := ""
+    if absolutePaths {
+        if rootPath, err = collectionPath(ctx, q, datasetId, root); err != nil {
+            return sql.NullInt64{}, "", err
+        }
+    }
+    return sql.NullInt64{Int64: root.Id, Valid: true}, rootPath, nil
+}
 
+// collectionPath returns the path of the collection from the dataset root, including its own name.
+func collectionPath(ctx context.Context, q store.DatasetsStore, datasetId int64, collection *pgdb.Package) (string, error) {
+    ancestors, err := q.GetPackageAncestors(ctx, datasetId, collection.Id)
+    if err != nil {
+        return "", err
+    }
+    names := make([]string, 0, len(ancestors)+1)
+    for _, ancestor := range ancestors {
+        names = append(names, ancestor.Name)
+    }
+    return strings.Join(append(names, collection.Name), "/"), nil
 }
 
+// streamManifest passes each row of the dataset's manifest to the encoder with its full path. Rows come in
+// path order, so the path of each collection is known before its contents are reached and only the collection
+// paths need to be kept. If rootId is valid only the contents of that collection are passed, with
+// paths starting at rootPath. If fileUrl is not nil it returns the URL of each file.
+func streamManifest(ctx context.Context, q store.DatasetsStore, datasetId int64, rootId sql.NullInt64, rootPath string, fileUrl func(models.DatasetManifest) (string, error), encoder models.ManifestEncoder) error {
+    collectionPaths := make(map[int64]string)
+    if rootId.Valid {
+        collectionPaths[rootId.Int64] = rootPath
+    }
+    return q.StreamDatasetManifest(ctx, datasetId, rootId, func(row models.DatasetManifest) error {
+        // The last element of row.Path is the parent, the first is the NULL parent of the top level
+        parentPath := ""
+        if last := len(row.Path) - 1; last >= 0 && row.Path[last].Valid {
+            var ok bool
+            if parentPath, ok = collectionPaths[row.Path[last].Int64]; !ok {
+                return fmt.Errorf("manifest row for package %s came before its parent %d", row.PackageNodeId, row.Path[last].Int64)
+            }
+        }
+
+        if strings.HasPrefix(row.PackageNodeId, "N:collection") {
+            collectionPath := row.PackageName
+            if len(parentPath) > 0 {
+                collectionPath = parentPath + "/" + collectionPath
+            }
+            collectionPaths[int64(row.PackageId)] = collectionPath
+            return encoder.WriteCollection(collectionPath)
+        }
+        file := models.ManifestDTO{
+            PackageName:   row.PackageName,
+            FileNodeId:    row.FileUUID,
+            FileName:      row.FileName,
+            Path:          parentPath,
+            PackageNodeId: row.PackageNodeId,
+            Size:          row.Size,
+            CheckSum:      row.CheckSum,
+        }
+        if fileUrl != nil {
+            var err error
+            if file.Url, err = fileUrl(row); err != nil {
+                return err
+            }
+        }
+        return encoder.WriteFile(file)
+    })
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
@@ -17,105 +18,141 @@ import (
 	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetTrashcanPageDeleting(t *testing.T) {
 	limit := 100
 	offset := 0
+	orgId := 2
+	datasetNodeId := "N:dataset:149b65da-6803-4a67-bf20-83076774a5c7"
+
+	db := store.OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("show-deleting-test.sql")
+	defer db.Truncate(orgId, "packages")
+
+	// every package is owned by user 1
+	owner := &models.TrashcanUser{}
+	var firstName, lastName string
+	err := db.QueryRow("SELECT node_id, first_name, last_name FROM pennsieve.users WHERE id = 1").Scan(&owner.NodeId, &firstName, &lastName)
+	if errors.Is(err, sql.ErrNoRows) {
+		owner = nil
+	} else if assert.NoError(t, err) {
+		owner.Name = strings.TrimSpace(firstName + " " + lastName)
+	}
+
 	rootNodeIdToExpectedPage := map[string]*models.TrashcanPage{
 		"": {
-			Limit: limit, Offset: offset, TotalCount: 2, Packages: []models.TrashcanItem{
+			Limit: limit, Offset: offset, TotalCount: ptr(2), Packages: []models.TrashcanItem{
 				{
-					ID:     4,
-					Name:   "root-dir-1",
-					NodeId: "N:collection:82c127ca-b72b-4d8b-a0c3-a9e4c7b14654",
-					Type:   packageType.Collection.String(),
-					State:  packageState.Ready.String(),
+					ID:         4,
+					Name:       "root-dir-1",
+					NodeId:     "N:collection:82c127ca-b72b-4d8b-a0c3-a9e4c7b14654",
+					Type:       packageType.Collection.String(),
+					State:      packageState.Ready.String(),
+					Owner:      owner,
+					ParentPath: "",
 				},
 				{
-					ID:     5,
-					Name:   "root-dir-2",
-					NodeId: "N:collection:d6542ca3-31a4-473f-a7ab-490ca4fddc63",
-					Type:   packageType.Collection.String(),
-					State:  packageState.Ready.String(),
+					ID:         5,
+					Name:       "root-dir-2",
+					NodeId:     "N:collection:d6542ca3-31a4-473f-a7ab-490ca4fddc63",
+					Type:       packageType.Collection.String(),
+					State:      packageState.Ready.String(),
+					Owner:      owner,
+					ParentPath: "",
 				},
 			},
 			Messages: []string{},
 		},
 		"N:collection:36cb9fb0-f72a-42fd-bcac-959ecb866279": {
-			Limit: limit, Offset: offset, TotalCount: 0, Packages: []models.TrashcanItem{}, Messages: []string{},
+			Limit: limit, Offset: offset, TotalCount: ptr(0), Packages: []models.TrashcanItem{}, Messages: []string{},
 		}, // an empty directory
 		"N:collection:82c127ca-b72b-4d8b-a0c3-a9e4c7b14654": {
-			Limit: limit, Offset: offset, TotalCount: 2, Packages: []models.TrashcanItem{
+			Limit: limit, Offset: offset, TotalCount: ptr(2), Packages: []models.TrashcanItem{
 				{
-					ID:     9,
-					Name:   "one-dir-deleting-1",
-					NodeId: "N:collection:e9bfe050-b375-43a1-91ec-b519439ad011",
-					Type:   packageType.Collection.String(),
-					State:  packageState.Deleting.String(),
+					ID:         9,
+					Name:       "one-dir-deleting-1",
+					NodeId:     "N:collection:e9bfe050-b375-43a1-91ec-b519439ad011",
+					Type:       packageType.Collection.String(),
+					State:      packageState.Deleting.String(),
+					DeletedAt:  ptr(time.Date(2023, 2, 2, 19, 45, 6, 932059000, time.UTC)),
+					Owner:      owner,
+					ParentPath: "root-dir-1",
 				},
 				{
-					ID:     13,
-					Name:   "one-dir-empty-deleting-1",
-					NodeId: "N:collection:113d3c44-af35-408f-9fcc-0e4aa0b20a5d",
-					Type:   packageType.Collection.String(),
-					State:  packageState.Deleting.String(),
+					ID:         13,
+					Name:       "one-dir-empty-deleting-1",
+					NodeId:     "N:collection:113d3c44-af35-408f-9fcc-0e4aa0b20a5d",
+					Type:       packageType.Collection.String(),
+					State:      packageState.Deleting.String(),
+					DeletedAt:  ptr(time.Date(2023, 2, 2, 19, 45, 7, 164210000, time.UTC)),
+					Owner:      owner,
+					ParentPath: "root-dir-1",
 				},
 			},
 			Messages: []string{},
 		},
 		"N:collection:d6542ca3-31a4-473f-a7ab-490ca4fddc63": {
-			Limit: limit, Offset: offset, TotalCount: 1, Packages: []models.TrashcanItem{
+			Limit: limit, Offset: offset, TotalCount: ptr(1), Packages: []models.TrashcanItem{
 				{
-					ID:     15,
-					Name:   "one-dir-1",
-					NodeId: "N:collection:f4136743-e930-401e-88bb-e7ef34789a88",
-					Type:   packageType.Collection.String(),
-					State:  packageState.Ready.String(),
+					ID:         15,
+					Name:       "one-dir-1",
+					NodeId:     "N:collection:f4136743-e930-401e-88bb-e7ef34789a88",
+					Type:       packageType.Collection.String(),
+					State:      packageState.Ready.String(),
+					Owner:      owner,
+					ParentPath: "root-dir-2",
 				},
 			},
 			Messages: []string{},
 		},
 		"N:collection:e9bfe050-b375-43a1-91ec-b519439ad011": { // only set to DELETING. It's contents still have non-DELET* states and so expect an empty page.
-			Limit: limit, Offset: offset, TotalCount: 0, Packages: []models.TrashcanItem{}, Messages: []string{},
+			Limit: limit, Offset: offset, TotalCount: ptr(0), Packages: []models.TrashcanItem{}, Messages: []string{},
 		},
 		"N:collection:113d3c44-af35-408f-9fcc-0e4aa0b20a5d": { // only set to DELETING. And it is empty, so nothing to show
-			Limit: limit, Offset: offset, TotalCount: 0, Packages: []models.TrashcanItem{}, Messages: []string{},
+			Limit: limit, Offset: offset, TotalCount: ptr(0), Packages: []models.TrashcanItem{}, Messages: []string{},
 		},
 		"N:collection:f4136743-e930-401e-88bb-e7ef34789a88": {
-			Limit: limit, Offset: offset, TotalCount: 1, Packages: []models.TrashcanItem{
+			Limit: limit, Offset: offset, TotalCount: ptr(1), Packages: []models.TrashcanItem{
 				{
-					ID:     25,
-					Name:   "two-file-deleting-1.csv",
-					NodeId: "N:package:d9ee5d8f-0f27-4179-ae9e-8b914a719543",
-					Type:   packageType.CSV.String(),
-					State:  packageState.Deleting.String(),
+					ID:         25,
+					Name:       "two-file-deleting-1.csv",
+					NodeId:     "N:package:d9ee5d8f-0f27-4179-ae9e-8b914a719543",
+					Type:       packageType.CSV.String(),
+					State:      packageState.Deleting.String(),
+					DeletedAt:  ptr(time.Date(2023, 2, 2, 19, 45, 7, 531309000, time.UTC)),
+					Owner:      owner,
+					ParentPath: "root-dir-2/one-dir-1",
 				},
 			},
 			Messages: []string{},
 		},
 	}
-	orgId := 2
-	datasetNodeId := "N:dataset:149b65da-6803-4a67-bf20-83076774a5c7"
-
-	db := store.OpenDB(t)
-	defer db.Close()
-
-	db.ExecSQLFile("show-deleting-test.sql")
-	defer db.Truncate(orgId, "packages")
 
 	mfBucket := getEnv("MANIFEST_FILES_BUCKET", "manifest-files-bucket")
 
 	service := NewDatasetsService(db.DB, getS3Client(), &MockSnSClient{}, &models.HandlerVars{S3Bucket: mfBucket}, orgId)
 	for rootId, expectedPage := range rootNodeIdToExpectedPage {
 		t.Run(fmt.Sprintf("GetTrashcanPage starting at folder %s", rootId), func(t *testing.T) {
-			actual, err := service.GetTrashcanPage(context.Background(), datasetNodeId, rootId, limit, offset)
-			if assert.NoError(t, err) {
+			actual, err := service.GetTrashcanPage(context.Background(), datasetNodeId, rootId, models.TrashcanFilter{}, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: limit, Offset: offset})
+			if assert.NoError(t, err) && assert.Len(t, actual.Packages, len(expectedPage.Packages)) {
+				// compare deletion times separately, since they may not come back in time.UTC
+				for i := range actual.Packages {
+					if expected := expectedPage.Packages[i].DeletedAt; expected != nil && assert.NotNil(t, actual.Packages[i].DeletedAt) {
+						assert.WithinDuration(t, *expected, *actual.Packages[i].DeletedAt, 0)
+						actual.Packages[i].DeletedAt = expected
+					}
+				}
 				assert.Equal(t, expectedPage, actual)
 			}
 		})
@@ -133,13 +170,154 @@ func TestGetTrashcanPageEmpty(t *testing.T) {
 
 	mfBucket := getEnv("MANIFEST_FILES_BUCKET", "manifest-files-bucket")
 	service := NewDatasetsServiceWithFactory(&mockFactory, &mockS3Factory, &mockSnsFactory, &models.HandlerVars{S3Bucket: mfBucket}, orgId)
-	page, err := service.GetTrashcanPage(context.Background(), "N:dataset:dddd", "", 100, 0)
+	page, err := service.GetTrashcanPage(context.Background(), "N:dataset:dddd", "", models.TrashcanFilter{}, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: 100})
 	if assert.NoError(t, err) {
 		assert.NotNil(t, page.Packages)
 		assert.Empty(t, page.Packages)
 	}
 }
 
+func TestGetTrashcanPageFilterAndSort(t *testing.T) {
+	filter := models.TrashcanFilter{Name: "data", Type: "CSV", State: "DELETED", Flat: true}
+	sort := models.TrashcanSort{Field: models.TrashcanSortByDeletedAt, Order: models.Descending}
+	for tName, tData := range map[string]struct {
+		rootNodeId string
+		mockStore  MockDatasetsStore
+	}{
+		"root": {"", MockDatasetsStore{
+			GetDatasetByNodeIdReturn:           MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			CountDatasetPackagesByStatesReturn: MockReturn[int]{Value: 6},
+			GetTrashcanRootPaginatedReturn:     MockReturn[*store.PackagePage]{Value: &store.PackagePage{}},
+		}},
+		"folder": {"N:collection:5790", MockDatasetsStore{
+			GetDatasetByNodeIdReturn:           MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			CountDatasetPackagesByStatesReturn: MockReturn[int]{Value: 6},
+			GetDatasetPackageByNodeIdReturn:    MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 57, PackageType: packageType.Collection}},
+			GetTrashcanPaginatedReturn:         MockReturn[*store.PackagePage]{Value: &store.PackagePage{}},
+		}},
+	} {
+		service := NewDatasetsServiceWithFactory(&MockFactory{&tData.mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+		t.Run(tName, func(t *testing.T) {
+			_, err := service.GetTrashcanPage(context.Background(), "N:dataset:7890", tData.rootNodeId, filter, sort, models.Paging[models.TrashcanCursor]{Limit: 10})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []models.TrashcanFilter{filter}, tData.mockStore.TrashcanFilters)
+				assert.Equal(t, []models.TrashcanSort{sort}, tData.mockStore.TrashcanSorts)
+			}
+		})
+	}
+}
+
+func TestGetTrashcanPageCursor(t *testing.T) {
+	sort := models.TrashcanSort{Field: models.TrashcanSortByDeletedAt, Order: models.Descending}
+	deletedAt := time.Date(2023, 2, 3, 4, 5, 6, 7000, time.UTC)
+	packages := []store.TrashcanPackage{
+		{Package: pgdb.Package{Id: 8, Name: "a.csv", PackageType: packageType.CSV, PackageState: packageState.Deleted, UpdatedAt: deletedAt.Add(time.Hour)}},
+		{Package: pgdb.Package{Id: 3, Name: "b.csv", PackageType: packageType.CSV, PackageState: packageState.Deleted, UpdatedAt: deletedAt}},
+		{Package: pgdb.Package{Id: 5, Name: "c.csv", PackageType: packageType.CSV, PackageState: packageState.Deleted, UpdatedAt: deletedAt}},
+	}
+	after := models.NewTrashcanCursor(sort, 1, "z.csv", "CSV", deletedAt.Add(2*time.Hour), nil)
+	for tName, tData := range map[string]struct {
+		paging             models.Paging[models.TrashcanCursor]
+		storePackages      []store.TrashcanPackage
+		expectedIds        []int64
+		expectedTotalCount *int
+		expectedNextCursor string
+	}{
+		"more pages": {models.Paging[models.TrashcanCursor]{Limit: 2}, packages, []int64{8, 3}, ptr(4),
+			models.EncodeCursor(models.NewTrashcanCursor(sort, 3, "b.csv", "CSV", deletedAt, nil))},
+		"last page":           {models.Paging[models.TrashcanCursor]{Limit: 3}, packages, []int64{8, 3, 5}, ptr(4), ""},
+		"after cursor":        {models.Paging[models.TrashcanCursor]{Limit: 3, After: &after}, packages, []int64{8, 3, 5}, nil, ""},
+		"without total count": {models.Paging[models.TrashcanCursor]{Limit: 3, SkipTotal: true}, packages[:1], []int64{8}, nil, ""},
+		"zero limit":          {models.Paging[models.TrashcanCursor]{Limit: 0}, packages[:1], []int64{}, ptr(4), ""},
+	} {
+		mockStore := MockDatasetsStore{
+			GetDatasetByNodeIdReturn:           MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			CountDatasetPackagesByStatesReturn: MockReturn[int]{Value: 6},
+			GetTrashcanRootPaginatedReturn:     MockReturn[*store.PackagePage]{Value: &store.PackagePage{TotalCount: 4, Packages: tData.storePackages}},
+		}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+		t.Run(tName, func(t *testing.T) {
+			page, err := service.GetTrashcanPage(context.Background(), "N:dataset:7890", "", models.TrashcanFilter{}, sort, tData.paging)
+			if assert.NoError(t, err) {
+				actualIds := []int64{}
+				for _, p := range page.Packages {
+					actualIds = append(actualIds, p.ID)
+				}
+				assert.Equal(t, tData.expectedIds, actualIds)
+				assert.Equal(t, tData.expectedTotalCount, page.TotalCount)
+				assert.Equal(t, tData.expectedNextCursor, page.NextCursor)
+				expectedStorePaging := tData.paging
+				expectedStorePaging.Limit++
+				assert.Equal(t, []models.Paging[models.TrashcanCursor]{expectedStorePaging}, mockStore.TrashcanPagings)
+			}
+		})
+	}
+}
+
+func TestGetTrashcanPageNameCursor(t *testing.T) {
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn:           MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		CountDatasetPackagesByStatesReturn: MockReturn[int]{Value: 2},
+		GetTrashcanRootPaginatedReturn: MockReturn[*store.PackagePage]{Value: &store.PackagePage{TotalCount: 2, Packages: []store.TrashcanPackage{
+			{Package: pgdb.Package{Id: 8, Name: "__DELETED__N:package:1234_a.csv", NodeId: "N:package:1234", PackageType: packageType.CSV, PackageState: packageState.Deleted}},
+			{Package: pgdb.Package{Id: 3, Name: "__DELETED__N:package:5678_b.csv", NodeId: "N:package:5678", PackageType: packageType.CSV, PackageState: packageState.Deleted}},
+		}}},
+	}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+	page, err := service.GetTrashcanPage(context.Background(), "N:dataset:7890", "", models.TrashcanFilter{}, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: 1})
+	if assert.NoError(t, err) {
+		// the store compares cursor names with the names packages had before deletion
+		assert.Equal(t, models.EncodeCursor(models.NewTrashcanCursor(models.DefaultTrashcanSort, 8, "a.csv", "CSV", time.Time{}, nil)), page.NextCursor)
+	}
+}
+
+func TestGetTrashcanPageItemDetails(t *testing.T) {
+	deletedAt := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn:           MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		CountDatasetPackagesByStatesReturn: MockReturn[int]{Value: 6},
+		GetTrashcanRootPaginatedReturn: MockReturn[*store.PackagePage]{Value: &store.PackagePage{TotalCount: 3, Packages: []store.TrashcanPackage{
+			{
+				Package:        pgdb.Package{Id: 8, Name: "__DELETED__N:package:8_a.csv", NodeId: "N:package:8", PackageType: packageType.CSV, PackageState: packageState.Deleted, ParentId: sql.NullInt64{Int64: 4, Valid: true}, Size: sql.NullInt64{Int64: 2048, Valid: true}, UpdatedAt: deletedAt},
+				OwnerNodeId:    sql.NullString{String: "N:user:1", Valid: true},
+				OwnerFirstName: sql.NullString{String: "Ada", Valid: true},
+				OwnerLastName:  sql.NullString{String: "Lovelace", Valid: true},
+			},
+			{
+				Package:        pgdb.Package{Id: 3, Name: "__DELETED__N:collection:3_b", NodeId: "N:collection:3", PackageType: packageType.Collection, PackageState: packageState.Deleting, ParentId: sql.NullInt64{Int64: 4, Valid: true}, UpdatedAt: deletedAt},
+				OwnerNodeId:    sql.NullString{String: "N:user:2", Valid: true},
+				OwnerFirstName: sql.NullString{String: "Grace", Valid: true},
+				OwnerLastName:  sql.NullString{String: "", Valid: true},
+			},
+			{
+				Package: pgdb.Package{Id: 5, Name: "c.csv", NodeId: "N:package:5", PackageType: packageType.CSV, PackageState: packageState.Deleted, UpdatedAt: deletedAt},
+			},
+			{
+				Package:        pgdb.Package{Id: 6, Name: "live", NodeId: "N:collection:6", PackageType: packageType.Collection, PackageState: packageState.Ready, UpdatedAt: deletedAt},
+				OwnerNodeId:    sql.NullString{String: "N:user:1", Valid: true},
+				OwnerFirstName: sql.NullString{String: "Ada", Valid: true},
+				OwnerLastName:  sql.NullString{String: "Lovelace", Valid: true},
+			},
+		}}},
+		GetPackagePathsReturn: MockReturn[map[int64]string]{Value: map[int64]string{4: "root-dir/sub-dir"}},
+	}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+	page, err := service.GetTrashcanPage(context.Background(), "N:dataset:7890", "", models.TrashcanFilter{Flat: true}, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: 10})
+	if assert.NoError(t, err) {
+		assert.Equal(t, [][]int64{{4}}, mockStore.PackagePathsCalls)
+		assert.Equal(t, []models.TrashcanItem{
+			{ID: 8, Name: "a.csv", NodeId: "N:package:8", Type: "CSV", State: "DELETED", Size: ptr(int64(2048)), DeletedAt: &deletedAt,
+				Owner: &models.TrashcanUser{NodeId: "N:user:1", Name: "Ada Lovelace"}, ParentPath: "root-dir/sub-dir"},
+			{ID: 3, Name: "b", NodeId: "N:collection:3", Type: "Collection", State: "DELETING", DeletedAt: &deletedAt,
+				Owner: &models.TrashcanUser{NodeId: "N:user:2", Name: "Grace"}, ParentPath: "root-dir/sub-dir"},
+			{ID: 5, Name: "c.csv", NodeId: "N:package:5", Type: "CSV", State: "DELETED", DeletedAt: &deletedAt},
+			// live folders are only in the trashcan because of what is below them, so they have no deletion time
+			{ID: 6, Name: "live", NodeId: "N:collection:6", Type: "Collection", State: "READY",
+				Owner: &models.TrashcanUser{NodeId: "N:user:1", Name: "Ada Lovelace"}},
+		}, page.Packages)
+	}
+}
+
 func TestGetTrashcanPageErrors(t *testing.T) {
 	orgId := 7
 	for tName, expected := range map[string]struct {
@@ -170,6 +348,14 @@ func TestGetTrashcanPageErrors(t *testing.T) {
 			CountDatasetPackagesByStatesReturn: MockReturn[int]{Value: 6},
 			GetTrashcanRootPaginatedReturn:     MockReturn[*store.PackagePage]{Error: errors.New("unexpected root error")},
 		}},
+		"unexpected package paths error": {"", MockDatasetsStore{
+			GetDatasetByNodeIdReturn:           MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			CountDatasetPackagesByStatesReturn: MockReturn[int]{Value: 6},
+			GetTrashcanRootPaginatedReturn: MockReturn[*store.PackagePage]{Value: &store.PackagePage{TotalCount: 1, Packages: []store.TrashcanPackage{
+				{Package: pgdb.Package{Id: 8, ParentId: sql.NullInt64{Int64: 4, Valid: true}}},
+			}}},
+			GetPackagePathsReturn: MockReturn[map[int64]string]{Error: errors.New("unexpected paths error")},
+		}},
 	} {
 		mockFactory := MockFactory{&expected.mockStore, -1}
 		mockS3Factory := MockS3Factory{}
@@ -179,7 +365,7 @@ func TestGetTrashcanPageErrors(t *testing.T) {
 
 		service := NewDatasetsServiceWithFactory(&mockFactory, &mockS3Factory, &mockSnsFactory, &models.HandlerVars{S3Bucket: mfBucket, SnsTopic: snsTopic}, orgId)
 		t.Run(tName, func(t *testing.T) {
-			_, err := service.GetTrashcanPage(context.Background(), "N:dataset:7890", expected.rootNodeId, 10, 0)
+			_, err := service.GetTrashcanPage(context.Background(), "N:dataset:7890", expected.rootNodeId, models.TrashcanFilter{}, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: 10})
 			if assert.Error(t, err) {
 				assert.Equal(t, expected.mockStore.getExpectedErrors(), []error{err})
 				assert.Equal(t, orgId, mockFactory.orgId)
@@ -188,7 +374,229 @@ func TestGetTrashcanPageErrors(t *testing.T) {
 	}
 }
 
-func TestGetManifest(t *testing.T) {
+func TestGetTrashcanSummary(t *testing.T) {
+	deletedAt := time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC)
+	storeSummary := &models.TrashcanSummary{PackageCount: 4, FolderCount: 1, FileCount: 3, TotalSize: 4096, OldestDeletedAt: &deletedAt, NewestDeletedAt: &deletedAt}
+	for tName, tData := range map[string]struct {
+		rootNodeId     string
+		rootPackage    *pgdb.Package
+		expectedRootId sql.NullInt64
+	}{
+		"whole trashcan": {"", nil, sql.NullInt64{}},
+		"below folder":   {"N:collection:57", &pgdb.Package{Id: 57, PackageType: packageType.Collection}, sql.NullInt64{Int64: 57, Valid: true}},
+	} {
+		mockStore := MockDatasetsStore{
+			GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: tData.rootPackage},
+			GetTrashcanSummaryReturn:        MockReturn[*models.TrashcanSummary]{Value: storeSummary},
+		}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+		t.Run(tName, func(t *testing.T) {
+			summary, err := service.GetTrashcanSummary(context.Background(), "N:dataset:7890", tData.rootNodeId)
+			if assert.NoError(t, err) {
+				assert.Equal(t, storeSummary, summary)
+				assert.Equal(t, []sql.NullInt64{tData.expectedRootId}, mockStore.TrashcanSummaryRootIds)
+			}
+		})
+	}
+}
+
+func TestGetTrashcanSummaryErrors(t *testing.T) {
+	orgId := 7
+	notFound := models.DatasetNotFoundError{OrgId: orgId, Id: models.DatasetNodeId("N:dataset:7890")}
+	summaryErr := errors.New("unexpected summary error")
+	for tName, tData := range map[string]struct {
+		rootNodeId string
+		mockStore  MockDatasetsStore
+		expected   error
+	}{
+		"dataset not found error": {"", MockDatasetsStore{
+			GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Error: notFound}}, notFound},
+		"folder not found error": {"N:package:5790", MockDatasetsStore{
+			GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 57, PackageType: packageType.CSV}}},
+			models.FolderNotFoundError{OrgId: orgId, NodeId: "N:package:5790", DatasetId: models.DatasetNodeId("N:dataset:7890"), ActualType: packageType.CSV}},
+		"unexpected summary error": {"", MockDatasetsStore{
+			GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetTrashcanSummaryReturn: MockReturn[*models.TrashcanSummary]{Error: summaryErr}}, summaryErr},
+	} {
+		service := NewDatasetsServiceWithFactory(&MockFactory{&tData.mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, orgId)
+		t.Run(tName, func(t *testing.T) {
+			_, err := service.GetTrashcanSummary(context.Background(), "N:dataset:7890", tData.rootNodeId)
+			assert.Equal(t, tData.expected, err)
+		})
+	}
+}
+
+func TestExportTrashcan(t *testing.T) {
+	deletedAt := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	owner := sql.NullString{String: "N:user:1", Valid: true}
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		GetTrashcanTreeReturn: MockReturn[[]store.TrashcanPackage]{Value: []store.TrashcanPackage{
+			{Package: pgdb.Package{Id: 5, Name: "c.csv", NodeId: "N:package:5", PackageType: packageType.CSV, PackageState: packageState.Deleted, UpdatedAt: deletedAt}},
+			{Package: pgdb.Package{Id: 4, Name: "root-dir", NodeId: "N:collection:4", PackageType: packageType.Collection, PackageState: packageState.Ready, UpdatedAt: deletedAt}},
+			{Package: pgdb.Package{Id: 8, Name: "a.csv", NodeId: "N:package:8", PackageType: packageType.CSV, PackageState: packageState.Deleting, ParentId: sql.NullInt64{Int64: 4, Valid: true}, Size: sql.NullInt64{Int64: 2048, Valid: true}, UpdatedAt: deletedAt},
+				OwnerNodeId: owner, OwnerFirstName: sql.NullString{String: "Ada", Valid: true}, OwnerLastName: sql.NullString{String: "Lovelace", Valid: true}},
+			{Package: pgdb.Package{Id: 9, Name: "__DELETED__N:collection:9_old", NodeId: "N:collection:9", PackageType: packageType.Collection, PackageState: packageState.Deleted, ParentId: sql.NullInt64{Int64: 4, Valid: true}, UpdatedAt: deletedAt}},
+			{Package: pgdb.Package{Id: 10, Name: "__DELETED__N:package:10_b.csv", NodeId: "N:package:10", PackageType: packageType.CSV, PackageState: packageState.Deleted, ParentId: sql.NullInt64{Int64: 9, Valid: true}, UpdatedAt: deletedAt}},
+		}},
+	}
+	mockS3Store := MockS3Store{}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, 7)
+
+	result, err := service.ExportTrashcan(context.Background(), "N:dataset:1234")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "manifest-bucket", result.S3Bucket)
+	assert.True(t, strings.HasPrefix(result.S3Key, "1234/trashcan/1234_"), result.S3Key)
+	assert.Equal(t, "https://manifest-bucket/"+result.S3Key, result.Url)
+	assert.Equal(t, 4, result.DeletedCount)
+	if assert.Contains(t, mockS3Store.Objects, result.S3Key) {
+		// the report uses the same snake_case keys as its items
+		assert.Contains(t, string(mockS3Store.Objects[result.S3Key]), `{"dataset_node_id":"N:dataset:1234","exported_at":`)
+		assert.Contains(t, string(mockS3Store.Objects[result.S3Key]), `"deleted_count":4,"items":[`)
+		var export models.TrashcanExport
+		if assert.NoError(t, json.Unmarshal(mockS3Store.Objects[result.S3Key], &export)) {
+			assert.Equal(t, "N:dataset:1234", export.DatasetNodeId)
+			assert.Equal(t, 4, export.DeletedCount)
+			assert.Equal(t, []*models.TrashcanExportItem{
+				{NodeId: "N:package:5", Name: "c.csv", Type: "CSV", State: "DELETED", Path: "c.csv", Deleted: true, DeletedAt: &deletedAt},
+				{NodeId: "N:collection:4", Name: "root-dir", Type: "Collection", State: "READY", Path: "root-dir", Children: []*models.TrashcanExportItem{
+					{NodeId: "N:package:8", Name: "a.csv", Type: "CSV", State: "DELETING", Path: "root-dir/a.csv", Size: ptr(int64(2048)), Deleted: true, DeletedAt: &deletedAt,
+						Owner: &models.TrashcanUser{NodeId: "N:user:1", Name: "Ada Lovelace"}},
+					{NodeId: "N:collection:9", Name: "old", Type: "Collection", State: "DELETED", Path: "root-dir/old", Deleted: true, DeletedAt: &deletedAt, Children: []*models.TrashcanExportItem{
+						{NodeId: "N:package:10", Name: "b.csv", Type: "CSV", State: "DELETED", Path: "root-dir/old/b.csv", Deleted: true, DeletedAt: &deletedAt},
+					}},
+				}},
+			}, export.Items)
+		}
+	}
+}
+
+func TestExportTrashcanEmpty(t *testing.T) {
+	mockStore := MockDatasetsStore{GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}}}
+	mockS3Store := MockS3Store{}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, 7)
+
+	result, err := service.ExportTrashcan(context.Background(), "N:dataset:1234")
+	if assert.NoError(t, err) && assert.Contains(t, mockS3Store.Objects, result.S3Key) {
+		assert.Equal(t, 0, result.DeletedCount)
+		assert.Contains(t, string(mockS3Store.Objects[result.S3Key]), `"items":[]`)
+	}
+}
+
+func TestExportTrashcanErrors(t *testing.T) {
+	orgId := 7
+	for tName, mockStore := range map[string]MockDatasetsStore{
+		"dataset not found error": {
+			GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Error: models.DatasetNotFoundError{OrgId: orgId, Id: models.DatasetNodeId("N:dataset:1234")}}},
+		"unexpected trashcan error": {
+			GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetTrashcanTreeReturn:    MockReturn[[]store.TrashcanPackage]{Error: errors.New("unexpected trashcan error")}},
+	} {
+		mockS3Store := MockS3Store{}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, orgId)
+		t.Run(tName, func(t *testing.T) {
+			_, err := service.ExportTrashcan(context.Background(), "N:dataset:1234")
+			if assert.Error(t, err) {
+				assert.Equal(t, mockStore.getExpectedErrors(), []error{err})
+				assert.Empty(t, mockS3Store.Objects)
+			}
+		})
+	}
+}
+
+func TestPackagesListing(t *testing.T) {
+	orgId := 7
+	created := time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC)
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 57, PackageType: packageType.Collection, PackageState: packageState.Ready}},
+		GetLivePackagesPaginatedReturn: MockReturn[*store.LivePackagePage]{Value: &store.LivePackagePage{TotalCount: 12, Packages: []store.LivePackage{
+			{Package: pgdb.Package{Id: 60, Name: "data", NodeId: "N:collection:60", PackageType: packageType.Collection, PackageState: packageState.Ready, CreatedAt: created}, ChildCount: 3},
+			{Package: pgdb.Package{Id: 61, Name: "data.csv", NodeId: "N:package:61", PackageType: packageType.CSV, PackageState: packageState.Uploaded, Size: sql.NullInt64{Int64: 2048, Valid: true}, CreatedAt: created}},
+		}}},
+	}
+	mockFactory := MockFactory{&mockStore, -1}
+	service := NewDatasetsServiceWithFactory(&mockFactory, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, orgId)
+	sort := models.PackageSort{Field: models.SortBySize, Order: models.Descending}
+
+	page, err := service.GetPackagesPage(context.Background(), "N:dataset:7890", "N:collection:57", sort, 2, 4)
+	if assert.NoError(t, err) {
+		childCount := 3
+		size := int64(2048)
+		assert.Equal(t, &models.PackagesPage{Limit: 2, Offset: 4, TotalCount: 12, Packages: []models.PackageItem{
+			{ID: 60, Name: "data", NodeId: "N:collection:60", Type: packageType.Collection.String(), State: packageState.Ready.String(), CreatedAt: created, ChildCount: &childCount},
+			{ID: 61, Name: "data.csv", NodeId: "N:package:61", Type: packageType.CSV.String(), State: packageState.Uploaded.String(), Size: &size, CreatedAt: created},
+		}}, page)
+		assert.Equal(t, []LivePackagesCall{{ParentId: sql.NullInt64{Int64: 57, Valid: true}, Sort: sort, Limit: 2, Offset: 4}}, mockStore.LivePackagesCalls)
+		assert.Equal(t, orgId, mockFactory.orgId)
+	}
+}
+
+func TestPackagesListingRoot(t *testing.T) {
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn:       MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		GetLivePackagesPaginatedReturn: MockReturn[*store.LivePackagePage]{Value: &store.LivePackagePage{Packages: []store.LivePackage{}}},
+	}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+
+	page, err := service.GetPackagesPage(context.Background(), "N:dataset:7890", "", models.DefaultPackageSort, 10, 0)
+	if assert.NoError(t, err) {
+		assert.NotNil(t, page.Packages)
+		assert.Empty(t, page.Packages)
+		assert.Equal(t, []LivePackagesCall{{Sort: models.DefaultPackageSort, Limit: 10}}, mockStore.LivePackagesCalls)
+	}
+}
+
+func TestPackagesListingErrors(t *testing.T) {
+	orgId := 7
+	for tName, tData := range map[string]struct {
+		rootNodeId string
+		mockStore  MockDatasetsStore
+		expected   error
+	}{
+		"dataset not found error": {"", MockDatasetsStore{
+			GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Error: models.DatasetNotFoundError{OrgId: orgId, Id: models.DatasetNodeId("N:dataset:7890")}}},
+			models.DatasetNotFoundError{OrgId: orgId, Id: models.DatasetNodeId("N:dataset:7890")}},
+		"folder not found error": {"N:package:5790", MockDatasetsStore{
+			GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 57, PackageType: packageType.CSV}}},
+			models.FolderNotFoundError{OrgId: orgId, NodeId: "N:package:5790", DatasetId: models.DatasetNodeId("N:dataset:7890"), ActualType: packageType.CSV}},
+		"deleted folder": {"N:collection:5790", MockDatasetsStore{
+			GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 57, PackageType: packageType.Collection, PackageState: packageState.Deleted}}},
+			models.PackageNotFoundError{Id: models.PackageNodeId("N:collection:5790"), OrgId: orgId, DatasetId: models.DatasetNodeId("N:dataset:7890")}},
+		"folder in deleted folder": {"N:collection:5790", MockDatasetsStore{
+			GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 57, PackageType: packageType.Collection, PackageState: packageState.Ready}},
+			GetPackageAncestorsReturn: MockReturn[[]pgdb.Package]{Value: []pgdb.Package{
+				{Id: 2, PackageType: packageType.Collection, PackageState: packageState.Ready},
+				{Id: 3, PackageType: packageType.Collection, PackageState: packageState.Deleted},
+			}}},
+			models.PackageNotFoundError{Id: models.PackageNodeId("N:collection:5790"), OrgId: orgId, DatasetId: models.DatasetNodeId("N:dataset:7890")}},
+		"unexpected ancestors error": {"N:collection:5790", MockDatasetsStore{
+			GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 57, PackageType: packageType.Collection, PackageState: packageState.Ready}},
+			GetPackageAncestorsReturn:       MockReturn[[]pgdb.Package]{Error: errors.New("unexpected ancestors error")}},
+			errors.New("unexpected ancestors error")},
+		"unexpected listing error": {"", MockDatasetsStore{
+			GetDatasetByNodeIdReturn:       MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetLivePackagesPaginatedReturn: MockReturn[*store.LivePackagePage]{Error: errors.New("unexpected listing error")}},
+			errors.New("unexpected listing error")},
+	} {
+		service := NewDatasetsServiceWithFactory(&MockFactory{&tData.mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, orgId)
+		t.Run(tName, func(t *testing.T) {
+			_, err := service.GetPackagesPage(context.Background(), "N:dataset:7890", tData.rootNodeId, models.DefaultPackageSort, 10, 0)
+			assert.Equal(t, tData.expected, err)
+		})
+	}
+}
+
+// TestGenerateManifest runs a manifest job the way the manifest worker does, against the test database and S3.
+func TestGenerateManifest(t *testing.T) {
 	datasetNodeId := "N:dataset:149b65da-6803-4a67-bf20-83076774a5c7"
 
 	orgId := 2
@@ -199,7 +607,7 @@ func TestGetManifest(t *testing.T) {
 	db.Truncate(orgId, "datasets")
 	db.Truncate(orgId, "packages")
 	db.Truncate(orgId, "files")
-	
+
 	db.ExecSQLFile("manifest-test.sql")
 	defer func() {
 		db.Truncate(orgId, "packages")
@@ -216,8 +624,12 @@ func TestGetManifest(t *testing.T) {
 	snsClient := MockSnSClient{}
 	service := NewDatasetsService(db.DB, s3Client, &snsClient, &handleVars, orgId)
 
-	result, err := service.GetManifest(context.Background(), datasetNodeId)
-	assert.NoError(t, err)
+	result, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{Force: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	input := models.ManifestWorkerInput{OrgIntId: orgId, DatasetNodeId: datasetNodeId, JobId: result.JobId, ManifestS3Key: result.S3Key}
+	assert.NoError(t, service.ProcessManifestJob(context.Background(), input))
 
 	// Reading file from S3 which should contain array of manifestFile objects
 	testResult, err := readS3Object(s3Client, mfBucket, result.S3Key)
@@ -265,6 +677,624 @@ func readS3Object(client *s3.Client, bucket string, key string) (*models.Workspa
 	return results, nil
 }
 
+func TestTriggerAsyncGetManifest(t *testing.T) {
+	orgId := 7
+	datasetNodeId := "N:dataset:1234"
+	updatedAt := time.Date(2023, 5, 17, 10, 4, 32, 0, time.UTC)
+	mockStore := MockDatasetsStore{GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13, UpdatedAt: updatedAt}}}
+	mockS3Store := MockS3Store{}
+	mockSnsStore := MockSnsStore{}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{S3Bucket: "manifest-bucket", SnsTopic: "manifest-topic"}, orgId)
+
+	result, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{})
+	if assert.NoError(t, err) {
+		assert.NotEmpty(t, result.JobId)
+		assert.Equal(t, models.CREATING, result.Status)
+		assert.Equal(t, "1234/1234_2023-05-17T10_04_32Z.json", result.S3Key)
+		assert.Empty(t, result.Url)
+		if assert.Contains(t, mockS3Store.Jobs, result.JobId) {
+			job := mockS3Store.Jobs[result.JobId]
+			assert.Equal(t, models.CREATING, job.Status)
+			assert.Equal(t, datasetNodeId, job.DatasetNodeId)
+			assert.Equal(t, result.S3Key, job.S3Key)
+		}
+		assert.Equal(t, []models.ManifestWorkerInput{{OrgIntId: orgId, DatasetNodeId: datasetNodeId, JobId: result.JobId, ManifestS3Key: result.S3Key}}, mockSnsStore.ManifestInputs)
+	}
+}
+
+func TestManifestFormat(t *testing.T) {
+	orgId := 7
+	datasetNodeId := "N:dataset:1234"
+	updatedAt := time.Date(2023, 5, 17, 10, 4, 32, 0, time.UTC)
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13, UpdatedAt: updatedAt}},
+		GetManifestReturn:        MockReturn[[]models.DatasetManifest]{Value: []models.DatasetManifest{}},
+	}
+	mockS3Store := MockS3Store{}
+	mockSnsStore := MockSnsStore{}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, orgId)
+
+	result, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{Format: models.TSVManifest})
+	if assert.NoError(t, err) && assert.Len(t, mockSnsStore.ManifestInputs, 1) {
+		assert.Equal(t, "1234/1234_2023-05-17T10_04_32Z.tsv", result.S3Key)
+		input := mockSnsStore.ManifestInputs[0]
+		assert.Equal(t, models.TSVManifest, input.Format)
+
+		if assert.NoError(t, service.ProcessManifestJob(context.Background(), input)) {
+			assert.Equal(t, models.TSVManifest, mockS3Store.Formats[result.S3Key])
+		}
+	}
+
+	// the JSON manifest of the same dataset version is a different object
+	result, err = service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.CREATING, result.Status)
+		assert.Equal(t, "1234/1234_2023-05-17T10_04_32Z.json", result.S3Key)
+	}
+}
+
+func TestManifestSubtree(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	rootNodeId := "N:collection:abc"
+	updatedAt := time.Date(2023, 5, 17, 10, 4, 32, 0, time.UTC)
+	rootId := sql.NullInt64{Int64: 5, Valid: true}
+	for name, tt := range map[string]struct {
+		absolute      bool
+		expectedKey   string
+		expectedPaths []string
+	}{
+		"relative": {false, "1234/1234_2023-05-17T10_04_32Z_abc.json", []string{"", "inner"}},
+		"absolute": {true, "1234/1234_2023-05-17T10_04_32Z_abc_absolute.json", []string{"top/sub", "top/sub/inner"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockStore := MockDatasetsStore{
+				GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13, UpdatedAt: updatedAt}},
+				GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{
+					Id: rootId.Int64, Name: "sub", NodeId: rootNodeId, PackageType: packageType.Collection, PackageState: packageState.Ready}},
+				GetPackageAncestorsReturn: MockReturn[[]pgdb.Package]{Value: []pgdb.Package{{Id: 4, Name: "top"}}},
+				GetManifestReturn: MockReturn[[]models.DatasetManifest]{Value: []models.DatasetManifest{
+					{PackageId: 6, PackageName: "a.txt", PackageNodeId: "N:package:6", Path: []sql.NullInt64{rootId}},
+					{PackageId: 7, PackageName: "inner", PackageNodeId: "N:collection:7", Path: []sql.NullInt64{rootId}},
+					{PackageId: 8, PackageName: "b.txt", PackageNodeId: "N:package:8", Path: []sql.NullInt64{rootId, {Int64: 7, Valid: true}}},
+				}},
+			}
+			mockS3Store := MockS3Store{}
+			mockSnsStore := MockSnsStore{}
+			service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{}, 7)
+
+			options := models.ManifestOptions{Format: models.JSONManifest, RootNodeId: rootNodeId, AbsolutePaths: tt.absolute}
+			result, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, options)
+			if !assert.NoError(t, err) || !assert.Len(t, mockSnsStore.ManifestInputs, 1) {
+				return
+			}
+			assert.Equal(t, tt.expectedKey, result.S3Key)
+			input := mockSnsStore.ManifestInputs[0]
+			assert.Equal(t, rootNodeId, input.RootNodeId)
+			assert.Equal(t, tt.absolute, input.AbsolutePaths)
+
+			if assert.NoError(t, service.ProcessManifestJob(context.Background(), input)) {
+				assert.Equal(t, []sql.NullInt64{rootId}, mockStore.ManifestRootIds)
+				var paths []string
+				for _, file := range mockS3Store.Manifests[tt.expectedKey].Files {
+					paths = append(paths, file.Path)
+				}
+				assert.Equal(t, tt.expectedPaths, paths)
+				assert.Equal(t, rootNodeId, mockS3Store.Manifests[tt.expectedKey].RootNodeId)
+				assert.Equal(t, tt.absolute, mockS3Store.Manifests[tt.expectedKey].AbsolutePaths)
+			}
+		})
+	}
+}
+
+func TestManifestSubtreeErrors(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	for name, tt := range map[string]struct {
+		rootReturn      MockReturn[*pgdb.Package]
+		ancestorsReturn MockReturn[[]pgdb.Package]
+		expectedError   error
+	}{
+		"not a collection": {
+			MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 5, PackageType: packageType.CSV, PackageState: packageState.Ready}},
+			MockReturn[[]pgdb.Package]{},
+			models.FolderNotFoundError{OrgId: 7, NodeId: "N:package:5", DatasetId: models.DatasetNodeId(datasetNodeId), ActualType: packageType.CSV},
+		},
+		"in the trashcan": {
+			MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 5, PackageType: packageType.Collection, PackageState: packageState.Deleted}},
+			MockReturn[[]pgdb.Package]{},
+			models.PackageNotFoundError{OrgId: 7, Id: models.PackageNodeId("N:package:5"), DatasetId: models.DatasetNodeId(datasetNodeId)},
+		},
+		"below a collection in the trashcan": {
+			MockReturn[*pgdb.Package]{Value: &pgdb.Package{Id: 5, PackageType: packageType.Collection, PackageState: packageState.Ready}},
+			MockReturn[[]pgdb.Package]{Value: []pgdb.Package{{Id: 4, PackageType: packageType.Collection, PackageState: packageState.Deleting}}},
+			models.PackageNotFoundError{OrgId: 7, Id: models.PackageNodeId("N:package:5"), DatasetId: models.DatasetNodeId(datasetNodeId)},
+		},
+		"not found": {
+			MockReturn[*pgdb.Package]{Error: models.PackageNotFoundError{OrgId: 7, Id: models.PackageNodeId("N:package:5"), DatasetId: models.DatasetIntId(13)}},
+			MockReturn[[]pgdb.Package]{},
+			models.PackageNotFoundError{OrgId: 7, Id: models.PackageNodeId("N:package:5"), DatasetId: models.DatasetIntId(13)},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockStore := MockDatasetsStore{
+				GetDatasetByNodeIdReturn:        MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+				GetDatasetPackageByNodeIdReturn: tt.rootReturn,
+				GetPackageAncestorsReturn:       tt.ancestorsReturn,
+			}
+			mockS3Store := MockS3Store{}
+			mockSnsStore := MockSnsStore{}
+			service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{}, 7)
+
+			_, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{RootNodeId: "N:package:5"})
+			assert.Equal(t, tt.expectedError, err)
+			assert.Empty(t, mockS3Store.Jobs)
+			assert.Empty(t, mockSnsStore.ManifestInputs)
+		})
+	}
+}
+
+func TestManifestIncludeUrls(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	updatedAt := time.Date(2023, 5, 17, 10, 4, 32, 0, time.UTC)
+	s3Key := "1234/1234_2023-05-17T10_04_32Z_urls.json"
+	nullString := func(s string) models.NullString {
+		return models.NullString{NullString: sql.NullString{String: s, Valid: true}}
+	}
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13, UpdatedAt: updatedAt}},
+		GetManifestReturn: MockReturn[[]models.DatasetManifest]{Value: []models.DatasetManifest{
+			{PackageId: 1, PackageName: "a.txt", PackageNodeId: "N:package:1", Path: []sql.NullInt64{{}},
+				FileName: nullString("a.txt"), S3Bucket: nullString("storage-bucket"), S3Key: nullString("org/a.txt")},
+			{PackageId: 2, PackageName: "no files", PackageNodeId: "N:package:2", Path: []sql.NullInt64{{}}},
+		}},
+	}
+	// a manifest with URLs is never reused since they may have expired
+	mockS3Store := MockS3Store{Manifests: map[string]models.WorkspaceManifest{s3Key: {}}}
+	mockSnsStore := MockSnsStore{}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{}, 7)
+
+	options := models.ManifestOptions{Format: models.JSONManifest, IncludeUrls: true, UrlExpiry: 10 * time.Minute}
+	result, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, options)
+	if !assert.NoError(t, err) || !assert.Len(t, mockSnsStore.ManifestInputs, 1) {
+		return
+	}
+	assert.Equal(t, models.CREATING, result.Status)
+	assert.Equal(t, s3Key, result.S3Key)
+	input := mockSnsStore.ManifestInputs[0]
+	assert.True(t, input.IncludeUrls)
+	assert.Equal(t, int64(600), input.UrlExpirySeconds)
+
+	if assert.NoError(t, service.ProcessManifestJob(context.Background(), input)) {
+		files := mockS3Store.Manifests[s3Key].Files
+		if assert.Len(t, files, 2) {
+			assert.Equal(t, "https://storage-bucket/org/a.txt?expires=600", files[0].Url)
+			assert.Empty(t, files[1].Url)
+		}
+		assert.Equal(t, 1, mockS3Store.FilePresignersMade)
+		if expiresAt := mockS3Store.Manifests[s3Key].FileUrlsExpireAt; assert.NotNil(t, expiresAt) {
+			assert.WithinDuration(t, time.Now().Add(10*time.Minute), *expiresAt, time.Minute)
+		}
+	}
+
+	t.Run("credentials expire first", func(t *testing.T) {
+		credentialsExpireAt := time.Now().Add(5 * time.Minute).UTC().Truncate(time.Second)
+		mockS3Store.CredentialsExpireAt = credentialsExpireAt
+		if assert.NoError(t, service.ProcessManifestJob(context.Background(), input)) {
+			if expiresAt := mockS3Store.Manifests[s3Key].FileUrlsExpireAt; assert.NotNil(t, expiresAt) {
+				assert.True(t, credentialsExpireAt.Equal(*expiresAt))
+			}
+		}
+	})
+}
+
+func TestGetManifestDiff(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	fileName := func(name string) models.NullString {
+		return models.NullString{NullString: sql.NullString{String: name, Valid: true}}
+	}
+	kept := models.ManifestDTO{PackageNodeId: "N:package:1", FileNodeId: fileName("uuid-1"), FileName: fileName("kept.txt")}
+	removed := models.ManifestDTO{PackageNodeId: "N:package:2", FileNodeId: fileName("uuid-2"), FileName: fileName("removed.txt")}
+	moved := models.ManifestDTO{PackageNodeId: "N:package:3", FileNodeId: fileName("uuid-3"), FileName: fileName("moved.txt"), Path: "folder"}
+	oldKey := "1234/1234_2023-05-17T10_04_32Z.json"
+	newKey := "1234/1234_2023-05-18T10_04_32Z.json"
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		GetManifestReturn: MockReturn[[]models.DatasetManifest]{Value: []models.DatasetManifest{
+			{PackageId: 4, PackageName: "added.txt", PackageNodeId: "N:package:4", FileUUID: fileName("uuid-4"), FileName: fileName("added.txt"), Path: []sql.NullInt64{{}}},
+			{PackageId: 1, PackageName: "kept.txt", PackageNodeId: "N:package:1", FileUUID: fileName("uuid-1"), FileName: fileName("kept.txt"), Path: []sql.NullInt64{{}}},
+			{PackageId: 3, PackageName: "moved.txt", PackageNodeId: "N:package:3", FileUUID: fileName("uuid-3"), FileName: fileName("moved.txt"), Path: []sql.NullInt64{{}}},
+		}},
+	}
+	unsortedKey := "1234/1234_2023-05-16T10_04_32Z.json"
+	mockS3Store := MockS3Store{Manifests: map[string]models.WorkspaceManifest{
+		oldKey:      {Files: []models.ManifestDTO{kept, removed, moved}},
+		newKey:      {Files: []models.ManifestDTO{kept, moved}},
+		unsortedKey: {Files: []models.ManifestDTO{moved, kept}},
+	}}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+
+	t.Run("stored versions", func(t *testing.T) {
+		diff, err := service.GetManifestDiff(context.Background(), datasetNodeId, oldKey, newKey)
+		if assert.NoError(t, err) {
+			assert.Equal(t, oldKey, diff.From)
+			assert.Equal(t, newKey, diff.To)
+			assert.Equal(t, []models.ManifestDTO{removed}, diff.Removed)
+			assert.Empty(t, diff.Added)
+			assert.Empty(t, diff.Moved)
+			assert.Empty(t, diff.Changed)
+		}
+	})
+	t.Run("live", func(t *testing.T) {
+		diff, err := service.GetManifestDiff(context.Background(), datasetNodeId, newKey, "")
+		if assert.NoError(t, err) {
+			assert.Equal(t, models.LiveManifest, diff.To)
+			if assert.Len(t, diff.Added, 1) {
+				assert.Equal(t, "N:package:4", diff.Added[0].PackageNodeId)
+			}
+			if assert.Len(t, diff.Moved, 1) {
+				assert.Equal(t, "folder", diff.Moved[0].From.Path)
+				assert.Equal(t, "", diff.Moved[0].To.Path)
+			}
+			assert.Empty(t, diff.Removed)
+			assert.Equal(t, []sql.NullInt64{{}}, mockStore.ManifestRootIds)
+		}
+	})
+	t.Run("not in path order", func(t *testing.T) {
+		_, err := service.GetManifestDiff(context.Background(), datasetNodeId, unsortedKey, newKey)
+		assert.IsType(t, models.ValidationError{}, err)
+	})
+	for name, key := range map[string]string{
+		"missing":       "1234/1234_2020-01-01T00_00_00Z.json",
+		"other dataset": "999/999_2023-05-17T10_04_32Z.json",
+		"not json":      "1234/1234_2023-05-17T10_04_32Z.csv",
+		"ro-crate":      "1234/1234_2023-05-17T10_04_32Z.ro-crate-metadata.json",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.GetManifestDiff(context.Background(), datasetNodeId, key, newKey)
+			assert.Equal(t, models.ManifestNotFoundError{S3Key: key, DatasetNodeId: datasetNodeId}, err)
+		})
+	}
+}
+
+func TestGetManifestDiffLiveSubtree(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	rootNodeId := "N:collection:5"
+	fileName := func(name string) models.NullString {
+		return models.NullString{NullString: sql.NullString{String: name, Valid: true}}
+	}
+	kept := models.ManifestDTO{PackageNodeId: "N:package:6", FileNodeId: fileName("uuid-6"), FileName: fileName("a.txt"), Path: "top/sub"}
+	key := "1234/1234_2023-05-17T10_04_32Z_5_absolute.json"
+	rootId := sql.NullInt64{Int64: 5, Valid: true}
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		GetDatasetPackageByNodeIdReturn: MockReturn[*pgdb.Package]{Value: &pgdb.Package{
+			Id: rootId.Int64, Name: "sub", NodeId: rootNodeId, PackageType: packageType.Collection, PackageState: packageState.Ready}},
+		GetPackageAncestorsReturn: MockReturn[[]pgdb.Package]{Value: []pgdb.Package{{Id: 4, Name: "top"}}},
+		GetManifestReturn: MockReturn[[]models.DatasetManifest]{Value: []models.DatasetManifest{
+			{PackageId: 6, PackageName: "a.txt", PackageNodeId: "N:package:6", FileUUID: fileName("uuid-6"), FileName: fileName("a.txt"), Path: []sql.NullInt64{rootId}},
+		}},
+	}
+	mockS3Store := MockS3Store{Manifests: map[string]models.WorkspaceManifest{
+		key: {RootNodeId: rootNodeId, AbsolutePaths: true, Files: []models.ManifestDTO{kept}},
+	}}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+
+	diff, err := service.GetManifestDiff(context.Background(), datasetNodeId, key, models.LiveManifest)
+	if assert.NoError(t, err) {
+		// only the stored manifest's collection is compared, with the same absolute paths
+		assert.Equal(t, []sql.NullInt64{rootId}, mockStore.ManifestRootIds)
+		assert.Empty(t, diff.Added)
+		assert.Empty(t, diff.Removed)
+		assert.Empty(t, diff.Moved)
+		assert.Empty(t, diff.Changed)
+	}
+}
+
+func TestStreamManifestAborted(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	input := models.ManifestWorkerInput{OrgIntId: 7, DatasetNodeId: datasetNodeId, JobId: "job-1", ManifestS3Key: "1234/a.json"}
+	job := models.ManifestJob{JobId: "job-1", DatasetNodeId: datasetNodeId, S3Key: "1234/a.json", Status: models.CREATING}
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		GetManifestReturn: MockReturn[[]models.DatasetManifest]{Value: []models.DatasetManifest{
+			{PackageId: 1, PackageName: "top.txt", PackageNodeId: "N:package:1", Path: []sql.NullInt64{{}}},
+			// the parent collection has not been seen
+			{PackageId: 2, PackageName: "file.txt", PackageNodeId: "N:package:2", Path: []sql.NullInt64{{}, {Int64: 3, Valid: true}}},
+		}},
+	}
+	mockS3Store := MockS3Store{Jobs: map[string]models.ManifestJob{job.JobId: job}}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+
+	err := service.ProcessManifestJob(context.Background(), input)
+	assert.ErrorContains(t, err, "N:package:2")
+	// the upload of the incomplete manifest fails too
+	assert.Empty(t, mockS3Store.Objects)
+	assert.Equal(t, models.CREATING, mockS3Store.Jobs[job.JobId].Status)
+}
+
+func TestManifestFormatROCrate(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	updatedAt := time.Date(2023, 5, 17, 10, 4, 32, 0, time.UTC)
+	s3Key := "1234/1234_2023-05-17T10_04_32Z.ro-crate-metadata.json"
+	root := sql.NullInt64{}
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13, UpdatedAt: updatedAt}},
+		GetManifestReturn: MockReturn[[]models.DatasetManifest]{Value: []models.DatasetManifest{
+			{PackageId: 1, PackageName: "folder", PackageNodeId: "N:collection:1", Path: []sql.NullInt64{root}},
+			{PackageId: 2, PackageName: "empty", PackageNodeId: "N:collection:2", Path: []sql.NullInt64{root, {Int64: 1, Valid: true}}},
+			{PackageId: 3, PackageName: "a.txt", PackageNodeId: "N:package:3", Path: []sql.NullInt64{root, {Int64: 1, Valid: true}},
+				FileName: models.NullString{NullString: sql.NullString{String: "a.txt", Valid: true}}},
+		}},
+	}
+	mockS3Store := MockS3Store{}
+	mockSnsStore := MockSnsStore{}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, 7)
+
+	result, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{Format: models.ROCrateManifest})
+	if assert.NoError(t, err) && assert.Len(t, mockSnsStore.ManifestInputs, 1) {
+		assert.Equal(t, s3Key, result.S3Key)
+		if assert.NoError(t, service.ProcessManifestJob(context.Background(), mockSnsStore.ManifestInputs[0])) {
+			assert.Equal(t, models.ROCrateManifest, mockS3Store.Formats[s3Key])
+			var crate models.ROCrate
+			if assert.NoError(t, json.Unmarshal(mockS3Store.Objects[s3Key], &crate)) {
+				var ids []string
+				for _, entity := range crate.Graph {
+					ids = append(ids, entity.Id)
+				}
+				assert.Equal(t, []string{"folder/a.txt", "ro-crate-metadata.json", "./", "folder/", "folder/empty/"}, ids)
+			}
+		}
+	}
+}
+
+func TestManifestFormatBagIt(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	updatedAt := time.Date(2023, 5, 17, 10, 4, 32, 0, time.UTC)
+	s3Key := "1234/1234_2023-05-17T10_04_32Z.bagit"
+	mockStore := MockDatasetsStore{
+		GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13, Name: "Test Dataset", UpdatedAt: updatedAt}},
+		GetManifestReturn:        MockReturn[[]models.DatasetManifest]{Value: []models.DatasetManifest{}},
+	}
+	mockS3Store := MockS3Store{}
+	mockSnsStore := MockSnsStore{}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, 7)
+	options := models.ManifestOptions{Format: models.BagItManifest}
+
+	result, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, options)
+	if !assert.NoError(t, err) || !assert.Len(t, mockSnsStore.ManifestInputs, 1) {
+		return
+	}
+	assert.Equal(t, s3Key, result.S3Key)
+	if !assert.NoError(t, service.ProcessManifestJob(context.Background(), mockSnsStore.ManifestInputs[0])) {
+		return
+	}
+	for _, name := range models.BagItTagFileNames {
+		assert.Contains(t, mockS3Store.Objects, s3Key+"/"+name)
+	}
+	assert.Empty(t, mockS3Store.Manifests)
+
+	expectedTagFiles := []models.TagFileUrl{
+		{Name: "bag-info.txt", Url: "https://manifest-bucket/" + s3Key + "/bag-info.txt"},
+		{Name: "manifest-sha256.txt", Url: "https://manifest-bucket/" + s3Key + "/manifest-sha256.txt"},
+		{Name: "bagit.txt", Url: "https://manifest-bucket/" + s3Key + "/bagit.txt"},
+	}
+	status, err := service.GetManifestJobStatus(context.Background(), datasetNodeId, result.JobId)
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.READY, status.Status)
+		assert.Empty(t, status.Url)
+		assert.Equal(t, expectedTagFiles, status.TagFiles)
+	}
+
+	// the complete bag is reused
+	existing, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, options)
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.READY, existing.Status)
+		assert.Equal(t, expectedTagFiles, existing.TagFiles)
+		assert.Len(t, mockSnsStore.ManifestInputs, 1)
+	}
+}
+
+func TestTriggerAsyncGetManifestExisting(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	updatedAt := time.Date(2023, 5, 17, 10, 4, 32, 0, time.UTC)
+	s3Key := "1234/1234_2023-05-17T10_04_32Z.json"
+	mockStore := MockDatasetsStore{GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13, UpdatedAt: updatedAt}}}
+
+	t.Run("reused", func(t *testing.T) {
+		mockS3Store := MockS3Store{Manifests: map[string]models.WorkspaceManifest{s3Key: {}}}
+		mockSnsStore := MockSnsStore{}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, 7)
+
+		result, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, models.ManifestResult{Url: "https://manifest-bucket/" + s3Key, S3Bucket: "manifest-bucket", S3Key: s3Key, Status: models.READY}, *result)
+			assert.Empty(t, mockS3Store.Jobs)
+			assert.Empty(t, mockSnsStore.ManifestInputs)
+		}
+	})
+
+	t.Run("forced", func(t *testing.T) {
+		mockS3Store := MockS3Store{Manifests: map[string]models.WorkspaceManifest{s3Key: {}}}
+		mockSnsStore := MockSnsStore{}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, 7)
+
+		result, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{Force: true})
+		if assert.NoError(t, err) {
+			assert.Equal(t, models.CREATING, result.Status)
+			assert.Len(t, mockS3Store.Jobs, 1)
+			assert.Len(t, mockSnsStore.ManifestInputs, 1)
+		}
+	})
+
+	t.Run("in flight", func(t *testing.T) {
+		mockS3Store := MockS3Store{}
+		mockSnsStore := MockSnsStore{}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, 7)
+
+		first, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{})
+		if !assert.NoError(t, err) {
+			return
+		}
+		second, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{Force: true})
+		if assert.NoError(t, err) {
+			assert.Equal(t, first, second)
+			assert.Len(t, mockS3Store.Jobs, 1)
+			assert.Len(t, mockSnsStore.ManifestInputs, 1)
+		}
+	})
+
+	t.Run("in flight job finished", func(t *testing.T) {
+		mockS3Store := MockS3Store{
+			Jobs:          map[string]models.ManifestJob{"failed": {JobId: "failed", DatasetNodeId: datasetNodeId, S3Key: s3Key, Status: models.FAILED}},
+			JobIdsByS3Key: map[string]string{s3Key: "failed"},
+		}
+		mockSnsStore := MockSnsStore{}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, 7)
+
+		result, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, models.CREATING, result.Status)
+			assert.NotEqual(t, "failed", result.JobId)
+			assert.Len(t, mockS3Store.Jobs, 2)
+			assert.Len(t, mockSnsStore.ManifestInputs, 1)
+		}
+	})
+
+	t.Run("head error", func(t *testing.T) {
+		headErr := errors.New("unexpected head error")
+		mockSnsStore := MockSnsStore{}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&MockS3Store{ObjectExistsError: headErr}}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{}, 7)
+
+		_, err := service.TriggerAsyncGetManifest(context.Background(), datasetNodeId, models.ManifestOptions{})
+		assert.Equal(t, headErr, err)
+		assert.Empty(t, mockSnsStore.ManifestInputs)
+	})
+}
+
+func TestTriggerAsyncGetManifestErrors(t *testing.T) {
+	orgId := 7
+	datasetNotFound := models.DatasetNotFoundError{OrgId: orgId, Id: models.DatasetNodeId("N:dataset:1234")}
+	t.Run("dataset not found", func(t *testing.T) {
+		mockStore := MockDatasetsStore{GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Error: datasetNotFound}}
+		mockS3Store := MockS3Store{}
+		mockSnsStore := MockSnsStore{}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&mockSnsStore}, &models.HandlerVars{}, orgId)
+		_, err := service.TriggerAsyncGetManifest(context.Background(), "N:dataset:1234", models.ManifestOptions{})
+		assert.Equal(t, datasetNotFound, err)
+		assert.Empty(t, mockS3Store.Jobs)
+		assert.Empty(t, mockSnsStore.ManifestInputs)
+	})
+	t.Run("publish error", func(t *testing.T) {
+		mockStore := MockDatasetsStore{GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}}}
+		mockS3Store := MockS3Store{}
+		publishErr := errors.New("unexpected publish error")
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{&MockSnsStore{ManifestError: publishErr}}, &models.HandlerVars{}, orgId)
+		_, err := service.TriggerAsyncGetManifest(context.Background(), "N:dataset:1234", models.ManifestOptions{})
+		assert.Equal(t, publishErr, err)
+		if assert.Len(t, mockS3Store.Jobs, 1) {
+			for _, job := range mockS3Store.Jobs {
+				assert.Equal(t, models.FAILED, job.Status)
+				assert.Equal(t, publishErr.Error(), job.Error)
+			}
+		}
+	})
+}
+
+func TestFailManifestJob(t *testing.T) {
+	job := models.ManifestJob{JobId: "job-1", DatasetNodeId: "N:dataset:1234", S3Key: "1234/a.json", Status: models.CREATING}
+	mockS3Store := MockS3Store{Jobs: map[string]models.ManifestJob{job.JobId: job}}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&MockDatasetsStore{}, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+
+	err := service.FailManifestJob(context.Background(), job.JobId, "build error")
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.FAILED, mockS3Store.Jobs[job.JobId].Status)
+		assert.Equal(t, "build error", mockS3Store.Jobs[job.JobId].Error)
+	}
+	assert.Equal(t, models.ManifestJobNotFoundError{JobId: "missing"}, service.FailManifestJob(context.Background(), "missing", "build error"))
+}
+
+func TestGetManifestJobStatus(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	mockS3Store := MockS3Store{Jobs: map[string]models.ManifestJob{
+		"creating": {JobId: "creating", DatasetNodeId: datasetNodeId, S3Key: "1234/a.json", Status: models.CREATING},
+		"ready":    {JobId: "ready", DatasetNodeId: datasetNodeId, S3Key: "1234/b.json", Status: models.READY},
+		"failed":   {JobId: "failed", DatasetNodeId: datasetNodeId, S3Key: "1234/c.json", Status: models.FAILED, Error: "build error"},
+		"other":    {JobId: "other", DatasetNodeId: "N:dataset:5678", S3Key: "5678/d.json", Status: models.READY},
+	}}
+	service := NewDatasetsServiceWithFactory(&MockFactory{&MockDatasetsStore{}, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{S3Bucket: "manifest-bucket"}, 7)
+
+	for jobId, expected := range map[string]models.ManifestResult{
+		"creating": {S3Bucket: "manifest-bucket", S3Key: "1234/a.json", JobId: "creating", Status: models.CREATING},
+		"ready":    {Url: "https://manifest-bucket/1234/b.json", S3Bucket: "manifest-bucket", S3Key: "1234/b.json", JobId: "ready", Status: models.READY},
+		"failed":   {S3Bucket: "manifest-bucket", S3Key: "1234/c.json", JobId: "failed", Status: models.FAILED, Error: "build error"},
+	} {
+		t.Run(jobId, func(t *testing.T) {
+			result, err := service.GetManifestJobStatus(context.Background(), datasetNodeId, jobId)
+			if assert.NoError(t, err) {
+				assert.Equal(t, expected, *result)
+			}
+		})
+	}
+	for _, jobId := range []string{"other", "missing"} {
+		t.Run(jobId, func(t *testing.T) {
+			_, err := service.GetManifestJobStatus(context.Background(), datasetNodeId, jobId)
+			assert.Equal(t, models.ManifestJobNotFoundError{JobId: jobId, DatasetNodeId: datasetNodeId}, err)
+		})
+	}
+}
+
+func TestProcessManifestJob(t *testing.T) {
+	datasetNodeId := "N:dataset:1234"
+	input := models.ManifestWorkerInput{OrgIntId: 7, DatasetNodeId: datasetNodeId, JobId: "job-1", ManifestS3Key: "1234/a.json"}
+	job := models.ManifestJob{JobId: "job-1", DatasetNodeId: datasetNodeId, S3Key: "1234/a.json", Status: models.CREATING}
+
+	t.Run("ready", func(t *testing.T) {
+		mockStore := MockDatasetsStore{
+			GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13, Name: "Test Dataset"}},
+			GetManifestReturn: MockReturn[[]models.DatasetManifest]{Value: []models.DatasetManifest{
+				{PackageId: 1, PackageName: "folder", PackageNodeId: "N:collection:1", Path: []sql.NullInt64{{}}},
+				{PackageId: 2, PackageName: "file.txt", PackageNodeId: "N:package:2", Path: []sql.NullInt64{{}, {Int64: 1, Valid: true}}},
+			}},
+		}
+		mockS3Store := MockS3Store{Jobs: map[string]models.ManifestJob{job.JobId: job}}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+
+		err := service.ProcessManifestJob(context.Background(), input)
+		if assert.NoError(t, err) {
+			assert.Equal(t, models.READY, mockS3Store.Jobs[job.JobId].Status)
+			if assert.Contains(t, mockS3Store.Manifests, input.ManifestS3Key) {
+				manifest := mockS3Store.Manifests[input.ManifestS3Key]
+				assert.Equal(t, "Test Dataset", manifest.Name)
+				if assert.Len(t, manifest.Files, 1) {
+					assert.Equal(t, "N:package:2", manifest.Files[0].PackageNodeId)
+					assert.Equal(t, "folder", manifest.Files[0].Path)
+				}
+			}
+		}
+	})
+
+	t.Run("build error", func(t *testing.T) {
+		buildErr := errors.New("unexpected manifest error")
+		mockStore := MockDatasetsStore{
+			GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetManifestReturn:        MockReturn[[]models.DatasetManifest]{Error: buildErr},
+		}
+		mockS3Store := MockS3Store{Jobs: map[string]models.ManifestJob{job.JobId: job}}
+		service := NewDatasetsServiceWithFactory(&MockFactory{&mockStore, -1}, &MockS3Factory{&mockS3Store}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+
+		err := service.ProcessManifestJob(context.Background(), input)
+		assert.Equal(t, buildErr, err)
+		// left as is so that the worker can retry
+		assert.Equal(t, job, mockS3Store.Jobs[job.JobId])
+		assert.Empty(t, mockS3Store.Manifests)
+	})
+
+	t.Run("missing job", func(t *testing.T) {
+		service := NewDatasetsServiceWithFactory(&MockFactory{&MockDatasetsStore{}, -1}, &MockS3Factory{&MockS3Store{}}, &MockSnsFactory{}, &models.HandlerVars{}, 7)
+		err := service.ProcessManifestJob(context.Background(), input)
+		assert.Equal(t, models.ManifestJobNotFoundError{JobId: job.JobId}, err)
+	})
+}
+
 type MockReturn[T any] struct {
 	Value T
 	Error error
@@ -278,6 +1308,10 @@ func (mr MockReturn[T]) ret() (T, error) {
 	return mr.Value, nil
 }
 
+func ptr[T any](v T) *T {
+	return &v
+}
+
 type MockDatasetsStore struct {
 	GetDatasetByNodeIdReturn           MockReturn[*pgdb.Dataset]
 	GetTrashcanRootPaginatedReturn     MockReturn[*store.PackagePage]
@@ -285,14 +1319,71 @@ type MockDatasetsStore struct {
 	CountDatasetPackagesByStatesReturn MockReturn[int]
 	GetDatasetPackageByNodeIdReturn    MockReturn[*pgdb.Package]
 	GetManifestReturn                  MockReturn[[]models.DatasetManifest]
+	GetPackageAncestorsReturn          MockReturn[[]pgdb.Package]
+	GetPackagePathsReturn              MockReturn[map[int64]string]
+	GetTrashcanTreeReturn              MockReturn[[]store.TrashcanPackage]
+	PackagePathsCalls                  [][]int64
+	GetDeletedDescendantsReturn        MockReturn[[]pgdb.Package]
+	MarkTrashForPurgeReturn            MockReturn[[]int64]
+	GetLivePackagesPaginatedReturn     MockReturn[*store.LivePackagePage]
+	GetDatasetStatsReturn              MockReturn[*models.DatasetStats]
+	GetTrashcanSummaryReturn           MockReturn[*models.TrashcanSummary]
+	// TrashcanSummaryRootIds records the rootId of each call to GetTrashcanSummary
+	TrashcanSummaryRootIds []sql.NullInt64
+	// LivePackageNames are the names CountLivePackagesByName will report as taken
+	LivePackageNames []string
+	// UpdatedPackages records the calls to UpdatePackageNameAndState
+	UpdatedPackages []UpdatedPackage
+	// TouchedDatasets records the dataset ids passed to TouchDataset
+	TouchedDatasets   []int64
+	TouchDatasetError error
+	// ManifestRootIds records the rootId of each call to StreamDatasetManifest
+	ManifestRootIds []sql.NullInt64
+	// TrashcanFilters records the filter of each call to GetTrashcanRootPaginated or GetTrashcanPaginated
+	TrashcanFilters []models.TrashcanFilter
+	// TrashcanSorts records the sort of each call to GetTrashcanRootPaginated or GetTrashcanPaginated
+	TrashcanSorts []models.TrashcanSort
+	// TrashcanPagings records the paging of each call to GetTrashcanRootPaginated or GetTrashcanPaginated
+	TrashcanPagings []models.Paging[models.TrashcanCursor]
+	// LivePackagesCalls records the calls to GetLivePackagesPaginated
+	LivePackagesCalls []LivePackagesCall
+	// PurgedPackageIds are the ids IsPackagePurged will report as purged
+	PurgedPackageIds     []int64
+	GetPurgedFilesReturn MockReturn[[]store.PurgedFile]
+	// DeletedFileIds records the file ids passed to DeleteFiles
+	DeletedFileIds   []int64
+	DeleteFilesError error
 }
 
-func (m *MockDatasetsStore) GetDatasetManifest(_ context.Context, _ int64) ([]models.DatasetManifest, error) {
-	return m.GetManifestReturn.ret()
+type LivePackagesCall struct {
+	ParentId sql.NullInt64
+	Sort     models.PackageSort
+	Limit    int
+	Offset   int
+}
+
+type UpdatedPackage struct {
+	Id    int64
+	Name  string
+	State packageState.State
+}
+
+func (m *MockDatasetsStore) StreamDatasetManifest(_ context.Context, _ int64, rootId sql.NullInt64, fn func(models.DatasetManifest) error) error {
+	m.ManifestRootIds = append(m.ManifestRootIds, rootId)
+	rows, err := m.GetManifestReturn.ret()
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err = fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (m *MockDatasetsStore) getExpectedErrors() []error {
-	expected := make([]error, 5)
+	expected := make([]error, 7)
 	var i int
 	if err := m.GetDatasetByNodeIdReturn.Error; err != nil {
 		expected[i] = err
@@ -314,17 +1405,45 @@ func (m *MockDatasetsStore) getExpectedErrors() []error {
 		expected[i] = err
 		i++
 	}
+	if err := m.GetPackagePathsReturn.Error; err != nil {
+		expected[i] = err
+		i++
+	}
+	if err := m.GetTrashcanTreeReturn.Error; err != nil {
+		expected[i] = err
+		i++
+	}
 	return expected[:i]
 }
 
-func (m *MockDatasetsStore) GetTrashcanRootPaginated(_ context.Context, _ int64, _ int, _ int) (*store.PackagePage, error) {
+func (m *MockDatasetsStore) GetTrashcanRootPaginated(_ context.Context, _ int64, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor]) (*store.PackagePage, error) {
+	m.TrashcanFilters = append(m.TrashcanFilters, filter)
+	m.TrashcanSorts = append(m.TrashcanSorts, sort)
+	m.TrashcanPagings = append(m.TrashcanPagings, paging)
 	return m.GetTrashcanRootPaginatedReturn.ret()
 }
 
-func (m *MockDatasetsStore) GetTrashcanPaginated(_ context.Context, _ int64, _ int64, _ int, _ int) (*store.PackagePage, error) {
+func (m *MockDatasetsStore) GetTrashcanPaginated(_ context.Context, _ int64, _ int64, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor]) (*store.PackagePage, error) {
+	m.TrashcanFilters = append(m.TrashcanFilters, filter)
+	m.TrashcanSorts = append(m.TrashcanSorts, sort)
+	m.TrashcanPagings = append(m.TrashcanPagings, paging)
 	return m.GetTrashcanPaginatedReturn.ret()
 }
 
+func (m *MockDatasetsStore) GetTrashcanTree(_ context.Context, _ int64) ([]store.TrashcanPackage, error) {
+	return m.GetTrashcanTreeReturn.ret()
+}
+
+func (m *MockDatasetsStore) GetLivePackagesPaginated(_ context.Context, _ int64, parentId sql.NullInt64, sort models.PackageSort, limit int, offset int) (*store.LivePackagePage, error) {
+	m.LivePackagesCalls = append(m.LivePackagesCalls, LivePackagesCall{ParentId: parentId, Sort: sort, Limit: limit, Offset: offset})
+	return m.GetLivePackagesPaginatedReturn.ret()
+}
+
+func (m *MockDatasetsStore) TouchDataset(_ context.Context, datasetId int64) error {
+	m.TouchedDatasets = append(m.TouchedDatasets, datasetId)
+	return m.TouchDatasetError
+}
+
 func (m *MockDatasetsStore) GetDatasetByNodeId(_ context.Context, _ string) (*pgdb.Dataset, error) {
 	return m.GetDatasetByNodeIdReturn.ret()
 }
@@ -337,6 +1456,54 @@ func (m *MockDatasetsStore) GetDatasetPackageByNodeId(_ context.Context, _ int64
 	return m.GetDatasetPackageByNodeIdReturn.ret()
 }
 
+func (m *MockDatasetsStore) GetPackageAncestors(_ context.Context, _ int64, _ int64) ([]pgdb.Package, error) {
+	return m.GetPackageAncestorsReturn.ret()
+}
+
+func (m *MockDatasetsStore) GetPackagePaths(_ context.Context, _ int64, packageIds []int64) (map[int64]string, error) {
+	m.PackagePathsCalls = append(m.PackagePathsCalls, packageIds)
+	return m.GetPackagePathsReturn.ret()
+}
+
+func (m *MockDatasetsStore) GetDeletedDescendants(_ context.Context, _ int64, _ int64) ([]pgdb.Package, error) {
+	return m.GetDeletedDescendantsReturn.ret()
+}
+
+func (m *MockDatasetsStore) CountLivePackagesByName(_ context.Context, _ int64, _ sql.NullInt64, name string) (int, error) {
+	count := 0
+	for _, n := range m.LivePackageNames {
+		if n == name {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockDatasetsStore) UpdatePackageNameAndState(_ context.Context, packageId int64, name string, state packageState.State) error {
+	m.UpdatedPackages = append(m.UpdatedPackages, UpdatedPackage{Id: packageId, Name: name, State: state})
+	return nil
+}
+
+func (m *MockDatasetsStore) MarkTrashForPurge(_ context.Context, _ int64, _ sql.NullInt64) ([]int64, error) {
+	return m.MarkTrashForPurgeReturn.ret()
+}
+
+func (m *MockDatasetsStore) IsPackagePurged(_ context.Context, packageId int64) (bool, error) {
+	return slices.Contains(m.PurgedPackageIds, packageId), nil
+}
+
+func (m *MockDatasetsStore) GetPurgedFiles(_ context.Context, _ int64, _ []int64) ([]store.PurgedFile, error) {
+	return m.GetPurgedFilesReturn.ret()
+}
+
+func (m *MockDatasetsStore) DeleteFiles(_ context.Context, fileIds []int64) error {
+	if m.DeleteFilesError != nil {
+		return m.DeleteFilesError
+	}
+	m.DeletedFileIds = append(m.DeletedFileIds, fileIds...)
+	return nil
+}
+
 type MockSnSClient struct{}
 
 func (m *MockSnSClient) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
@@ -344,19 +1511,174 @@ func (m *MockSnSClient) Publish(ctx context.Context, params *sns.PublishInput, o
 }
 
 type MockS3Store struct {
+	// Manifests maps the S3 key of each written manifest to the manifest
+	Manifests map[string]models.WorkspaceManifest
+	// Formats maps the S3 key of each written manifest to the format it was written in
+	Formats map[string]models.ManifestFormat
+	// WriteManifestErrors are returned, one per call, by the first calls to WriteManifestToS3
+	WriteManifestErrors []error
+	ObjectExistsError   error
+	// Objects maps the S3 key of each object written with WriteObject to its content
+	Objects map[string][]byte
+	// Jobs holds the manifest jobs by job id
+	Jobs map[string]models.ManifestJob
+	// JobIdsByS3Key maps the S3 key of each manifest to the id of the latest CREATING job written for it
+	JobIdsByS3Key map[string]string
+	WriteJobError error
+	// CredentialsExpireAt, if set, is when the credentials presigning URLs expire
+	CredentialsExpireAt time.Time
+	// FilePresignersMade counts the calls to NewFilePresigner
+	FilePresignersMade int
+	// DeletedObjects records the keys passed to DeleteObjects
+	DeletedObjects     []string
+	DeleteObjectsError error
 }
 
-func (m *MockS3Store) WriteManifestToS3(ctx context.Context, datasetNodeId string, s3Key string, manifest models.WorkspaceManifest) (*models.WriteManifestOutput, error) {
-	return nil, nil
+func (m *MockS3Store) WriteManifestToS3(ctx context.Context, s3Key string, format models.ManifestFormat, manifest io.Reader) (*models.WriteManifestOutput, error) {
+	if len(m.WriteManifestErrors) > 0 {
+		err := m.WriteManifestErrors[0]
+		m.WriteManifestErrors = m.WriteManifestErrors[1:]
+		return nil, err
+	}
+	content, err := io.ReadAll(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.WriteObject(ctx, s3Key, format.ContentType(), content); err != nil {
+		return nil, err
+	}
+	if m.Formats == nil {
+		m.Formats = map[string]models.ManifestFormat{}
+	}
+	m.Formats[s3Key] = format
+	if format == "" || format == models.JSONManifest {
+		var decoded models.WorkspaceManifest
+		if err = json.Unmarshal(content, &decoded); err != nil {
+			return nil, err
+		}
+		if m.Manifests == nil {
+			m.Manifests = map[string]models.WorkspaceManifest{}
+		}
+		m.Manifests[s3Key] = decoded
+	}
+	return &models.WriteManifestOutput{S3Key: s3Key}, nil
 }
 func (m *MockS3Store) GetPresignedUrl(ctx context.Context, bucket string, key string) (*url.URL, error) {
-	return nil, nil
+	return &url.URL{Scheme: "https", Host: bucket, Path: key}, nil
+}
+
+func (m *MockS3Store) ObjectExists(ctx context.Context, key string) (bool, error) {
+	if m.ObjectExistsError != nil {
+		return false, m.ObjectExistsError
+	}
+	_, isManifest := m.Manifests[key]
+	_, isObject := m.Objects[key]
+	return isManifest || isObject, nil
+}
+
+func (m *MockS3Store) NewFilePresigner(ctx context.Context, expiry time.Duration) (store.FilePresigner, error) {
+	m.FilePresignersMade++
+	expiresAt := time.Now().Add(expiry)
+	if !m.CredentialsExpireAt.IsZero() && m.CredentialsExpireAt.Before(expiresAt) {
+		expiresAt = m.CredentialsExpireAt
+	}
+	return &MockFilePresigner{int64(time.Until(expiresAt).Round(time.Second) / time.Second), expiresAt}, nil
+}
+
+type MockFilePresigner struct {
+	LifetimeSecs int64
+	Expires      time.Time
+}
+
+func (m *MockFilePresigner) ExpiresAt() time.Time {
+	return m.Expires
+}
+
+func (m *MockFilePresigner) PresignUrl(bucket, key string) (string, error) {
+	return fmt.Sprintf("https://%s/%s?expires=%d", bucket, key, m.LifetimeSecs), nil
+}
+
+func (m *MockS3Store) OpenManifest(ctx context.Context, s3Key string) (*models.ManifestReader, error) {
+	manifest, ok := m.Manifests[s3Key]
+	if !ok {
+		return nil, models.ManifestNotFoundError{S3Key: s3Key}
+	}
+	var encoded bytes.Buffer
+	if err := manifest.Encode(&encoded, models.JSONManifest); err != nil {
+		return nil, err
+	}
+	return models.NewManifestReader(io.NopCloser(&encoded))
+}
+
+func (m *MockS3Store) WriteObject(ctx context.Context, key string, contentType string, content []byte) error {
+	if m.Objects == nil {
+		m.Objects = map[string][]byte{}
+	}
+	m.Objects[key] = content
+	return nil
+}
+
+func (m *MockS3Store) DeleteObjects(ctx context.Context, keys []string) error {
+	if m.DeleteObjectsError != nil {
+		return m.DeleteObjectsError
+	}
+	m.DeletedObjects = append(m.DeletedObjects, keys...)
+	return nil
+}
+
+func (m *MockS3Store) WriteManifestJob(ctx context.Context, job models.ManifestJob) error {
+	if m.WriteJobError != nil {
+		return m.WriteJobError
+	}
+	if m.Jobs == nil {
+		m.Jobs = map[string]models.ManifestJob{}
+	}
+	m.Jobs[job.JobId] = job
+	if job.Status == models.CREATING {
+		if m.JobIdsByS3Key == nil {
+			m.JobIdsByS3Key = map[string]string{}
+		}
+		m.JobIdsByS3Key[job.S3Key] = job.JobId
+	}
+	return nil
+}
+
+func (m *MockS3Store) GetManifestJob(ctx context.Context, jobId string) (*models.ManifestJob, error) {
+	job, ok := m.Jobs[jobId]
+	if !ok {
+		return nil, models.ManifestJobNotFoundError{JobId: jobId}
+	}
+	return &job, nil
+}
+
+func (m *MockS3Store) GetManifestJobByS3Key(ctx context.Context, s3Key string) (*models.ManifestJob, error) {
+	jobId, ok := m.JobIdsByS3Key[s3Key]
+	if !ok {
+		return nil, models.ManifestJobNotFoundError{}
+	}
+	return m.GetManifestJob(ctx, jobId)
 }
 
 type MockSnsStore struct {
+	ManifestInputs []models.ManifestWorkerInput
+	ManifestError  error
+	PurgeInputs    []models.PurgeWorkerInput
+	PurgeError     error
 }
 
 func (m *MockSnsStore) TriggerWorkerLambda(ctx context.Context, input models.ManifestWorkerInput) error {
+	if m.ManifestError != nil {
+		return m.ManifestError
+	}
+	m.ManifestInputs = append(m.ManifestInputs, input)
+	return nil
+}
+
+func (m *MockSnsStore) TriggerPurgeWorker(ctx context.Context, input models.PurgeWorkerInput) error {
+	if m.PurgeError != nil {
+		return m.PurgeError
+	}
+	m.PurgeInputs = append(m.PurgeInputs, input)
 	return nil
 }
 
@@ -376,6 +1698,15 @@ func (m *MockS3Factory) NewSimpleStore(bucket string) store.S3Store {
 	return m.mockStore
 }
 
+func (m *MockDatasetsStore) GetDatasetStats(_ context.Context, _ int64, _ int) (*models.DatasetStats, error) {
+	return m.GetDatasetStatsReturn.ret()
+}
+
+func (m *MockDatasetsStore) GetTrashcanSummary(_ context.Context, _ int64, rootId sql.NullInt64) (*models.TrashcanSummary, error) {
+	m.TrashcanSummaryRootIds = append(m.TrashcanSummaryRootIds, rootId)
+	return m.GetTrashcanSummaryReturn.ret()
+}
+
 type MockFactory struct {
 	mockStore *MockDatasetsStore
 	orgId     int
@@ -63,9 +63,11 @@ func GetAppClientVars(ctx context.Context) (*models.HandlerVars, error) {
 	}
 
 	snsTopic := os.Getenv("CREATE_MANIFEST_SNS_TOPIC")
+	purgeSnsTopic := os.Getenv("PURGE_TRASHCAN_SNS_TOPIC")
 
 	return &models.HandlerVars{
-		S3Bucket: s3BucketId,
-		SnsTopic: snsTopic,
+		S3Bucket:      s3BucketId,
+		SnsTopic:      snsTopic,
+		PurgeSnsTopic: purgeSnsTopic,
 	}, nil
 }
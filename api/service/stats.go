@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/datasets-service/api/store"
+)
+
+// largestFileCount is the number of files listed in DatasetStats.LargestFiles.
+const largestFileCount = 10
+
+// GetDatasetStats summarizes the packages and files of a dataset by package type and state.
+func (s *datasetsService) GetDatasetStats(ctx context.Context, datasetNodeId string) (*models.DatasetStats, error) {
+	var stats *models.DatasetStats
+	err := s.StoreFactory.ExecStoreTx(ctx, s.OrgId, func(q store.DatasetsStore) error {
+		dataset, err := q.GetDatasetByNodeId(ctx, datasetNodeId)
+		if err != nil {
+			return err
+		}
+		stats, err = q.GetDatasetStats(ctx, dataset.Id, largestFileCount)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.DatasetId = datasetNodeId
+	return stats, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDatasetStats(t *testing.T) {
+	orgId := 7
+	storeStats := &models.DatasetStats{
+		PackageCount:  3,
+		FileCount:     2,
+		TotalSize:     30,
+		PackageTypes:  map[string]models.PackageTypeStats{"Collection": {Count: 1}, "CSV": {Count: 2, Size: 30}},
+		PackageStates: map[string]int{"READY": 1, "UPLOADED": 2, "DELETED": 1},
+		TrashedCount:  1,
+		LargestFiles:  []models.LargestFile{{PackageNodeId: "N:package:1", PackageName: "a.csv", FileName: "a.csv", Size: 20}},
+	}
+	mockFactory := MockFactory{&MockDatasetsStore{
+		GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+		GetDatasetStatsReturn:    MockReturn[*models.DatasetStats]{Value: storeStats},
+	}, -1}
+	service := NewDatasetsServiceWithFactory(&mockFactory, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, orgId)
+
+	stats, err := service.GetDatasetStats(context.Background(), "N:dataset:7890")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "N:dataset:7890", stats.DatasetId)
+		assert.Equal(t, 3, stats.PackageCount)
+		assert.Equal(t, 1, stats.TrashedCount)
+		assert.Equal(t, orgId, mockFactory.orgId)
+	}
+}
+
+func TestGetDatasetStatsErrors(t *testing.T) {
+	orgId := 7
+	notFound := models.DatasetNotFoundError{OrgId: orgId, Id: models.DatasetNodeId("N:dataset:7890")}
+	statsErr := errors.New("unexpected stats error")
+	for tName, tData := range map[string]struct {
+		mockStore MockDatasetsStore
+		expected  error
+	}{
+		"dataset not found error": {MockDatasetsStore{
+			GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Error: notFound}}, notFound},
+		"unexpected stats error": {MockDatasetsStore{
+			GetDatasetByNodeIdReturn: MockReturn[*pgdb.Dataset]{Value: &pgdb.Dataset{Id: 13}},
+			GetDatasetStatsReturn:    MockReturn[*models.DatasetStats]{Error: statsErr}}, statsErr},
+	} {
+		service := NewDatasetsServiceWithFactory(&MockFactory{&tData.mockStore, -1}, &MockS3Factory{}, &MockSnsFactory{}, &models.HandlerVars{}, orgId)
+		t.Run(tName, func(t *testing.T) {
+			_, err := service.GetDatasetStats(context.Background(), "N:dataset:7890")
+			assert.Equal(t, tData.expected, err)
+		})
+	}
+}
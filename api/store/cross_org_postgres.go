@@ -8,7 +8,7 @@ import (
 
 // CrossOrgStore provides methods for queries that span multiple organization schemas
 type CrossOrgStore interface {
-    GetSharedDatasetsForUser(ctx context.Context, userId int, limit int, offset int) (*models.SharedDatasetsPage, error)
+    GetSharedDatasetsForUser(ctx context.Context, userId int, paging models.Paging[models.SharedDatasetsCursor]) (*models.SharedDatasetsPage, error)
 }
 
 // CrossOrgStoreFactory creates CrossOrgStore instances
@@ -23,12 +23,14 @@ func NewCrossOrgQueriesSimple(db pg.DBTX) CrossOrgStore {
 
 // GetSharedDatasetsForUser retrieves all datasets shared with a user across all organizations
 // This implementation builds dynamic SQL queries for each organization
-func (q *crossOrgQueriesSimple) GetSharedDatasetsForUser(ctx context.Context, userId int, limit int, offset int) (*models.SharedDatasetsPage, error) {
+func (q *crossOrgQueriesSimple) GetSharedDatasetsForUser(ctx context.Context, userId int, paging models.Paging[models.SharedDatasetsCursor]) (*models.SharedDatasetsPage, error) {
 	// Handle negative values gracefully
+	limit := paging.Limit
 	if limit < 0 {
 		limit = 0
 	}
-	if offset < 0 {
+	offset := paging.Offset
+	if offset < 0 || paging.After != nil {
 		offset = 0
 	}
 
@@ -109,15 +111,29 @@ func (q *crossOrgQueriesSimple) GetSharedDatasetsForUser(ctx context.Context, us
 
 	if len(unionParts) == 0 {
 		// No organizations with shared datasets
-		return &models.SharedDatasetsPage{
-			Limit:      limit,
-			Offset:     offset,
-			TotalCount: 0,
-			Datasets:   []models.SharedDatasetItem{},
-		}, nil
+		page := &models.SharedDatasetsPage{
+			Limit:    limit,
+			Offset:   offset,
+			Datasets: []models.SharedDatasetItem{},
+		}
+		if paging.CountTotal() {
+			page.TotalCount = new(int)
+		}
+		return page, nil
 	}
 
-	// Step 3: Combine all parts with UNION and add pagination
+	// Step 3: Combine all parts with UNION and add pagination. A page after a cursor starts with the datasets
+	// that follow it in the order of the query.
+	keyset := ""
+	if paging.After != nil {
+		keyset = fmt.Sprintf("WHERE updated_at < $%[1]d OR (updated_at = $%[1]d AND (name, node_id) > ($%[2]d, $%[3]d))",
+			len(queryArgs)+1, len(queryArgs)+2, len(queryArgs)+3)
+		queryArgs = append(queryArgs, paging.After.UpdatedAt, paging.After.Name, paging.After.NodeId)
+	}
+	totalCount := "0"
+	if paging.CountTotal() {
+		totalCount = "COUNT(*) OVER()"
+	}
 	fullQuery := fmt.Sprintf(`
 		WITH all_shared_datasets AS (
 			%s
@@ -135,13 +151,15 @@ func (q *crossOrgQueriesSimple) GetSharedDatasetsForUser(ctx context.Context, us
 			id,
 			org_node_id,
 			org_name,
-			COUNT(*) OVER() as total_count
+			%s as total_count
 		FROM all_shared_datasets
-		ORDER BY updated_at DESC, name
+		%s
+		ORDER BY updated_at DESC, name, node_id
 		LIMIT $%d OFFSET $%d
-	`, strings.Join(unionParts, " UNION ALL "), len(queryArgs)+1, len(queryArgs)+2)
+	`, strings.Join(unionParts, " UNION ALL "), totalCount, keyset, len(queryArgs)+1, len(queryArgs)+2)
 
-	queryArgs = append(queryArgs, limit, offset)
+	// One more dataset than the limit is fetched to tell whether there is a next page
+	queryArgs = append(queryArgs, limit+1, offset)
 
 	// Execute the query
 	rows, err := q.db.QueryContext(ctx, fullQuery, queryArgs...)
@@ -152,7 +170,7 @@ func (q *crossOrgQueriesSimple) GetSharedDatasetsForUser(ctx context.Context, us
 	defer rows.Close()
 
 	var datasets []models.SharedDatasetItem
-	var totalCount int
+	var total int
 	hasRows := false
 
 	for rows.Next() {
@@ -176,7 +194,7 @@ func (q *crossOrgQueriesSimple) GetSharedDatasetsForUser(ctx context.Context, us
 			&intId,
 			&content.WorkspaceNodeID,
 			&content.WorkspaceName,
-			&totalCount,
+			&total,
 		)
 		if err != nil {
 			log.WithError(err).Error("Failed to scan dataset row")
@@ -202,8 +220,17 @@ func (q *crossOrgQueriesSimple) GetSharedDatasetsForUser(ctx context.Context, us
 		return nil, fmt.Errorf("error iterating dataset rows: %w", err)
 	}
 
+	var nextCursor string
+	if len(datasets) > limit {
+		datasets = datasets[:limit]
+		if limit > 0 {
+			last := datasets[limit-1].Content
+			nextCursor = models.EncodeCursor(models.SharedDatasetsCursor{UpdatedAt: last.UpdatedAt, Name: last.Name, NodeId: last.ID})
+		}
+	}
+
 	// If we got no rows (e.g., offset beyond results), get the total count separately
-	if !hasRows && len(unionParts) > 0 {
+	if !hasRows && paging.CountTotal() {
 		countQuery := fmt.Sprintf(`
 			WITH all_shared_datasets AS (
 				%s
@@ -211,17 +238,21 @@ func (q *crossOrgQueriesSimple) GetSharedDatasetsForUser(ctx context.Context, us
 			SELECT COUNT(*) FROM all_shared_datasets
 		`, strings.Join(unionParts, " UNION ALL "))
 
-		err := q.db.QueryRowContext(ctx, countQuery, userId).Scan(&totalCount)
+		err := q.db.QueryRowContext(ctx, countQuery, userId).Scan(&total)
 		if err != nil && err != sql.ErrNoRows {
 			log.WithError(err).Error("Failed to get total count")
 			return nil, fmt.Errorf("failed to get total count: %w", err)
 		}
 	}
 
-	return &models.SharedDatasetsPage{
+	page := &models.SharedDatasetsPage{
 		Limit:      limit,
 		Offset:     offset,
-		TotalCount: totalCount,
 		Datasets:   datasets,
-	}, nil
+		NextCursor: nextCursor,
+	}
+	if paging.CountTotal() {
+		page.TotalCount = &total
+	}
+	return page, nil
 }
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+)
+
+// migrations create the tables this service keeps next to the platform's. Each one can be run more than once.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrate runs the migrations in the order of their file names.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	entries, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		migration, err := migrations.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, string(migration)); err != nil {
+			return fmt.Errorf("migration %s failed: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
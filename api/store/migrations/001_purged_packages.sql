@@ -0,0 +1,12 @@
+-- Packages purged from the trashcan. A purged package stays DELETING until the purge worker has removed its files,
+-- and is no longer in the trashcan, so it cannot be restored. Package ids are only unique within an organization.
+CREATE SCHEMA IF NOT EXISTS datasets_service;
+
+CREATE TABLE IF NOT EXISTS datasets_service.purged_packages
+(
+    organization_id INTEGER   NOT NULL,
+    package_id      INTEGER   NOT NULL,
+    dataset_id      INTEGER   NOT NULL,
+    purged_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, package_id)
+);
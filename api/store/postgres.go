@@ -10,6 +10,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/pennsieve/datasets-service/api/models"
 	"github.com/pennsieve/pennsieve-go-core/pkg/models/packageInfo/packageState"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/packageInfo/packageType"
 	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
 	pg "github.com/pennsieve/pennsieve-go-core/pkg/queries/pgdb"
 	log "github.com/sirupsen/logrus"
@@ -17,42 +18,251 @@ import (
 	"strings"
 )
 
+// purgedPackagesTable records the packages purged from the trashcan. See migrations/001_purged_packages.sql.
+const purgedPackagesTable = "datasets_service.purged_packages"
+
+// notPurged returns the condition that the package p has not been purged from the trashcan, for a query format in
+// which the org id is argument orgIdIndex.
+func notPurged(orgIdIndex int) string {
+	return fmt.Sprintf(`NOT EXISTS(SELECT 1 FROM %s pp WHERE pp.organization_id = %%[%d]d AND pp.package_id = p.id)`, purgedPackagesTable, orgIdIndex)
+}
+
 var (
 	packagesColumns            = []string{"id", "name", "type", "state", "node_id", "parent_id", "dataset_id", "owner_id", "size", "import_id", "attributes", "created_at", "updated_at"}
 	packageColumnsString       = strings.Join(packagesColumns, ", ")
 	getTrashcanPageQueryFormat = `WITH RECURSIVE trash(id, node_id, type, parent_id, name, state, id_path) AS
                                   (
 									SELECT id, node_id, type, %[1]s, name, state, ARRAY [id]
-									FROM "%[4]d".packages
+									FROM "%[4]d".packages p
 									WHERE parent_id %[2]s
 									AND dataset_id = $1
+									AND ` + notPurged(4) + `
                                   UNION ALL
 									SELECT p.id, p.node_id, p.type, p.parent_id, p.name, p.state, id_path || p.id
 									FROM "%[4]d".packages p
 									JOIN trash t ON t.id = p.parent_id
 									WHERE t.state <> 'DELETED' AND t.state <> 'DELETING'
+									AND ` + notPurged(4) + `
                                   )
-                                  SELECT %[3]s, COUNT(*) OVER() as total_count
+                                  SELECT %[3]s, u.node_id, u.first_name, u.last_name, %[7]s as total_count
                                   FROM trash t JOIN "%[4]d".packages p ON t.id = p.id
+                                  LEFT JOIN pennsieve.users u ON u.id = p.owner_id
                                   WHERE t.parent_id %[2]s
-  					              AND EXISTS(SELECT 1 FROM trash t2 WHERE (t2.state = 'DELETED' OR t2.state = 'DELETING') and t.id = ANY(t2.id_path))
-					              ORDER BY t.name, t.id
+  					              AND EXISTS(SELECT 1 FROM trash t2 WHERE (t2.state = 'DELETED' OR t2.state = 'DELETING') and t.id = ANY(t2.id_path))%[5]s
+					              ORDER BY %[6]s, p.id
 					              LIMIT $2 OFFSET $3;`
-	getManifestQueryFormat = `WITH RECURSIVE parents (dataset_id, state, id, name, parent_id, node_id, path) AS
+	// getFlatTrashcanPageQueryFormat pages through the DELETED or DELETING packages at any depth below a folder
+	getFlatTrashcanPageQueryFormat = `WITH RECURSIVE descendants(id) AS
+                                      (
+                                        SELECT id
+                                        FROM "%[1]d".packages
+                                        WHERE parent_id %[2]s
+                                        AND dataset_id = $1
+                                      UNION ALL
+                                        SELECT p.id
+                                        FROM "%[1]d".packages p
+                                        JOIN descendants d ON p.parent_id = d.id
+                                      )
+                                      SELECT %[3]s, u.node_id, u.first_name, u.last_name, %[6]s as total_count
+                                      FROM descendants d JOIN "%[1]d".packages p ON d.id = p.id
+                                      LEFT JOIN pennsieve.users u ON u.id = p.owner_id
+                                      WHERE p.state IN ('DELETED', 'DELETING') AND ` + notPurged(1) + `%[4]s
+                                      ORDER BY %[5]s, p.id
+                                      LIMIT $2 OFFSET $3;`
+	// getTrashcanTreeQueryFormat selects the whole trashcan of dataset $1, which is every DELETED or DELETING package
+	// and every package above one. Unlike getTrashcanPageQueryFormat it does not stop at deleted folders, so that
+	// deleted packages inside them are included. Packages come ordered by depth, so a package always comes after its
+	// parent.
+	getTrashcanTreeQueryFormat = `WITH RECURSIVE tree(id, state, id_path) AS
+                                  (
+                                    SELECT id, state, ARRAY [id]
+                                    FROM "%[1]d".packages p
+                                    WHERE parent_id IS NULL
+                                    AND dataset_id = $1
+                                    AND ` + notPurged(1) + `
+                                  UNION ALL
+                                    SELECT p.id, p.state, t.id_path || p.id
+                                    FROM "%[1]d".packages p
+                                    JOIN tree t ON t.id = p.parent_id
+                                    WHERE ` + notPurged(1) + `
+                                  )
+                                  SELECT %[2]s, u.node_id, u.first_name, u.last_name
+                                  FROM tree t JOIN "%[1]d".packages p ON t.id = p.id
+                                  LEFT JOIN pennsieve.users u ON u.id = p.owner_id
+                                  WHERE EXISTS(SELECT 1 FROM tree t2 WHERE t2.state IN ('DELETED', 'DELETING') AND t.id = ANY(t2.id_path))
+                                  ORDER BY array_length(t.id_path, 1), p.name, p.id;`
+	getLivePackagesPageQueryFormat = `SELECT %[2]s,
+                                        (SELECT COUNT(*) FROM "%[1]d".packages c
+                                         WHERE c.parent_id = p.id AND c.state NOT IN ('DELETED', 'DELETING')) AS child_count,
+                                        COUNT(*) OVER() AS total_count
+                                      FROM "%[1]d".packages p
+                                      WHERE p.dataset_id = $1
+                                      AND p.parent_id IS NOT DISTINCT FROM $2
+                                      AND p.state NOT IN ('DELETED', 'DELETING')
+                                      ORDER BY %[3]s, p.id
+                                      LIMIT $3 OFFSET $4;`
+	// liveTreeCTEFormat selects the live packages of dataset $1 with their depth, the root level being 1. Packages
+	// below a folder in the trashcan are not live even if they have a live state.
+	liveTreeCTEFormat = `WITH RECURSIVE live(id, type, depth) AS
+                         (
+                           SELECT id, type, 1
+                           FROM "%[1]d".packages
+                           WHERE dataset_id = $1 AND parent_id IS NULL AND state NOT IN ('DELETED', 'DELETING')
+                         UNION ALL
+                           SELECT p.id, p.type, l.depth + 1
+                           FROM "%[1]d".packages p
+                           JOIN live l ON p.parent_id = l.id
+                           WHERE p.state NOT IN ('DELETED', 'DELETING') AND l.type = 'Collection'
+                         )
+                         `
+	getPackageTypeStatsQueryFormat = liveTreeCTEFormat + `SELECT l.type, COUNT(*), COALESCE(SUM(fs.size), 0), MAX(l.depth)
+                         FROM live l
+                         LEFT JOIN (SELECT package_id, SUM(size) AS size FROM "%[1]d".files GROUP BY package_id) fs ON fs.package_id = l.id
+                         GROUP BY l.type;`
+	// getPackageStateStatsQueryFormat counts the packages of dataset $1 that have not been purged by state, along
+	// with how many of them are in the trashcan, which includes the packages below a deleted folder as trashScope does.
+	getPackageStateStatsQueryFormat = `WITH RECURSIVE tree(id, state, in_trash) AS
+                         (
+                           SELECT id, state, state IN ('DELETED', 'DELETING')
+                           FROM "%[1]d".packages p
+                           WHERE dataset_id = $1 AND parent_id IS NULL AND ` + notPurged(1) + `
+                         UNION ALL
+                           SELECT p.id, p.state, t.in_trash OR p.state IN ('DELETED', 'DELETING')
+                           FROM "%[1]d".packages p
+                           JOIN tree t ON p.parent_id = t.id
+                           WHERE ` + notPurged(1) + `
+                         )
+                         SELECT state, COUNT(*), COUNT(*) FILTER (WHERE in_trash)
+                         FROM tree
+                         GROUP BY state;`
+	getFileStatsQueryFormat = liveTreeCTEFormat + `SELECT COUNT(*), COUNT(*) FILTER (WHERE f.checksum IS NULL), COALESCE(SUM(f.size), 0)
+                         FROM live l JOIN "%[1]d".files f ON f.package_id = l.id;`
+	getLargestFilesQueryFormat = liveTreeCTEFormat + `SELECT p.node_id, p.name, f.name, f.size
+                         FROM live l
+                         JOIN "%[1]d".packages p ON p.id = l.id
+                         JOIN "%[1]d".files f ON f.package_id = l.id
+                         WHERE f.size IS NOT NULL
+                         ORDER BY f.size DESC, f.id
+                         LIMIT $2;`
+	getManifestQueryFormat = `WITH RECURSIVE parents (dataset_id, state, id, name, parent_id, node_id, path, parent_names) AS
 		                      (
-		                         SELECT p.dataset_id, p.state, p.id, p.name,  p.parent_id, p.node_id,  array[parent_id]
+		                         SELECT p.dataset_id, p.state, p.id, p.name,  p.parent_id, p.node_id,  array[parent_id], ARRAY[]::text[]
 		                             FROM "%[1]d".packages p
-		                         WHERE p.dataset_id = %[2]d AND p.parent_id IS NULL AND p.state NOT IN ('DELETING', 'DELETED')
+		                         WHERE p.dataset_id = %[2]d AND p.parent_id %[3]s AND p.state NOT IN ('DELETING', 'DELETED')
 		                      UNION
-		                         SELECT children.dataset_id, children.state, children.id, children.name,  children.parent_id, children.node_id, path || children.parent_id
+		                         SELECT children.dataset_id, children.state, children.id, children.name,  children.parent_id, children.node_id, path || children.parent_id, parents.parent_names || parents.name::text
 		                         FROM "%[1]d".packages children
 		                         INNER JOIN parents ON
 		                            parents.id = children.parent_id
 		                         WHERE children.state NOT IN ('DELETING', 'DELETED') AND parents.node_id LIKE 'N:collection:%%'
 							  )
-		                      SELECT parents.id AS package_id, parents.name AS package_name, f.name, path, node_id, f.size, f.checksum, f.uuid
+		                      SELECT parents.id AS package_id, parents.name AS package_name, f.name, path, node_id, f.size, f.checksum, f.uuid, f.s3_bucket, f.s3_key
 		                      FROM parents
-		                      LEFT JOIN "%[1]d".files f ON parents.id = f.package_id`
+		                      LEFT JOIN "%[1]d".files f ON parents.id = f.package_id
+		                      ORDER BY parent_names COLLATE "C", f.name COLLATE "C", parents.id`
+
+	getPackageAncestorsQueryFormat = `WITH RECURSIVE ancestors(id, parent_id, depth) AS
+                                      (
+                                        SELECT id, parent_id, 0
+                                        FROM "%[1]d".packages
+                                        WHERE id = $2
+                                        AND dataset_id = $1
+                                      UNION ALL
+                                        SELECT p.id, p.parent_id, a.depth + 1
+                                        FROM "%[1]d".packages p
+                                        JOIN ancestors a ON p.id = a.parent_id
+                                      )
+                                      SELECT %[2]s
+                                      FROM ancestors a JOIN "%[1]d".packages p ON a.id = p.id
+                                      WHERE a.depth > 0
+                                      ORDER BY a.depth DESC;`
+	// getPackagePathsQueryFormat selects the names and node ids of each package in $2 and its ancestors, from the
+	// dataset root down to the package itself
+	getPackagePathsQueryFormat = `WITH RECURSIVE ancestors(package_id, id, parent_id, depth) AS
+                                  (
+                                    SELECT id, id, parent_id, 0
+                                    FROM "%[1]d".packages
+                                    WHERE id = ANY($2)
+                                    AND dataset_id = $1
+                                  UNION ALL
+                                    SELECT a.package_id, p.id, p.parent_id, a.depth + 1
+                                    FROM "%[1]d".packages p
+                                    JOIN ancestors a ON p.id = a.parent_id
+                                  )
+                                  SELECT a.package_id, array_agg(p.name ORDER BY a.depth DESC), array_agg(p.node_id ORDER BY a.depth DESC)
+                                  FROM ancestors a JOIN "%[1]d".packages p ON a.id = p.id
+                                  GROUP BY a.package_id;`
+	getDeletedDescendantsQueryFormat = `WITH RECURSIVE descendants(id, depth) AS
+                                        (
+                                          SELECT id, 1
+                                          FROM "%[1]d".packages
+                                          WHERE parent_id = $2
+                                          AND dataset_id = $1
+                                        UNION ALL
+                                          SELECT p.id, d.depth + 1
+                                          FROM "%[1]d".packages p
+                                          JOIN descendants d ON p.parent_id = d.id
+                                        )
+                                        SELECT %[2]s
+                                        FROM descendants d JOIN "%[1]d".packages p ON d.id = p.id
+                                        WHERE p.state IN ('DELETED', 'DELETING') AND ` + notPurged(1) + `
+                                        ORDER BY d.depth, p.id;`
+	// trashScopeCTEFormat selects the packages of dataset $1 below the folder $2, or the dataset root if $2 is null,
+	// and whether they are in the trashcan, either themselves or because a folder above them is. The folders above
+	// them include $2 and its ancestors.
+	trashScopeCTEFormat = `WITH RECURSIVE root_path(id, parent_id, state) AS
+                           (
+                             SELECT id, parent_id, state
+                             FROM "%[1]d".packages
+                             WHERE id = $2
+                             AND dataset_id = $1
+                           UNION ALL
+                             SELECT p.id, p.parent_id, p.state
+                             FROM "%[1]d".packages p
+                             JOIN root_path r ON p.id = r.parent_id
+                           ),
+                           scope(id, in_trash) AS
+                           (
+                             SELECT id, state IN ('DELETED', 'DELETING')
+                               OR EXISTS(SELECT 1 FROM root_path r WHERE r.state IN ('DELETED', 'DELETING'))
+                             FROM "%[1]d".packages
+                             WHERE dataset_id = $1
+                             AND parent_id IS NOT DISTINCT FROM $2
+                           UNION ALL
+                             SELECT p.id, s.in_trash OR p.state IN ('DELETED', 'DELETING')
+                             FROM "%[1]d".packages p
+                             JOIN scope s ON p.parent_id = s.id
+                           )
+                           `
+	markTrashForPurgeQueryFormat = trashScopeCTEFormat + `, marked(id) AS
+                                    (
+                                      UPDATE "%[1]d".packages p
+                                      SET state = 'DELETING',
+                                          updated_at = CURRENT_TIMESTAMP
+                                      FROM scope s
+                                      WHERE p.id = s.id AND s.in_trash
+                                      RETURNING p.id
+                                    ),
+                                    recorded AS
+                                    (
+                                      INSERT INTO ` + purgedPackagesTable + ` (organization_id, package_id, dataset_id)
+                                      SELECT %[1]d, id, $1 FROM marked
+                                      ON CONFLICT DO NOTHING
+                                    )
+                                    SELECT id FROM marked;`
+	// getTrashcanSummaryQueryFormat totals what MarkTrashForPurge would mark, apart from packages that were already
+	// purged. The packages that are DELETED or
+	// DELETING themselves were last updated when they were deleted, so their updated_at is their deletion time.
+	getTrashcanSummaryQueryFormat = trashScopeCTEFormat + `SELECT COUNT(DISTINCT p.id),
+                                      COUNT(DISTINCT p.id) FILTER (WHERE p.type = 'Collection'),
+                                      COUNT(f.id),
+                                      COALESCE(SUM(f.size), 0),
+                                      MIN(p.updated_at) FILTER (WHERE p.state IN ('DELETED', 'DELETING')),
+                                      MAX(p.updated_at) FILTER (WHERE p.state IN ('DELETED', 'DELETING'))
+                                    FROM scope s
+                                    JOIN "%[1]d".packages p ON p.id = s.id
+                                    LEFT JOIN "%[1]d".files f ON f.package_id = p.id
+                                    WHERE s.in_trash AND ` + notPurged(1) + `;`
 
 	//getManifestQueryFormatOld = `WITH RECURSIVE parents (dataset_id, state, id, name, file_name, parent_id, node_id, checksum, size, path) AS
 	//							(
@@ -74,7 +284,81 @@ var (
 
 type PackagePage struct {
 	TotalCount int
-	Packages   []pgdb.Package
+	Packages   []TrashcanPackage
+}
+
+// TrashcanPackage is a package in the trashcan with its owner, whose columns are null if the owner's account no
+// longer exists.
+type TrashcanPackage struct {
+	pgdb.Package
+	OwnerNodeId    sql.NullString
+	OwnerFirstName sql.NullString
+	OwnerLastName  sql.NullString
+}
+
+// LivePackage is a package that is not deleted, with the number of live packages directly inside it.
+type LivePackage struct {
+	pgdb.Package
+	ChildCount int
+}
+
+type LivePackagePage struct {
+	TotalCount int
+	Packages   []LivePackage
+}
+
+// deletedNamePrefixFormat is the prefix added to the name of the package in the packages table with alias %[1]s
+// when it was deleted.
+const deletedNamePrefixFormat = `'` + models.DeletedNamePrefix + `' || %[1]s.node_id || '_'`
+
+// originalNameFormat is the name the package in the packages table with alias %[1]s had before it was deleted, as
+// models.OriginalPackageName returns it.
+const originalNameFormat = `CASE WHEN left(%[1]s.name, length(` + deletedNamePrefixFormat + `)) = ` + deletedNamePrefixFormat +
+	` THEN substr(%[1]s.name, length(` + deletedNamePrefixFormat + `) + 1) ELSE %[1]s.name END`
+
+// packageSortColumns maps each models.PackageSortField to the expression it sorts by, where %[1]s is the alias of
+// the packages table. Sort fields come from clients and end up in the query text, so only fields in this map can be
+// used.
+var packageSortColumns = map[models.PackageSortField]string{
+	models.SortByName:      "%[1]s.name",
+	models.SortBySize:      "%[1]s.size",
+	models.SortByCreatedAt: "%[1]s.created_at",
+	models.SortByType:      "%[1]s.type",
+}
+
+// trashcanSortColumns maps each models.TrashcanSortField to the expression it sorts by, as packageSortColumns does.
+// Packages are deleted by setting their state, so updated_at is the deletion time, and names are compared without
+// the prefix added on deletion.
+var trashcanSortColumns = map[models.TrashcanSortField]string{
+	models.TrashcanSortByName:      originalNameFormat,
+	models.TrashcanSortByDeletedAt: "%[1]s.updated_at",
+	models.TrashcanSortBySize:      "%[1]s.size",
+	models.TrashcanSortByType:      "%[1]s.type",
+}
+
+// sortColumn returns the expression that columns maps the field of sort to, on the packages table with the given
+// alias.
+func sortColumn[F ~string](table string, columns map[F]string, sort models.Sort[F]) (string, error) {
+	column, ok := columns[sort.Field]
+	if !ok {
+		return "", fmt.Errorf("unsupported sort field %q", sort.Field)
+	}
+	return fmt.Sprintf(column, table), nil
+}
+
+// sortOrderBy returns the ORDER BY expression for sort on the packages table with the given alias, sorting by the
+// expression that columns maps the field of sort to. Packages without a value, like collections without a size,
+// come last in either order.
+func sortOrderBy[F ~string](table string, columns map[F]string, sort models.Sort[F]) (string, error) {
+	column, err := sortColumn(table, columns, sort)
+	if err != nil {
+		return "", err
+	}
+	direction := "ASC"
+	if sort.Order == models.Descending {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("%s %s NULLS LAST", column, direction), nil
 }
 
 type DatasetsStoreFactory interface {
@@ -161,6 +445,15 @@ func (q *Queries) GetDatasetByNodeId(ctx context.Context, dsNodeId string) (*pgd
 	}
 }
 
+// TouchDataset sets the updated_at of the dataset to the current time. Changes to the packages of a dataset that are
+// made here rather than by the platform, such as restoring or purging the trashcan, call it so that anything keyed on
+// updated_at, such as generated manifests, sees the change.
+func (q *Queries) TouchDataset(ctx context.Context, datasetId int64) error {
+	query := fmt.Sprintf(`UPDATE "%d".datasets SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`, q.OrgId)
+	_, err := q.db.ExecContext(ctx, query, datasetId)
+	return err
+}
+
 func (q *Queries) CountDatasetPackagesByStates(ctx context.Context, datasetId int64, states []packageState.State) (int, error) {
 	query := fmt.Sprintf(`SELECT COUNT(*) FROM "%d".packages WHERE dataset_id = $1 AND state = ANY($2)`, q.OrgId)
 	var count int
@@ -191,18 +484,205 @@ func (q *Queries) GetDatasetPackageByNodeId(ctx context.Context, datasetId int64
 	}
 }
 
-func (q *Queries) queryTrashcan(ctx context.Context, query string, datasetId int64, limit int, offset int) (*PackagePage, error) {
-	rows, err := q.db.QueryContext(ctx, query, datasetId, limit, offset)
+func (q *Queries) queryTrashcan(ctx context.Context, query string, datasetId int64, limit int, offset int, filterArgs []any) (*PackagePage, error) {
+	args := append([]any{datasetId, limit, offset}, filterArgs...)
+	rows, err := q.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var page PackagePage
 	var totalCount int
-	packages := make([]pgdb.Package, limit)
+	packages := make([]TrashcanPackage, limit)
 	i := 0
 	for rows.Next() {
 		p := &packages[i]
+		if err := rows.Scan(append(trashcanPackageDest(p), &totalCount)...); err != nil {
+			return &page, err
+		}
+		i++
+	}
+	if err := rows.Err(); err != nil {
+		return &page, err
+	}
+	page.TotalCount = totalCount
+	page.Packages = packages[:i]
+
+	return &page, nil
+}
+
+// trashcanPackageDest returns the scan destinations of the package and owner columns selected by the trashcan
+// queries.
+func trashcanPackageDest(p *TrashcanPackage) []any {
+	return []any{
+		&p.Id,
+		&p.Name,
+		&p.PackageType,
+		&p.PackageState,
+		&p.NodeId,
+		&p.ParentId,
+		&p.DatasetId,
+		&p.OwnerId,
+		&p.Size,
+		&p.ImportId,
+		&p.Attributes,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.OwnerNodeId,
+		&p.OwnerFirstName,
+		&p.OwnerLastName,
+	}
+}
+
+// GetTrashcanTree returns the whole trashcan of the dataset: every DELETED or DELETING package at any depth, and
+// every package above one. Packages are ordered by depth, so a package always comes after its parent.
+func (q *Queries) GetTrashcanTree(ctx context.Context, datasetId int64) ([]TrashcanPackage, error) {
+	query := fmt.Sprintf(getTrashcanTreeQueryFormat, q.OrgId, qualifiedColumns("p", packagesColumns))
+	rows, err := q.db.QueryContext(ctx, query, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var packages []TrashcanPackage
+	for rows.Next() {
+		var p TrashcanPackage
+		if err := rows.Scan(trashcanPackageDest(&p)...); err != nil {
+			return nil, err
+		}
+		packages = append(packages, p)
+	}
+	return packages, rows.Err()
+}
+
+// GetTrashcanRootPaginated returns a page of the trashcan at the dataset root. The TotalCount of the page is only
+// set if paging.CountTotal() is true.
+func (q *Queries) GetTrashcanRootPaginated(ctx context.Context, datasetId int64, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor]) (*PackagePage, error) {
+	return q.getTrashcanPage(ctx, datasetId, "null::integer", "is null", filter, sort, paging)
+}
+
+// GetTrashcanPaginated is GetTrashcanRootPaginated for the folder with id parentId.
+func (q *Queries) GetTrashcanPaginated(ctx context.Context, datasetId int64, parentId int64, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor]) (*PackagePage, error) {
+	return q.getTrashcanPage(ctx, datasetId, strconv.FormatInt(parentId, 10), fmt.Sprintf("= %d", parentId), filter, sort, paging)
+}
+
+func (q *Queries) getTrashcanPage(ctx context.Context, datasetId int64, parentIdValue string, parentCondition string, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor]) (*PackagePage, error) {
+	orderBy, err := sortOrderBy("p", trashcanSortColumns, sort)
+	if err != nil {
+		return nil, err
+	}
+	// $1 to $3 are the dataset id, limit and offset
+	conditions, filterArgs := trashcanFilterConditions("p", filter, 4)
+	offset := paging.Offset
+	if paging.After != nil {
+		keyset, keysetArgs, err := trashcanKeysetCondition("p", sort, *paging.After, 4+len(filterArgs))
+		if err != nil {
+			return nil, err
+		}
+		conditions += keyset
+		filterArgs = append(filterArgs, keysetArgs...)
+		offset = 0
+	}
+	totalCount := "0"
+	if paging.CountTotal() {
+		totalCount = "COUNT(*) OVER()"
+	}
+	var query string
+	if filter.Flat {
+		query = fmt.Sprintf(getFlatTrashcanPageQueryFormat, q.OrgId, parentCondition, qualifiedColumns("p", packagesColumns), conditions, orderBy, totalCount)
+	} else {
+		query = fmt.Sprintf(getTrashcanPageQueryFormat, parentIdValue, parentCondition, qualifiedColumns("p", packagesColumns), q.OrgId, conditions, orderBy, totalCount)
+	}
+	return q.queryTrashcan(ctx, query, datasetId, paging.Limit, offset, filterArgs)
+}
+
+// trashcanKeysetCondition returns the SQL condition, starting with AND, that selects the packages after cursor in
+// the order sortOrderBy returns for sort, and its arguments, which are numbered from firstArg. Since packages
+// without a value come last, a package with a NULL value can only be followed by others with a NULL value.
+func trashcanKeysetCondition(table string, sort models.TrashcanSort, cursor models.TrashcanCursor, firstArg int) (string, []any, error) {
+	column, err := sortColumn(table, trashcanSortColumns, sort)
+	if err != nil {
+		return "", nil, err
+	}
+	var value any
+	switch sort.Field {
+	case models.TrashcanSortByName:
+		value = cursor.Name
+	case models.TrashcanSortByType:
+		value = cursor.Type
+	case models.TrashcanSortByDeletedAt:
+		if cursor.DeletedAt != nil {
+			value = *cursor.DeletedAt
+		}
+	case models.TrashcanSortBySize:
+		if cursor.Size != nil {
+			value = *cursor.Size
+		}
+	}
+	if value == nil {
+		return fmt.Sprintf(" AND %[2]s IS NULL AND %[1]s.id > $%[3]d", table, column, firstArg), []any{cursor.Id}, nil
+	}
+	comparison := ">"
+	if sort.Order == models.Descending {
+		comparison = "<"
+	}
+	condition := fmt.Sprintf(" AND (%[2]s %[3]s $%[4]d OR (%[2]s = $%[4]d AND %[1]s.id > $%[5]d) OR %[2]s IS NULL)",
+		table, column, comparison, firstArg, firstArg+1)
+	return condition, []any{value, cursor.Id}, nil
+}
+
+// trashcanFilterConditions returns the SQL conditions, each starting with AND, that apply filter to the packages
+// table with the given alias, and their arguments, which are numbered from firstArg. Filter values are only ever
+// passed as arguments. Names are matched without the prefix added on deletion.
+func trashcanFilterConditions(table string, filter models.TrashcanFilter, firstArg int) (string, []any) {
+	var conditions strings.Builder
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions.WriteString(fmt.Sprintf(" AND "+condition, table, firstArg+len(args)-1))
+	}
+	if len(filter.Name) > 0 {
+		pattern := escapeLike(filter.Name) + "%"
+		if !filter.NamePrefix {
+			pattern = "%" + pattern
+		}
+		add(originalNameFormat+" ILIKE $%[2]d", pattern)
+	}
+	if len(filter.Type) > 0 {
+		add("lower(%s.type) = lower($%d)", filter.Type)
+	}
+	if len(filter.State) > 0 {
+		add("%s.state = $%d", filter.State)
+	}
+	if !filter.DeletedAfter.IsZero() {
+		add("%s.updated_at >= $%d", filter.DeletedAfter)
+	}
+	if !filter.DeletedBefore.IsZero() {
+		add("%s.updated_at < $%d", filter.DeletedBefore)
+	}
+	return conditions.String(), args
+}
+
+// escapeLike escapes the LIKE wildcards in s so that it only matches itself.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// GetLivePackagesPaginated returns a page of the packages directly inside the given folder that are not DELETED or
+// DELETING, in the given order. An invalid parentId refers to the dataset root.
+func (q *Queries) GetLivePackagesPaginated(ctx context.Context, datasetId int64, parentId sql.NullInt64, sort models.PackageSort, limit int, offset int) (*LivePackagePage, error) {
+	orderBy, err := sortOrderBy("p", packageSortColumns, sort)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(getLivePackagesPageQueryFormat, q.OrgId, qualifiedColumns("p", packagesColumns), orderBy)
+	rows, err := q.db.QueryContext(ctx, query, datasetId, parentId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	page := LivePackagePage{Packages: []LivePackage{}}
+	for rows.Next() {
+		var p LivePackage
 		if err := rows.Scan(
 			&p.Id,
 			&p.Name,
@@ -217,43 +697,310 @@ func (q *Queries) queryTrashcan(ctx context.Context, query string, datasetId int
 			&p.Attributes,
 			&p.CreatedAt,
 			&p.UpdatedAt,
-			&totalCount); err != nil {
-			return &page, err
+			&p.ChildCount,
+			&page.TotalCount); err != nil {
+			return nil, err
 		}
-		i++
+		page.Packages = append(page.Packages, p)
 	}
 	if err := rows.Err(); err != nil {
-		return &page, err
+		return nil, err
 	}
-	page.TotalCount = totalCount
-	page.Packages = packages[:i]
-
 	return &page, nil
 }
 
-func (q *Queries) GetTrashcanRootPaginated(ctx context.Context, datasetId int64, limit int, offset int) (*PackagePage, error) {
-	getTrashcanRootPageQuery := fmt.Sprintf(getTrashcanPageQueryFormat, "null::integer", "is null", qualifiedColumns("p", packagesColumns), q.OrgId)
-	return q.queryTrashcan(ctx, getTrashcanRootPageQuery, datasetId, limit, offset)
+// queryPackages runs a query whose result columns are packagesColumns and scans each row into a pgdb.Package.
+func (q *Queries) queryPackages(ctx context.Context, query string, args ...any) ([]pgdb.Package, error) {
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var packages []pgdb.Package
+	for rows.Next() {
+		var p pgdb.Package
+		if err := rows.Scan(
+			&p.Id,
+			&p.Name,
+			&p.PackageType,
+			&p.PackageState,
+			&p.NodeId,
+			&p.ParentId,
+			&p.DatasetId,
+			&p.OwnerId,
+			&p.Size,
+			&p.ImportId,
+			&p.Attributes,
+			&p.CreatedAt,
+			&p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		packages = append(packages, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// GetPackageAncestors returns the ancestors of the given package, starting at the dataset root
+// and ending with the package's parent. Returns an empty slice for packages at the root.
+func (q *Queries) GetPackageAncestors(ctx context.Context, datasetId int64, packageId int64) ([]pgdb.Package, error) {
+	query := fmt.Sprintf(getPackageAncestorsQueryFormat, q.OrgId, qualifiedColumns("p", packagesColumns))
+	return q.queryPackages(ctx, query, datasetId, packageId)
+}
+
+// GetPackagePaths returns the path of each of the given packages in the dataset, which is the names from the
+// dataset root down to and including the package separated by '/'. Deleted packages have the names they had before
+// they were deleted. Packages not in the dataset are left out.
+func (q *Queries) GetPackagePaths(ctx context.Context, datasetId int64, packageIds []int64) (map[int64]string, error) {
+	paths := map[int64]string{}
+	if len(packageIds) == 0 {
+		return paths, nil
+	}
+	query := fmt.Sprintf(getPackagePathsQueryFormat, q.OrgId)
+	rows, err := q.db.QueryContext(ctx, query, datasetId, pq.Array(packageIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var names, nodeIds []string
+		if err := rows.Scan(&id, pq.Array(&names), pq.Array(&nodeIds)); err != nil {
+			return nil, err
+		}
+		for i := range names {
+			names[i] = models.OriginalPackageName(names[i], nodeIds[i])
+		}
+		paths[id] = strings.Join(names, "/")
+	}
+	return paths, rows.Err()
 }
 
-func (q *Queries) GetTrashcanPaginated(ctx context.Context, datasetId int64, parentId int64, limit int, offset int) (*PackagePage, error) {
-	pIdStr := strconv.FormatInt(parentId, 10)
-	equalPIdStr := fmt.Sprintf("= %d", parentId)
-	query := fmt.Sprintf(getTrashcanPageQueryFormat, pIdStr, equalPIdStr, qualifiedColumns("p", packagesColumns), q.OrgId)
-	return q.queryTrashcan(ctx, query, datasetId, limit, offset)
+// GetDeletedDescendants returns all DELETED or DELETING packages below the given collection.
+// Results are ordered by depth, so a package always comes after its parent.
+func (q *Queries) GetDeletedDescendants(ctx context.Context, datasetId int64, parentId int64) ([]pgdb.Package, error) {
+	query := fmt.Sprintf(getDeletedDescendantsQueryFormat, q.OrgId, qualifiedColumns("p", packagesColumns))
+	return q.queryPackages(ctx, query, datasetId, parentId)
 }
 
-func (q *Queries) GetDatasetManifest(ctx context.Context, datasetId int64) ([]models.DatasetManifest, error) {
+// CountLivePackagesByName returns the number of packages in the given folder with the given name that are not
+// DELETED or DELETING. An invalid parentId refers to the dataset root.
+func (q *Queries) CountLivePackagesByName(ctx context.Context, datasetId int64, parentId sql.NullInt64, name string) (int, error) {
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM "%d".packages
+                          WHERE dataset_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND name = $3
+                          AND state NOT IN ('DELETED', 'DELETING')`, q.OrgId)
+	var count int
+	err := q.db.QueryRowContext(ctx, query, datasetId, parentId, name).Scan(&count)
+	return count, err
+}
 
-	query := fmt.Sprintf(getManifestQueryFormat, q.OrgId, datasetId)
+func (q *Queries) UpdatePackageNameAndState(ctx context.Context, packageId int64, name string, state packageState.State) error {
+	query := fmt.Sprintf(`UPDATE "%d".packages SET name = $2, state = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, q.OrgId)
+	_, err := q.db.ExecContext(ctx, query, packageId, name, state)
+	return err
+}
+
+// MarkTrashForPurge sets every package in the trashcan below the given folder, and everything below those packages,
+// to DELETING so that the purge worker can permanently remove them, and records them in purgedPackagesTable so that
+// they leave the trashcan. An invalid rootId refers to the dataset root.
+// Packages that were already purged are marked again, so that their ids can be published again. Returns the ids of
+// the marked packages.
+func (q *Queries) MarkTrashForPurge(ctx context.Context, datasetId int64, rootId sql.NullInt64) ([]int64, error) {
+	query := fmt.Sprintf(markTrashForPurgeQueryFormat, q.OrgId)
+	rows, err := q.db.QueryContext(ctx, query, datasetId, rootId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// IsPackagePurged returns true if the package has been purged from the trashcan by MarkTrashForPurge.
+func (q *Queries) IsPackagePurged(ctx context.Context, packageId int64) (bool, error) {
+	query := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE organization_id = $1 AND package_id = $2)`, purgedPackagesTable)
+	var purged bool
+	err := q.db.QueryRowContext(ctx, query, q.OrgId, packageId).Scan(&purged)
+	return purged, err
+}
+
+// PurgedFile is a file of a purged package, to be removed from storage.
+type PurgedFile struct {
+	Id       int64
+	S3Bucket string
+	S3Key    string
+}
+
+// GetPurgedFiles returns the files of the given packages in the dataset. Packages that have not been purged by
+// MarkTrashForPurge are left out, so that the files of a package can only be removed once it left the trashcan.
+func (q *Queries) GetPurgedFiles(ctx context.Context, datasetId int64, packageIds []int64) ([]PurgedFile, error) {
+	query := fmt.Sprintf(`SELECT f.id, f.s3_bucket, f.s3_key
+                          FROM "%[1]d".files f
+                          JOIN "%[1]d".packages p ON p.id = f.package_id
+                          JOIN %[2]s pp ON pp.organization_id = %[1]d AND pp.package_id = p.id
+                          WHERE p.dataset_id = $1
+                          AND p.id = ANY($2)
+                          ORDER BY f.id`, q.OrgId, purgedPackagesTable)
+	rows, err := q.db.QueryContext(ctx, query, datasetId, pq.Array(packageIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var files []PurgedFile
+	for rows.Next() {
+		var f PurgedFile
+		if err := rows.Scan(&f.Id, &f.S3Bucket, &f.S3Key); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// DeleteFiles deletes the rows of the files with the given ids, once their objects have been removed from storage.
+func (q *Queries) DeleteFiles(ctx context.Context, fileIds []int64) error {
+	query := fmt.Sprintf(`DELETE FROM "%d".files WHERE id = ANY($1)`, q.OrgId)
+	_, err := q.db.ExecContext(ctx, query, pq.Array(fileIds))
+	return err
+}
+
+// GetDatasetStats summarizes the packages and files of the dataset. See models.DatasetStats. At most largestFileCount
+// files are returned as the largest files.
+func (q *Queries) GetDatasetStats(ctx context.Context, datasetId int64, largestFileCount int) (*models.DatasetStats, error) {
+	stats := models.DatasetStats{
+		PackageTypes:  map[string]models.PackageTypeStats{},
+		PackageStates: map[string]int{},
+		LargestFiles:  []models.LargestFile{},
+	}
+	if err := q.getPackageTypeStats(ctx, datasetId, &stats); err != nil {
+		return nil, err
+	}
+	if err := q.getPackageStateStats(ctx, datasetId, &stats); err != nil {
+		return nil, err
+	}
+	fileStatsQuery := fmt.Sprintf(getFileStatsQueryFormat, q.OrgId)
+	if err := q.db.QueryRowContext(ctx, fileStatsQuery, datasetId).Scan(&stats.FileCount, &stats.FilesWithoutChecksum, &stats.TotalSize); err != nil {
+		return nil, err
+	}
+	if err := q.getLargestFiles(ctx, datasetId, largestFileCount, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (q *Queries) getPackageTypeStats(ctx context.Context, datasetId int64, stats *models.DatasetStats) error {
+	query := fmt.Sprintf(getPackageTypeStatsQueryFormat, q.OrgId)
+	rows, err := q.db.QueryContext(ctx, query, datasetId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pType string
+		var typeStats models.PackageTypeStats
+		var maxDepth int
+		if err := rows.Scan(&pType, &typeStats.Count, &typeStats.Size, &maxDepth); err != nil {
+			return err
+		}
+		stats.PackageTypes[pType] = typeStats
+		stats.PackageCount += typeStats.Count
+		if pType == packageType.Collection.String() {
+			stats.MaxFolderDepth = maxDepth
+		}
+	}
+	return rows.Err()
+}
+
+func (q *Queries) getPackageStateStats(ctx context.Context, datasetId int64, stats *models.DatasetStats) error {
+	query := fmt.Sprintf(getPackageStateStatsQueryFormat, q.OrgId)
+	rows, err := q.db.QueryContext(ctx, query, datasetId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var state string
+		var count, trashedCount int
+		if err := rows.Scan(&state, &count, &trashedCount); err != nil {
+			return err
+		}
+		stats.PackageStates[state] = count
+		stats.TrashedCount += trashedCount
+	}
+	return rows.Err()
+}
+
+func (q *Queries) getLargestFiles(ctx context.Context, datasetId int64, limit int, stats *models.DatasetStats) error {
+	query := fmt.Sprintf(getLargestFilesQueryFormat, q.OrgId)
+	rows, err := q.db.QueryContext(ctx, query, datasetId, limit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var file models.LargestFile
+		if err := rows.Scan(&file.PackageNodeId, &file.PackageName, &file.FileName, &file.Size); err != nil {
+			return err
+		}
+		stats.LargestFiles = append(stats.LargestFiles, file)
+	}
+	return rows.Err()
+}
+
+// GetTrashcanSummary totals the trashcan below the given folder: everything that MarkTrashForPurge would mark.
+// An invalid rootId refers to the dataset root.
+func (q *Queries) GetTrashcanSummary(ctx context.Context, datasetId int64, rootId sql.NullInt64) (*models.TrashcanSummary, error) {
+	query := fmt.Sprintf(getTrashcanSummaryQueryFormat, q.OrgId)
+	var summary models.TrashcanSummary
+	var oldest, newest sql.NullTime
+	if err := q.db.QueryRowContext(ctx, query, datasetId, rootId).Scan(
+		&summary.PackageCount,
+		&summary.FolderCount,
+		&summary.FileCount,
+		&summary.TotalSize,
+		&oldest,
+		&newest); err != nil {
+		return nil, err
+	}
+	if oldest.Valid {
+		summary.OldestDeletedAt = &oldest.Time
+	}
+	if newest.Valid {
+		summary.NewestDeletedAt = &newest.Time
+	}
+	return &summary, nil
+}
+
+// StreamDatasetManifest calls fn with each row of the dataset's manifest without holding the rows in memory.
+// If rootId is valid, only the packages below that collection are included. Rows are ordered by the names of their
+// parent collections and then by file name, as models.CompareManifestFiles orders manifest files, so each
+// collection comes before its contents. Iteration stops at the first error returned by fn, which is then returned.
+func (q *Queries) StreamDatasetManifest(ctx context.Context, datasetId int64, rootId sql.NullInt64, fn func(models.DatasetManifest) error) error {
+
+	parentCondition := "IS NULL"
+	if rootId.Valid {
+		parentCondition = fmt.Sprintf("= %d", rootId.Int64)
+	}
+	query := fmt.Sprintf(getManifestQueryFormat, q.OrgId, datasetId, parentCondition)
 
 	rows, err := q.db.QueryContext(ctx, query)
 	if err != nil {
 		log.Println("ERROR: ", err)
-		return nil, err
+		return err
 	}
 	defer rows.Close()
-	var files []models.DatasetManifest
 	for rows.Next() {
 		var m models.DatasetManifest
 		err = rows.Scan(
@@ -264,17 +1011,21 @@ func (q *Queries) GetDatasetManifest(ctx context.Context, datasetId int64) ([]mo
 			&m.PackageNodeId,
 			&m.Size,
 			&m.CheckSum,
-			&m.FileUUID)
+			&m.FileUUID,
+			&m.S3Bucket,
+			&m.S3Key)
 
 		if err != nil {
 			log.Println("ERROR: ", err)
-			return nil, err
+			return err
 		}
 
-		files = append(files, m)
+		if err = fn(m); err != nil {
+			return err
+		}
 	}
 
-	return files, nil
+	return rows.Err()
 
 }
 
@@ -288,9 +1039,23 @@ func qualifiedColumns(table string, columns []string) string {
 
 type DatasetsStore interface {
 	GetDatasetByNodeId(ctx context.Context, dsNodeId string) (*pgdb.Dataset, error)
-	GetTrashcanRootPaginated(ctx context.Context, datasetId int64, limit int, offset int) (*PackagePage, error)
-	GetTrashcanPaginated(ctx context.Context, datasetId int64, parentId int64, limit int, offset int) (*PackagePage, error)
+	TouchDataset(ctx context.Context, datasetId int64) error
+	GetTrashcanRootPaginated(ctx context.Context, datasetId int64, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor]) (*PackagePage, error)
+	GetTrashcanPaginated(ctx context.Context, datasetId int64, parentId int64, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor]) (*PackagePage, error)
+	GetTrashcanTree(ctx context.Context, datasetId int64) ([]TrashcanPackage, error)
+	GetLivePackagesPaginated(ctx context.Context, datasetId int64, parentId sql.NullInt64, sort models.PackageSort, limit int, offset int) (*LivePackagePage, error)
 	CountDatasetPackagesByStates(ctx context.Context, datasetId int64, states []packageState.State) (int, error)
 	GetDatasetPackageByNodeId(ctx context.Context, datasetId int64, packageNodeId string) (*pgdb.Package, error)
-	GetDatasetManifest(ctx context.Context, datasetId int64) ([]models.DatasetManifest, error)
+	StreamDatasetManifest(ctx context.Context, datasetId int64, rootId sql.NullInt64, fn func(models.DatasetManifest) error) error
+	GetPackageAncestors(ctx context.Context, datasetId int64, packageId int64) ([]pgdb.Package, error)
+	GetPackagePaths(ctx context.Context, datasetId int64, packageIds []int64) (map[int64]string, error)
+	GetDeletedDescendants(ctx context.Context, datasetId int64, parentId int64) ([]pgdb.Package, error)
+	CountLivePackagesByName(ctx context.Context, datasetId int64, parentId sql.NullInt64, name string) (int, error)
+	UpdatePackageNameAndState(ctx context.Context, packageId int64, name string, state packageState.State) error
+	MarkTrashForPurge(ctx context.Context, datasetId int64, rootId sql.NullInt64) ([]int64, error)
+	IsPackagePurged(ctx context.Context, packageId int64) (bool, error)
+	GetPurgedFiles(ctx context.Context, datasetId int64, packageIds []int64) ([]PurgedFile, error)
+	DeleteFiles(ctx context.Context, fileIds []int64) error
+	GetDatasetStats(ctx context.Context, datasetId int64, largestFileCount int) (*models.DatasetStats, error)
+	GetTrashcanSummary(ctx context.Context, datasetId int64, rootId sql.NullInt64) (*models.TrashcanSummary, error)
 }
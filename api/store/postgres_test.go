@@ -10,6 +10,7 @@ import (
 	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestGetDatasetByNodeId(t *testing.T) {
@@ -50,6 +51,27 @@ func TestGetDatasetByNodeId(t *testing.T) {
 
 }
 
+func TestTouchDataset(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	orgId := 3
+	store := db.Queries(orgId)
+	insert := fmt.Sprintf("INSERT INTO \"%d\".datasets (id, name, state, node_id, status_id, updated_at) VALUES ($1, $2, $3, $4, $5, $6)", orgId)
+	before := time.Now().Add(-time.Hour)
+	_, err := db.Exec(insert, 1, "Test Dataset", "READY", "N:dataset:1234", 1, before)
+	defer db.Truncate(orgId, "datasets")
+
+	if assert.NoError(t, err) {
+		if assert.NoError(t, store.TouchDataset(context.Background(), 1)) {
+			actual, err := store.GetDatasetByNodeId(context.Background(), "N:dataset:1234")
+			if assert.NoError(t, err) {
+				assert.True(t, actual.UpdatedAt.After(before))
+			}
+		}
+	}
+}
+
 func TestGetTrashcanPaginated(t *testing.T) {
 	rootNodeIdToExpectedLevel := map[int64]TrashcanLevel{
 		// Level zero
@@ -287,13 +309,240 @@ func TestGetTrashcanDeleting(t *testing.T) {
 
 }
 
+func TestGetTrashcanFiltered(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	db.PrefixDeletedNames(2)
+	store := db.Queries(2)
+	for tName, tData := range map[string]struct {
+		parentId    int64
+		filter      models.TrashcanFilter
+		expectedIds []int64
+	}{
+		"level by type":          {0, models.TrashcanFilter{Type: "collection"}, []int64{5, 4}},
+		"level by state":         {0, models.TrashcanFilter{State: "DELETED"}, []int64{4, 2}},
+		"flat folder":            {21, models.TrashcanFilter{Flat: true}, []int64{38, 37, 42, 33, 34, 30}},
+		"flat by name":           {0, models.TrashcanFilter{Flat: true, Name: "FOUR-file"}, []int64{38, 37, 42}},
+		"flat by name prefix":    {0, models.TrashcanFilter{Flat: true, Name: "two", NamePrefix: true}, []int64{22, 26, 16, 19, 25, 20}},
+		"flat by name infix":     {0, models.TrashcanFilter{Flat: true, Name: "deleted-2"}, []int64{14, 34, 20}},
+		"prefix is not infix":    {0, models.TrashcanFilter{Flat: true, Name: "deleted-2", NamePrefix: true}, nil},
+		"wildcards are escaped":  {0, models.TrashcanFilter{Flat: true, Name: "_"}, nil},
+		"deleted prefix ignored": {0, models.TrashcanFilter{Flat: true, Name: models.DeletedNamePrefix, NamePrefix: true}, nil},
+		"node id ignored":        {0, models.TrashcanFilter{Flat: true, Name: "N:package"}, nil},
+		"flat by type":           {0, models.TrashcanFilter{Flat: true, Type: "Image"}, []int64{37, 42, 36}},
+		"flat deleted after":     {0, models.TrashcanFilter{Flat: true, DeletedAfter: time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC)}, []int64{38, 37, 42, 36}},
+		"flat deleted between":   {0, models.TrashcanFilter{Flat: true, DeletedAfter: time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC), DeletedBefore: time.Date(2023, 2, 6, 0, 0, 0, 0, time.UTC)}, []int64{37, 36}},
+		"flat deleting":          {0, models.TrashcanFilter{Flat: true, State: "DELETING"}, nil},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			var page *PackagePage
+			var err error
+			if tData.parentId == 0 {
+				page, err = store.GetTrashcanRootPaginated(context.Background(), 1, tData.filter, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: 10})
+			} else {
+				page, err = store.GetTrashcanPaginated(context.Background(), 1, tData.parentId, tData.filter, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: 10})
+			}
+			if assert.NoError(t, err) {
+				var actualIds []int64
+				for _, p := range page.Packages {
+					actualIds = append(actualIds, p.Id)
+				}
+				assert.Equal(t, tData.expectedIds, actualIds)
+				assert.Equal(t, len(tData.expectedIds), page.TotalCount)
+			}
+		})
+	}
+}
+
+func TestGetTrashcanSorted(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	store := db.Queries(2)
+	for tName, tData := range map[string]struct {
+		parentId      int64
+		filter        models.TrashcanFilter
+		sort          models.TrashcanSort
+		expectedIds   []int64
+		expectedTotal int
+	}{
+		"level newest first": {0, models.TrashcanFilter{}, models.TrashcanSort{Field: models.TrashcanSortByDeletedAt, Order: models.Descending}, []int64{5, 4, 2}, 3},
+		"level oldest first": {0, models.TrashcanFilter{}, models.TrashcanSort{Field: models.TrashcanSortByDeletedAt, Order: models.Ascending}, []int64{2, 4, 5}, 3},
+		"level by type":      {0, models.TrashcanFilter{}, models.TrashcanSort{Field: models.TrashcanSortByType, Order: models.Ascending}, []int64{4, 5, 2}, 3},
+		"level by type desc": {0, models.TrashcanFilter{}, models.TrashcanSort{Field: models.TrashcanSortByType, Order: models.Descending}, []int64{2, 4, 5}, 3},
+		"flat newest first":  {0, models.TrashcanFilter{Flat: true}, models.TrashcanSort{Field: models.TrashcanSortByDeletedAt, Order: models.Descending}, []int64{42, 38, 37, 36, 33, 34, 30, 26, 22, 25}, 21},
+		"flat by size":       {21, models.TrashcanFilter{Flat: true}, models.TrashcanSort{Field: models.TrashcanSortBySize, Order: models.Descending}, []int64{30, 33, 34, 37, 38, 42}, 6},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			var page *PackagePage
+			var err error
+			if tData.parentId == 0 {
+				page, err = store.GetTrashcanRootPaginated(context.Background(), 1, tData.filter, tData.sort, models.Paging[models.TrashcanCursor]{Limit: 10})
+			} else {
+				page, err = store.GetTrashcanPaginated(context.Background(), 1, tData.parentId, tData.filter, tData.sort, models.Paging[models.TrashcanCursor]{Limit: 10})
+			}
+			if assert.NoError(t, err) {
+				var actualIds []int64
+				for _, p := range page.Packages {
+					actualIds = append(actualIds, p.Id)
+				}
+				assert.Equal(t, tData.expectedIds, actualIds)
+				assert.Equal(t, tData.expectedTotal, page.TotalCount)
+			}
+		})
+	}
+}
+
+func TestGetTrashcanSortedByName(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	store := db.Queries(2)
+	sorts := []models.TrashcanSort{models.DefaultTrashcanSort, {Field: models.TrashcanSortByName, Order: models.Descending}}
+	filters := []models.TrashcanFilter{{}, {Flat: true}}
+	getIds := func(filter models.TrashcanFilter, sort models.TrashcanSort) []int64 {
+		page, err := store.GetTrashcanRootPaginated(context.Background(), 1, filter, sort, models.Paging[models.TrashcanCursor]{Limit: 50})
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		var ids []int64
+		for _, p := range page.Packages {
+			ids = append(ids, p.Id)
+		}
+		return ids
+	}
+	var expectedIds [][]int64
+	for _, filter := range filters {
+		for _, sort := range sorts {
+			expectedIds = append(expectedIds, getIds(filter, sort))
+		}
+	}
+	assert.Equal(t, []int64{5, 4, 2}, expectedIds[0])
+
+	// Deleting a package prefixes its name with its node id, which must not change the order
+	db.PrefixDeletedNames(2)
+	var actualIds [][]int64
+	for _, filter := range filters {
+		for _, sort := range sorts {
+			actualIds = append(actualIds, getIds(filter, sort))
+		}
+	}
+	assert.Equal(t, expectedIds, actualIds)
+}
+
+func TestGetTrashcanPaginatedBadSort(t *testing.T) {
+	store := NewQueries(nil, 2)
+	_, err := store.GetTrashcanRootPaginated(context.Background(), 1, models.TrashcanFilter{}, models.TrashcanSort{Field: "name; DROP TABLE packages"}, models.Paging[models.TrashcanCursor]{Limit: 10})
+	assert.ErrorContains(t, err, "unsupported sort field")
+}
+
+func TestGetTrashcanCursor(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	db.PrefixDeletedNames(2)
+	store := db.Queries(2)
+	for tName, tData := range map[string]struct {
+		filter      models.TrashcanFilter
+		sort        models.TrashcanSort
+		expectedIds []int64
+	}{
+		"newest first": {models.TrashcanFilter{Flat: true}, models.TrashcanSort{Field: models.TrashcanSortByDeletedAt, Order: models.Descending},
+			[]int64{42, 38, 37, 36, 33, 34, 30, 26, 22, 25, 20, 10, 15, 27, 19, 13, 16, 14, 8, 4, 2}},
+		"by size": {models.TrashcanFilter{Flat: true, Type: "Image"}, models.TrashcanSort{Field: models.TrashcanSortBySize, Order: models.Ascending},
+			[]int64{36, 37, 42}},
+		"level by type": {models.TrashcanFilter{}, models.TrashcanSort{Field: models.TrashcanSortByType, Order: models.Ascending},
+			[]int64{4, 5, 2}},
+		"by name": {models.TrashcanFilter{Flat: true, Name: "two", NamePrefix: true}, models.DefaultTrashcanSort,
+			[]int64{22, 26, 16, 19, 25, 20}},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			var actualIds []int64
+			paging := models.Paging[models.TrashcanCursor]{Limit: 2}
+			for pages := 0; pages < 20; pages++ {
+				page, err := store.GetTrashcanRootPaginated(context.Background(), 1, tData.filter, tData.sort, paging)
+				if !assert.NoError(t, err) {
+					return
+				}
+				if pages == 0 {
+					assert.Equal(t, len(tData.expectedIds), page.TotalCount)
+				} else {
+					assert.Zero(t, page.TotalCount)
+				}
+				for _, p := range page.Packages {
+					actualIds = append(actualIds, p.Id)
+				}
+				if len(page.Packages) < paging.Limit {
+					break
+				}
+				last := page.Packages[len(page.Packages)-1]
+				var size *int64
+				if last.Size.Valid {
+					size = &last.Size.Int64
+				}
+				after := models.NewTrashcanCursor(tData.sort, last.Id, models.OriginalPackageName(last.Name, last.NodeId), last.PackageType.String(), last.UpdatedAt, size)
+				paging.After = &after
+			}
+			assert.Equal(t, tData.expectedIds, actualIds)
+		})
+	}
+}
+
+func TestTrashcanKeysetCondition(t *testing.T) {
+	size := int64(2048)
+	deletedAt := time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC)
+	for tName, tData := range map[string]struct {
+		sort              models.TrashcanSort
+		cursor            models.TrashcanCursor
+		expectedCondition string
+		expectedArgs      []any
+	}{
+		"name ascending": {models.DefaultTrashcanSort, models.TrashcanCursor{Name: "a.csv", Id: 7},
+			fmt.Sprintf(" AND (%[1]s > $6 OR (%[1]s = $6 AND p.id > $7) OR %[1]s IS NULL)", fmt.Sprintf(originalNameFormat, "p")), []any{"a.csv", int64(7)}},
+		"deleted_at descending": {models.TrashcanSort{Field: models.TrashcanSortByDeletedAt, Order: models.Descending}, models.TrashcanCursor{DeletedAt: &deletedAt, Id: 7},
+			" AND (p.updated_at < $6 OR (p.updated_at = $6 AND p.id > $7) OR p.updated_at IS NULL)", []any{deletedAt, int64(7)}},
+		"size": {models.TrashcanSort{Field: models.TrashcanSortBySize, Order: models.Ascending}, models.TrashcanCursor{Size: &size, Id: 7},
+			" AND (p.size > $6 OR (p.size = $6 AND p.id > $7) OR p.size IS NULL)", []any{size, int64(7)}},
+		"null size": {models.TrashcanSort{Field: models.TrashcanSortBySize, Order: models.Descending}, models.TrashcanCursor{Id: 7},
+			" AND p.size IS NULL AND p.id > $6", []any{int64(7)}},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			condition, args, err := trashcanKeysetCondition("p", tData.sort, tData.cursor, 6)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tData.expectedCondition, condition)
+				assert.Equal(t, tData.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestTrashcanFilterConditions(t *testing.T) {
+	after := time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC)
+	conditions, args := trashcanFilterConditions("p", models.TrashcanFilter{Name: `50%_off\`, Type: "CSV", DeletedAfter: after}, 4)
+	assert.Equal(t, " AND "+fmt.Sprintf(originalNameFormat, "p")+" ILIKE $4 AND lower(p.type) = lower($5) AND p.updated_at >= $6", conditions)
+	assert.Equal(t, []any{`%50\%\_off\\%`, "CSV", after}, args)
+
+	conditions, args = trashcanFilterConditions("p", models.TrashcanFilter{Flat: true}, 4)
+	assert.Empty(t, conditions)
+	assert.Empty(t, args)
+}
+
 func CheckGetTrashcanLevel(t *testing.T, store DatasetsStore, rootFolderId int64, expectedLevel TrashcanLevel) {
 	var page *PackagePage
 	var err error
 	if rootFolderId == 0 {
-		page, err = store.GetTrashcanRootPaginated(context.Background(), 1, 10, 0)
+		page, err = store.GetTrashcanRootPaginated(context.Background(), 1, models.TrashcanFilter{}, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: 10})
 	} else {
-		page, err = store.GetTrashcanPaginated(context.Background(), 1, rootFolderId, 10, 0)
+		page, err = store.GetTrashcanPaginated(context.Background(), 1, rootFolderId, models.TrashcanFilter{}, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: 10})
 	}
 
 	if assert.NoError(t, err) {
@@ -318,16 +567,108 @@ func TestGetManifest(t *testing.T) {
 	datasetId := int64(1)
 	store := db.Queries(orgId)
 
-	actual, err := store.GetDatasetManifest(context.Background(), datasetId)
+	var actual []models.DatasetManifest
+	err := store.StreamDatasetManifest(context.Background(), datasetId, sql.NullInt64{}, func(m models.DatasetManifest) error {
+		actual = append(actual, m)
+		return nil
+	})
 
 	if assert.NoError(t, err) {
 		// Should ignore DELETED packages
 		// Should include two results for package with multiple source files.
 		assert.Len(t, actual, 10, "Incorrect number of results.")
+		// Ordered by the names of the parent collections, then by file name
+		var packageIds []int
+		for _, m := range actual {
+			packageIds = append(packageIds, m.PackageId)
+		}
+		assert.Equal(t, []int{1, 3, 5, 6, 7, 7, 9, 11, 12, 10}, packageIds)
 	}
 
 }
 
+func TestGetDatasetStats(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("manifest-test.sql")
+	defer func() {
+		db.Truncate(2, "packages")
+		db.Truncate(2, "files")
+	}()
+	_, err := db.Exec(`UPDATE "2".files SET checksum = NULL, size = 25 WHERE id = 9`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	store := db.Queries(2)
+	stats, err := store.GetDatasetStats(context.Background(), 1, 2)
+	if assert.NoError(t, err) {
+		// Deleted packages and their files are only counted by state
+		assert.Equal(t, 9, stats.PackageCount)
+		assert.Equal(t, 6, stats.FileCount)
+		assert.Equal(t, int64(75), stats.TotalSize)
+		assert.Equal(t, map[string]models.PackageTypeStats{
+			"Collection": {Count: 4, Size: 0},
+			"Text":       {Count: 1, Size: 10},
+			"PERSYST":    {Count: 1, Size: 20},
+			"JPEG":       {Count: 1, Size: 10},
+			"CSV":        {Count: 2, Size: 35},
+		}, stats.PackageTypes)
+		assert.Equal(t, map[string]int{"UPLOADED": 5, "READY": 4, "DELETED": 2}, stats.PackageStates)
+		assert.Equal(t, 2, stats.TrashedCount)
+		assert.Equal(t, 2, stats.MaxFolderDepth)
+		assert.Equal(t, 1, stats.FilesWithoutChecksum)
+		assert.Equal(t, []models.LargestFile{
+			{PackageNodeId: "N:package:11", PackageName: "two-file-2.csv", FileName: "two-file-2.csv", Size: 25},
+			{PackageNodeId: "N:package:1", PackageName: "root-file.txt-1", FileName: "root-file.txt-1", Size: 10},
+		}, stats.LargestFiles)
+	}
+}
+
+func TestGetDatasetStatsTrash(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("manifest-test.sql")
+	defer func() {
+		db.Truncate(2, "packages")
+		db.Truncate(2, "files")
+		db.TruncatePurgedPackages()
+	}()
+	// one-dir-1 is deleted with its two files, and root-file-deleted-1.txt purged
+	_, err := db.Exec(`UPDATE "2".packages SET state = 'DELETED' WHERE id = 9`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = db.Exec(`INSERT INTO ` + purgedPackagesTable + ` (organization_id, package_id, dataset_id) VALUES (2, 2, 1)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	store := db.Queries(2)
+	stats, err := store.GetDatasetStats(context.Background(), 1, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]int{"UPLOADED": 5, "READY": 3, "DELETED": 2}, stats.PackageStates)
+		assert.Equal(t, 4, stats.TrashedCount)
+	}
+}
+
+func TestGetDatasetStatsEmpty(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	store := db.Queries(2)
+	stats, err := store.GetDatasetStats(context.Background(), 1, 10)
+	if assert.NoError(t, err) {
+		assert.Zero(t, stats.PackageCount)
+		assert.Zero(t, stats.TotalSize)
+		assert.Zero(t, stats.MaxFolderDepth)
+		assert.Empty(t, stats.PackageTypes)
+		assert.NotNil(t, stats.LargestFiles)
+	}
+}
+
 func TestGetPackageByNodeId(t *testing.T) {
 	db := OpenDB(t)
 	defer db.Close()
@@ -361,6 +702,390 @@ func TestGetPackageByNodeId_BadPackage(t *testing.T) {
 
 }
 
+func TestGetLivePackagesPaginated(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	store := db.Queries(2)
+	root := sql.NullInt64{}
+	for tName, tData := range map[string]struct {
+		parentId            sql.NullInt64
+		sort                models.PackageSort
+		limit               int
+		offset              int
+		expectedIds         []int64
+		expectedChildCounts []int
+		expectedTotal       int
+	}{
+		"root by name":           {root, models.DefaultPackageSort, 10, 0, []int64{5, 6, 3, 1}, []int{2, 2, 0, 0}, 4},
+		"root by created desc":   {root, models.PackageSort{Field: models.SortByCreatedAt, Order: models.Descending}, 10, 0, []int64{3, 6, 5, 1}, []int{0, 2, 2, 0}, 4},
+		"root by type":           {root, models.PackageSort{Field: models.SortByType, Order: models.Ascending}, 10, 0, []int64{3, 5, 6, 1}, []int{0, 2, 2, 0}, 4},
+		"root page":              {root, models.DefaultPackageSort, 2, 1, []int64{6, 3}, []int{2, 0}, 4},
+		"folder skips deleted":   {sql.NullInt64{Int64: 9, Valid: true}, models.DefaultPackageSort, 10, 0, []int64{21, 17, 18}, []int{4, 0, 0}, 3},
+		"empty folder":           {sql.NullInt64{Int64: 3, Valid: true}, models.DefaultPackageSort, 10, 0, nil, nil, 0},
+		"offset past last child": {root, models.DefaultPackageSort, 10, 10, nil, nil, 0},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			page, err := store.GetLivePackagesPaginated(context.Background(), 1, tData.parentId, tData.sort, tData.limit, tData.offset)
+			if assert.NoError(t, err) {
+				var actualIds []int64
+				var actualChildCounts []int
+				for _, p := range page.Packages {
+					actualIds = append(actualIds, p.Id)
+					actualChildCounts = append(actualChildCounts, p.ChildCount)
+				}
+				assert.Equal(t, tData.expectedIds, actualIds)
+				assert.Equal(t, tData.expectedChildCounts, actualChildCounts)
+				assert.Equal(t, tData.expectedTotal, page.TotalCount)
+			}
+		})
+	}
+}
+
+func TestGetLivePackagesPaginatedBadSort(t *testing.T) {
+	store := NewQueries(nil, 2)
+	_, err := store.GetLivePackagesPaginated(context.Background(), 1, sql.NullInt64{}, models.PackageSort{Field: "name; DROP TABLE packages"}, 10, 0)
+	assert.ErrorContains(t, err, "unsupported sort field")
+}
+
+func TestGetPackageAncestors(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	store := db.Queries(2)
+	for packageId, expectedAncestorIds := range map[int64][]int64{
+		1:  nil,            // root-file.txt-1
+		8:  {5},            // root-dir-1/one-file-deleted-1.csv
+		27: {5, 9, 22},     // root-dir-1/one-dir-1/two-dir-deleted-1/three-file-deleted-1.csv
+		36: {4, 15, 26},    // root-dir-deleted-1/one-dir-deleted-1/two-dir-deleted-1/three-file-deleted-1.png
+		37: {5, 9, 21, 34}, // root-dir-1/one-dir-1/two-dir-1/three-dir-deleted-2/four-file-deleted-1.png
+	} {
+		t.Run(fmt.Sprintf("ancestors of %d", packageId), func(t *testing.T) {
+			ancestors, err := store.GetPackageAncestors(context.Background(), 1, packageId)
+			if assert.NoError(t, err) {
+				var actualIds []int64
+				for _, a := range ancestors {
+					actualIds = append(actualIds, a.Id)
+				}
+				assert.Equal(t, expectedAncestorIds, actualIds)
+			}
+		})
+	}
+}
+
+func TestGetTrashcanTree(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	store := db.Queries(2)
+	packages, err := store.GetTrashcanTree(context.Background(), 1)
+	if assert.NoError(t, err) {
+		var actualIds []int64
+		for _, p := range packages {
+			actualIds = append(actualIds, p.Id)
+		}
+		// every deleted package, including those inside deleted folders, and the live folders 5, 9, 21 and 31 above
+		// them, by depth and then name
+		assert.Equal(t, []int64{
+			5, 4, 2,
+			9, 10, 15, 8, 13, 14,
+			21, 22, 26, 16, 19, 25, 20,
+			31, 33, 34, 27, 36, 30,
+			38, 37, 42,
+		}, actualIds)
+	}
+}
+
+func TestGetPackagePaths(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	store := db.Queries(2)
+	for tName, tData := range map[string]struct {
+		packageIds    []int64
+		expectedPaths map[int64]string
+	}{
+		"none": {nil, map[int64]string{}},
+		"several": {[]int64{1, 8, 22, 27}, map[int64]string{
+			1:  "root-file.txt-1",
+			8:  "root-dir-1/one-file-deleted-1.csv",
+			22: "root-dir-1/one-dir-1/two-dir-deleted-1",
+			27: "root-dir-1/one-dir-1/two-dir-deleted-1/three-file-deleted-1.csv",
+		}},
+		"missing package": {[]int64{5, 9999}, map[int64]string{5: "root-dir-1"}},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			paths, err := store.GetPackagePaths(context.Background(), 1, tData.packageIds)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tData.expectedPaths, paths)
+			}
+		})
+	}
+
+	t.Run("deleted names", func(t *testing.T) {
+		_, err := db.Exec(`UPDATE "2".packages SET name = '__DELETED__' || node_id || '_' || name WHERE id IN (22, 27)`)
+		if assert.NoError(t, err) {
+			paths, err := store.GetPackagePaths(context.Background(), 1, []int64{27})
+			if assert.NoError(t, err) {
+				assert.Equal(t, map[int64]string{27: "root-dir-1/one-dir-1/two-dir-deleted-1/three-file-deleted-1.csv"}, paths)
+			}
+		}
+	})
+}
+
+func TestGetDeletedDescendants(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	store := db.Queries(2)
+	for parentId, expectedIds := range map[int64][]int64{
+		4:  {13, 14, 15, 25, 26, 36}, // root-dir-deleted-1
+		21: {30, 33, 34, 37, 38, 42}, // root-dir-1/one-dir-1/two-dir-1
+		3:  nil,                      // root-dir-empty-1
+	} {
+		t.Run(fmt.Sprintf("deleted descendants of %d", parentId), func(t *testing.T) {
+			descendants, err := store.GetDeletedDescendants(context.Background(), 1, parentId)
+			if assert.NoError(t, err) {
+				var actualIds []int64
+				for _, d := range descendants {
+					actualIds = append(actualIds, d.Id)
+				}
+				assert.Equal(t, expectedIds, actualIds)
+			}
+		})
+	}
+}
+
+func TestCountLivePackagesByName(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	store := db.Queries(2)
+	for tName, tData := range map[string]struct {
+		parentId sql.NullInt64
+		name     string
+		expected int
+	}{
+		"live root package":      {sql.NullInt64{}, "root-file.txt-1", 1},
+		"deleted root package":   {sql.NullInt64{}, "root-dir-deleted-1", 0},
+		"live folder package":    {sql.NullInt64{Int64: 9, Valid: true}, "two-file-1.csv", 1},
+		"deleted folder package": {sql.NullInt64{Int64: 9, Valid: true}, "two-file-deleted-1.csv", 0},
+		"wrong folder":           {sql.NullInt64{Int64: 5, Valid: true}, "two-file-1.csv", 0},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			count, err := store.CountLivePackagesByName(context.Background(), 1, tData.parentId, tData.name)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tData.expected, count)
+			}
+		})
+	}
+}
+
+func TestUpdatePackageNameAndState(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	store := db.Queries(2)
+	nodeId := "N:collection:0f197fab-cb7b-4414-8f7c-27d7aafe7c53"
+	before, err := store.GetDatasetPackageByNodeId(context.Background(), 1, nodeId)
+	if assert.NoError(t, err) {
+		if assert.NoError(t, store.UpdatePackageNameAndState(context.Background(), before.Id, "restored", packageState.Ready)) {
+			after, err := store.GetDatasetPackageByNodeId(context.Background(), 1, nodeId)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "restored", after.Name)
+				assert.Equal(t, packageState.Ready, after.PackageState)
+				assert.True(t, after.UpdatedAt.After(before.UpdatedAt))
+			}
+		}
+	}
+}
+
+func TestMarkTrashForPurge(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	defer db.TruncatePurgedPackages()
+	store := db.Queries(2)
+
+	// root-dir-1/one-dir-1/two-dir-1
+	rootId := sql.NullInt64{Int64: 21, Valid: true}
+	ids, err := store.MarkTrashForPurge(context.Background(), 1, rootId)
+	if assert.NoError(t, err) {
+		assert.ElementsMatch(t, []int64{30, 33, 34, 37, 38, 42}, ids)
+		for _, id := range ids {
+			var name string
+			var state packageState.State
+			if assert.NoError(t, db.QueryRow(`SELECT name, state FROM "2".packages WHERE id = $1`, id).Scan(&name, &state)) {
+				assert.Equal(t, packageState.Deleting, state)
+				// purges are recorded in their own table, so other readers of packages see the name as it was
+				assert.NotContains(t, name, "PURGED")
+			}
+			purged, err := store.IsPackagePurged(context.Background(), id)
+			if assert.NoError(t, err) {
+				assert.True(t, purged, id)
+			}
+		}
+		// purged packages are no longer in the trashcan
+		descendants, err := store.GetDeletedDescendants(context.Background(), 1, rootId.Int64)
+		if assert.NoError(t, err) {
+			assert.Empty(t, descendants)
+		}
+		page, err := store.GetTrashcanPaginated(context.Background(), 1, rootId.Int64, models.TrashcanFilter{}, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: 10})
+		if assert.NoError(t, err) {
+			assert.Empty(t, page.Packages)
+		}
+		summary, err := store.GetTrashcanSummary(context.Background(), 1, rootId)
+		if assert.NoError(t, err) {
+			assert.Zero(t, summary.PackageCount)
+		}
+		// purging again marks the same packages, so that a failed publish can be retried, without recording them twice
+		again, err := store.MarkTrashForPurge(context.Background(), 1, rootId)
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, ids, again)
+			var recorded int
+			if assert.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM datasets_service.purged_packages WHERE organization_id = 2 AND dataset_id = 1`).Scan(&recorded)) {
+				assert.Equal(t, len(ids), recorded)
+			}
+		}
+		// live package with a deleted sibling is untouched
+		live, err := store.GetDatasetPackageByNodeId(context.Background(), 1, "N:package:b8578c6b-929d-4b1a-876b-5e0a87cfa3ad")
+		if assert.NoError(t, err) {
+			assert.Equal(t, packageState.Uploaded, live.PackageState)
+		}
+	}
+}
+
+func TestGetPurgedFiles(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("manifest-test.sql")
+	defer func() {
+		db.Truncate(2, "packages")
+		db.Truncate(2, "files")
+		db.TruncatePurgedPackages()
+	}()
+	store := db.Queries(2)
+
+	// root-file-deleted-1.txt
+	ids, err := store.MarkTrashForPurge(context.Background(), 1, sql.NullInt64{})
+	if !assert.NoError(t, err) || !assert.Contains(t, ids, int64(2)) {
+		return
+	}
+	// one-file-1-multiple-sources is live, so its files are left out
+	files, err := store.GetPurgedFiles(context.Background(), 1, []int64{2, 7})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []PurgedFile{{Id: 2, S3Bucket: "storage-use1", S3Key: "1111/2222"}}, files)
+	}
+	if assert.NoError(t, store.DeleteFiles(context.Background(), []int64{2})) {
+		files, err = store.GetPurgedFiles(context.Background(), 1, []int64{2})
+		if assert.NoError(t, err) {
+			assert.Empty(t, files)
+		}
+	}
+}
+
+func TestMarkTrashForPurgeInDeletedFolder(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+	store := db.Queries(2)
+
+	for tName, tData := range map[string]struct {
+		rootId      int64
+		expectedIds []int64
+	}{
+		// root-dir-1/one-dir-deleting-1, whose contents are still UPLOADED or READY
+		"deleted folder": {9, []int64{16, 90, 91}},
+		// root-dir-1/one-dir-deleting-1/sub-dir, a READY folder below a deleted one
+		"folder below deleted folder": {90, []int64{91}},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			db.ExecSQLFile("show-deleting-test.sql")
+			defer db.Truncate(2, "packages")
+			defer db.TruncatePurgedPackages()
+			_, err := db.Exec(`INSERT INTO "2".packages (id, name, type, state, dataset_id, parent_id, updated_at, created_at, attributes, node_id, size, owner_id, import_id) VALUES
+                               (90, 'sub-dir', 'Collection', 'READY', 1, 9, '2023-02-02 19:45:07', '2023-02-02 19:44:03', '[]', 'N:collection:90', null, 1, 'a3f1c1e4-5b0e-4d8e-9d43-8f7f5c1d9e90'),
+                               (91, 'sub-file.csv', 'CSV', 'UPLOADED', 1, 90, '2023-02-02 19:45:07', '2023-02-02 19:44:03', '[]', 'N:package:91', null, 1, 'b6c2d7a8-1e3f-4a5b-8c9d-0e1f2a3b4c91')`)
+			if !assert.NoError(t, err) {
+				return
+			}
+			rootId := sql.NullInt64{Int64: tData.rootId, Valid: true}
+			summary, err := store.GetTrashcanSummary(context.Background(), 1, rootId)
+			if assert.NoError(t, err) {
+				assert.Equal(t, len(tData.expectedIds), summary.PackageCount)
+			}
+			ids, err := store.MarkTrashForPurge(context.Background(), 1, rootId)
+			if assert.NoError(t, err) {
+				assert.ElementsMatch(t, tData.expectedIds, ids)
+			}
+		})
+	}
+}
+
+func TestGetTrashcanSummary(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("folder-nav-test.sql")
+	defer db.Truncate(2, "packages")
+	store := db.Queries(2)
+
+	// root-dir-1/one-dir-1/two-dir-1, the same packages TestMarkTrashForPurge marks
+	summary, err := store.GetTrashcanSummary(context.Background(), 1, sql.NullInt64{Int64: 21, Valid: true})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 6, summary.PackageCount)
+		assert.Equal(t, 2, summary.FolderCount)
+		assert.Zero(t, summary.FileCount)
+		assert.Zero(t, summary.TotalSize)
+		if assert.NotNil(t, summary.OldestDeletedAt) && assert.NotNil(t, summary.NewestDeletedAt) {
+			assert.WithinDuration(t, time.Date(2023, 2, 2, 23, 2, 7, 594921000, time.UTC), *summary.OldestDeletedAt, 0)
+			assert.WithinDuration(t, time.Date(2023, 2, 13, 18, 48, 32, 49565000, time.UTC), *summary.NewestDeletedAt, 0)
+		}
+	}
+
+	// root-dir-empty-1
+	summary, err = store.GetTrashcanSummary(context.Background(), 1, sql.NullInt64{Int64: 3, Valid: true})
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.TrashcanSummary{}, *summary)
+	}
+}
+
+func TestGetTrashcanSummaryFiles(t *testing.T) {
+	db := OpenDB(t)
+	defer db.Close()
+
+	db.ExecSQLFile("manifest-test.sql")
+	defer func() {
+		db.Truncate(2, "packages")
+		db.Truncate(2, "files")
+	}()
+	store := db.Queries(2)
+
+	summary, err := store.GetTrashcanSummary(context.Background(), 1, sql.NullInt64{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, summary.PackageCount)
+		assert.Zero(t, summary.FolderCount)
+		assert.Equal(t, 2, summary.FileCount)
+		assert.Equal(t, int64(20), summary.TotalSize)
+	}
+}
+
 func testGetManifest(t *testing.T) {
 	db := OpenDB(t)
 	defer db.Close()
@@ -376,7 +1101,7 @@ type PackageSummary struct {
 // ExpectedLevel maps a collection package id to a summary of its trashcan results
 type TrashcanLevel map[string]PackageSummary
 
-func summarize(packages []pgdb.Package) TrashcanLevel {
+func summarize(packages []TrashcanPackage) TrashcanLevel {
 	summary := make(map[string]PackageSummary, len(packages))
 	for _, p := range packages {
 		summary[p.NodeId] = PackageSummary{
@@ -5,14 +5,18 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/pennsieve/datasets-service/api/models"
 	log "github.com/sirupsen/logrus"
+	"io"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -35,8 +39,16 @@ type s3StoreFactory struct {
 }
 
 type S3Store interface {
-	WriteManifestToS3(ctx context.Context, datasetNodeId string, s3Key string, manifest models.WorkspaceManifest) (*models.WriteManifestOutput, error)
+	WriteManifestToS3(ctx context.Context, s3Key string, format models.ManifestFormat, manifest io.Reader) (*models.WriteManifestOutput, error)
+	OpenManifest(ctx context.Context, s3Key string) (*models.ManifestReader, error)
 	GetPresignedUrl(ctx context.Context, bucket, key string) (*url.URL, error)
+	NewFilePresigner(ctx context.Context, expiry time.Duration) (FilePresigner, error)
+	ObjectExists(ctx context.Context, key string) (bool, error)
+	WriteObject(ctx context.Context, key string, contentType string, content []byte) error
+	DeleteObjects(ctx context.Context, keys []string) error
+	WriteManifestJob(ctx context.Context, job models.ManifestJob) error
+	GetManifestJob(ctx context.Context, jobId string) (*models.ManifestJob, error)
+	GetManifestJobByS3Key(ctx context.Context, s3Key string) (*models.ManifestJob, error)
 }
 
 type s3Store struct {
@@ -44,36 +56,180 @@ type s3Store struct {
 	S3Bucket string
 }
 
-func (d *s3Store) WriteManifestToS3(ctx context.Context, datasetNodeId string, s3Key string, manifest models.WorkspaceManifest) (*models.WriteManifestOutput, error) {
-
-	//// Method to create relatively short UUID JSON file name
-	//randName, _ := randomFilename16Char()
-	//manifestFileName := fmt.Sprintf("%s/%s.json",
-	//	strings.Replace(datasetNodeId, "N:dataset:", "", -1), randName)
+// WriteManifestToS3 uploads the encoded manifest read from manifest. The manifest is uploaded in parts as it is
+// read, so it never has to be held in memory as a whole.
+func (d *s3Store) WriteManifestToS3(ctx context.Context, s3Key string, format models.ManifestFormat, manifest io.Reader) (*models.WriteManifestOutput, error) {
 
 	uploader := manager.NewUploader(d.S3Client, func(u *manager.Uploader) {
 		// Define a strategy that will buffer 25 MiB in memory
 		u.BufferProvider = manager.NewBufferedReadSeekerWriteToPool(25 * 1024 * 1024)
 	})
 
-	serializedManifests, err := json.MarshalIndent(manifest, "", "    ")
+	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(d.S3Bucket),
+		Key:         aws.String(s3Key),
+		Body:        manifest,
+		ContentType: aws.String(format.ContentType()),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = uploader.Upload(ctx, &s3.PutObjectInput{
+	return &models.WriteManifestOutput{
+		S3Bucket: d.S3Bucket,
+		S3Key:    s3Key,
+	}, nil
+}
+
+// OpenManifest opens a manifest written in the JSON format for reading one file at a time.
+func (d *s3Store) OpenManifest(ctx context.Context, s3Key string) (*models.ManifestReader, error) {
+	output, err := d.S3Client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(d.S3Bucket),
 		Key:    aws.String(s3Key),
-		Body:   bytes.NewReader(serializedManifests),
 	})
 	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, models.ManifestNotFoundError{S3Key: s3Key}
+		}
+		return nil, err
+	}
+	reader, err := models.NewManifestReader(output.Body)
+	if err != nil {
+		output.Body.Close()
 		return nil, err
 	}
+	return reader, nil
+}
 
-	return &models.WriteManifestOutput{
-		S3Bucket: d.S3Bucket,
-		S3Key:    s3Key,
-	}, nil
+func (d *s3Store) WriteObject(ctx context.Context, key string, contentType string, content []byte) error {
+	_, err := d.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(d.S3Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+// deleteObjectsBatchSize is the most keys S3 deletes in one request.
+const deleteObjectsBatchSize = 1000
+
+// DeleteObjects removes the objects with the given keys from the store's bucket. Keys without an object are ignored.
+func (d *s3Store) DeleteObjects(ctx context.Context, keys []string) error {
+	for start := 0; start < len(keys); start += deleteObjectsBatchSize {
+		end := min(start+deleteObjectsBatchSize, len(keys))
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+		output, err := d.S3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(d.S3Bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return err
+		}
+		if len(output.Errors) > 0 {
+			e := output.Errors[0]
+			return fmt.Errorf("unable to delete %d objects from %s, first %s: %s", len(output.Errors), d.S3Bucket, aws.ToString(e.Key), aws.ToString(e.Message))
+		}
+	}
+	return nil
+}
+
+// ObjectExists returns true if there is an object with the given key in the store's bucket.
+func (d *s3Store) ObjectExists(ctx context.Context, key string) (bool, error) {
+	_, err := d.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(d.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// manifestJobKey is where the status of the manifest job with the given id is kept.
+func manifestJobKey(jobId string) string {
+	return fmt.Sprintf("jobs/%s.json", jobId)
+}
+
+// manifestJobMarkerKey is where the id of the latest job started for the manifest at s3Key is kept.
+func manifestJobMarkerKey(s3Key string) string {
+	return fmt.Sprintf("jobs/by-manifest/%s", s3Key)
+}
+
+// WriteManifestJob saves job. Saving a CREATING job also makes it the job GetManifestJobByS3Key returns for the
+// manifest it creates.
+func (d *s3Store) WriteManifestJob(ctx context.Context, job models.ManifestJob) error {
+	serializedJob, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = d.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(d.S3Bucket),
+		Key:         aws.String(manifestJobKey(job.JobId)),
+		Body:        bytes.NewReader(serializedJob),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil || job.Status != models.CREATING {
+		return err
+	}
+	_, err = d.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(d.S3Bucket),
+		Key:         aws.String(manifestJobMarkerKey(job.S3Key)),
+		Body:        strings.NewReader(job.JobId),
+		ContentType: aws.String("text/plain"),
+	})
+	return err
+}
+
+// GetManifestJob returns models.ManifestJobNotFoundError if there is no job with the given id.
+func (d *s3Store) GetManifestJob(ctx context.Context, jobId string) (*models.ManifestJob, error) {
+	output, err := d.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(d.S3Bucket),
+		Key:    aws.String(manifestJobKey(jobId)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, models.ManifestJobNotFoundError{JobId: jobId}
+		}
+		return nil, err
+	}
+	defer output.Body.Close()
+	var job models.ManifestJob
+	if err := json.NewDecoder(output.Body).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetManifestJobByS3Key returns the latest job started for the manifest at s3Key, whatever its status, or
+// models.ManifestJobNotFoundError if none was.
+func (d *s3Store) GetManifestJobByS3Key(ctx context.Context, s3Key string) (*models.ManifestJob, error) {
+	output, err := d.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(d.S3Bucket),
+		Key:    aws.String(manifestJobMarkerKey(s3Key)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, models.ManifestJobNotFoundError{}
+		}
+		return nil, err
+	}
+	defer output.Body.Close()
+	jobId, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, err
+	}
+	return d.GetManifestJob(ctx, string(jobId))
 }
 
 func randomFilename16Char() (s string, err error) {
@@ -102,6 +258,62 @@ func (d *s3Store) GetPresignedUrl(ctx context.Context, bucket, key string) (*url
 	return u, nil
 }
 
+// FilePresigner presigns GET URLs for objects in any bucket, such as dataset files in their storage bucket. One is
+// made for all the URLs of a request. Signing happens locally, without a request to S3.
+type FilePresigner interface {
+	// ExpiresAt is when the URLs stop working
+	ExpiresAt() time.Time
+	PresignUrl(bucket, key string) (string, error)
+}
+
+// sessionCredentialsLifetime is how long temporary credentials that do not say when they expire are assumed to
+// last. Credentials a Lambda function reads from its environment are such, and are refreshed by Lambda on its own
+// schedule, so they are only relied on for the default lifetime of an STS session.
+const sessionCredentialsLifetime = time.Hour
+
+// NewFilePresigner returns a FilePresigner for URLs valid for expiry, unless the credentials that sign them expire
+// first. Then the URLs are only valid until the credentials expire.
+func (d *s3Store) NewFilePresigner(ctx context.Context, expiry time.Duration) (FilePresigner, error) {
+	now := time.Now()
+	expiresAt := now.Add(expiry)
+	if provider := d.S3Client.Options().Credentials; provider != nil {
+		credentials, err := provider.Retrieve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		credentialsExpireAt := credentials.Expires
+		if !credentials.CanExpire && credentials.SessionToken != "" {
+			credentialsExpireAt = now.Add(sessionCredentialsLifetime)
+		}
+		if !credentialsExpireAt.IsZero() && credentialsExpireAt.Before(expiresAt) {
+			expiresAt = credentialsExpireAt
+		}
+	}
+	lifetimeSecs := int64(expiresAt.Sub(now) / time.Second)
+	if lifetimeSecs < 1 {
+		lifetimeSecs = 1
+	}
+	return &filePresigner{Presigner{s3.NewPresignClient(d.S3Client)}, lifetimeSecs, expiresAt}, nil
+}
+
+type filePresigner struct {
+	presigner    Presigner
+	lifetimeSecs int64
+	expiresAt    time.Time
+}
+
+func (f *filePresigner) ExpiresAt() time.Time {
+	return f.expiresAt
+}
+
+func (f *filePresigner) PresignUrl(bucket, key string) (string, error) {
+	res, err := f.presigner.GetObject(bucket, key, f.lifetimeSecs)
+	if err != nil {
+		return "", err
+	}
+	return res.URL, nil
+}
+
 type Presigner struct {
 	PresignClient *s3.PresignClient
 }
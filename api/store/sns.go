@@ -29,6 +29,7 @@ type snsStoreFactory struct {
 
 type SnsStore interface {
 	TriggerWorkerLambda(ctx context.Context, input models.ManifestWorkerInput) error
+	TriggerPurgeWorker(ctx context.Context, input models.PurgeWorkerInput) error
 }
 
 type snsStore struct {
@@ -37,15 +38,27 @@ type snsStore struct {
 }
 
 func (s *snsStore) TriggerWorkerLambda(ctx context.Context, input models.ManifestWorkerInput) error {
+	return s.publish(ctx, input)
+}
+
+func (s *snsStore) TriggerPurgeWorker(ctx context.Context, input models.PurgeWorkerInput) error {
+	return s.publish(ctx, input)
+}
+
+// publish sends the JSON encoding of message to the store's topic.
+func (s *snsStore) publish(ctx context.Context, message any) error {
 
-	jsonInput, err := json.Marshal(input)
+	jsonInput, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
 
 	params := sns.PublishInput{
 		Message:  aws.String(string(jsonInput)),
 		TopicArn: aws.String(s.SnsTopic),
 	}
 
-	_, err = s.SnsClient.Publish(context.Background(), &params)
+	_, err = s.SnsClient.Publish(ctx, &params)
 	if err != nil {
 		log.Error("Error publishing to SNS: ", err)
 		return err
@@ -1,8 +1,10 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"github.com/pennsieve/datasets-service/api/models"
 	"github.com/stretchr/testify/assert"
 	"math/rand"
 	"os"
@@ -51,6 +53,9 @@ func OpenDB(t *testing.T) TestDB {
 	if err = testDB.PingUntilReady(); err != nil {
 		assert.FailNow(testDB.t, "cannot ping database", "config: %s, err: %v", pgConfig, err)
 	}
+	if err = Migrate(context.Background(), db); err != nil {
+		assert.FailNow(testDB.t, "cannot migrate database", "config: %s, err: %v", pgConfig, err)
+	}
 	return testDB
 }
 
@@ -83,6 +88,25 @@ func (tdb *TestDB) TruncatePennsieve(table string) {
 	}
 }
 
+// TruncatePurgedPackages removes the purges recorded by MarkTrashForPurge, which truncating packages leaves behind.
+func (tdb *TestDB) TruncatePurgedPackages() {
+	_, err := tdb.Exec(`TRUNCATE TABLE ` + purgedPackagesTable)
+	if err != nil {
+		assert.FailNowf(tdb.t, "error truncating purged packages", "error: %v", err)
+	}
+}
+
+// PrefixDeletedNames renames the DELETED and DELETING packages of the org the way deleting them does, so that tests
+// can use fixtures written with the names packages had before deletion.
+func (tdb *TestDB) PrefixDeletedNames(orgID int) {
+	query := fmt.Sprintf(`UPDATE "%d".packages SET name = '%s' || node_id || '_' || name WHERE state IN ('DELETED', 'DELETING')`,
+		orgID, models.DeletedNamePrefix)
+	_, err := tdb.Exec(query)
+	if err != nil {
+		assert.FailNowf(tdb.t, "error prefixing deleted package names", "orgID: %d, error: %v", orgID, err)
+	}
+}
+
 func (tdb *TestDB) Close() {
 	if err := tdb.DB.Close(); err != nil {
 		assert.FailNowf(tdb.t, "error closing database", "error: %v", err)
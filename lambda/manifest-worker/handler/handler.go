@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	log "github.com/sirupsen/logrus"
+	"os"
+)
+
+// MessageHandler processes the message of a single SNS record. Implemented by service.ManifestWorker.
+type MessageHandler interface {
+	HandleMessage(ctx context.Context, message string) error
+}
+
+var Worker MessageHandler
+
+func init() {
+	log.SetFormatter(&log.JSONFormatter{})
+	if level, ok := os.LookupEnv("LOG_LEVEL"); !ok {
+		log.SetLevel(log.InfoLevel)
+	} else {
+		if ll, err := log.ParseLevel(level); err == nil {
+			log.SetLevel(ll)
+		} else {
+			log.SetLevel(log.InfoLevel)
+			log.Warnf("could not set log level to %q: %v", level, err)
+		}
+
+	}
+}
+
+// ManifestWorkerHandler passes each record of the event to Worker. Returning an error fails the invocation so
+// that Lambda sends the event to the dead letter queue.
+func ManifestWorkerHandler(ctx context.Context, event events.SNSEvent) error {
+	var errs []error
+	for _, record := range event.Records {
+		logger := log.WithFields(log.Fields{"messageId": record.SNS.MessageID})
+		logger.Info("processing manifest message")
+		if err := Worker.HandleMessage(ctx, record.SNS.Message); err != nil {
+			logger.Errorf("manifest message failed: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
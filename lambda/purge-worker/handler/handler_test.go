@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type MockWorker struct {
+	Messages []string
+	Errors   map[string]error
+}
+
+func (m *MockWorker) HandleMessage(_ context.Context, message string) error {
+	m.Messages = append(m.Messages, message)
+	return m.Errors[message]
+}
+
+func snsEvent(messages ...string) events.SNSEvent {
+	var event events.SNSEvent
+	for _, message := range messages {
+		event.Records = append(event.Records, events.SNSEventRecord{SNS: events.SNSEntity{Message: message}})
+	}
+	return event
+}
+
+func TestPurgeWorkerHandler(t *testing.T) {
+	mockWorker := MockWorker{}
+	Worker = &mockWorker
+
+	err := PurgeWorkerHandler(context.Background(), snsEvent(`{"package_ids": [1]}`, `{"package_ids": [2]}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{`{"package_ids": [1]}`, `{"package_ids": [2]}`}, mockWorker.Messages)
+	}
+}
+
+func TestPurgeWorkerHandlerError(t *testing.T) {
+	workerErr := errors.New("unexpected worker error")
+	mockWorker := MockWorker{Errors: map[string]error{`{"package_ids": [1]}`: workerErr}}
+	Worker = &mockWorker
+
+	err := PurgeWorkerHandler(context.Background(), snsEvent(`{"package_ids": [1]}`, `{"package_ids": [2]}`))
+	assert.ErrorIs(t, err, workerErr)
+	// a failed record does not stop the others
+	assert.Len(t, mockWorker.Messages, 2)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pennsieve/datasets-service/api/service"
+	"github.com/pennsieve/datasets-service/api/store"
+	"github.com/pennsieve/datasets-service/purge-worker/handler"
+	"github.com/pennsieve/pennsieve-go-core/pkg/queries/pgdb"
+	"github.com/sirupsen/logrus"
+	"log"
+)
+
+func init() {
+	db, err := pgdb.ConnectRDS()
+	if err != nil {
+		panic(fmt.Sprintf("unable to connect to RDS database: %s", err))
+	}
+	logrus.Info("connected to RDS database")
+	if err := store.Migrate(context.Background(), db); err != nil {
+		panic(fmt.Sprintf("unable to migrate RDS database: %s", err))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		log.Fatalf("LoadDefaultConfig: %v\n", err)
+	}
+
+	handler.Worker = service.NewPurgeWorker(db, s3.NewFromConfig(cfg))
+
+}
+
+func main() {
+	lambda.Start(handler.PurgeWorkerHandler)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type lambdaHandler func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error)
+
+// lambdaAdapter serves HTTP requests with a Lambda handler, translating them the way API Gateway does with
+// payload format version 2.0.
+type lambdaAdapter struct {
+	handler lambdaHandler
+	claims  claimsReader
+	// basePath is stripped from request paths, like the API Gateway base path mapping of the custom domain
+	basePath string
+}
+
+func (a lambdaAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	claims, err := a.claims.read(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": err.Error()})
+		return
+	}
+	request, err := a.toLambdaRequest(r, claims)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	response, err := a.handler(r.Context(), request)
+	if err != nil || response == nil {
+		// what API Gateway responds if the Lambda fails
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "Internal Server Error"})
+		return
+	}
+	writeLambdaResponse(w, response)
+}
+
+func (a lambdaAdapter) toLambdaRequest(r *http.Request, claims map[string]any) (events.APIGatewayV2HTTPRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayV2HTTPRequest{}, err
+	}
+	path := r.URL.Path
+	if trimmed, ok := strings.CutPrefix(path, a.basePath); ok && len(a.basePath) > 0 && (len(trimmed) == 0 || trimmed[0] == '/') {
+		path = trimmed
+	}
+	headers := map[string]string{}
+	for name, values := range r.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	var cookies []string
+	for _, cookie := range r.Cookies() {
+		cookies = append(cookies, cookie.String())
+	}
+	var queryParams map[string]string
+	if query := r.URL.Query(); len(query) > 0 {
+		queryParams = map[string]string{}
+		for name, values := range query {
+			queryParams[name] = strings.Join(values, ",")
+		}
+	}
+	now := time.Now()
+	request := events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              "$default",
+		RawPath:               path,
+		RawQueryString:        r.URL.RawQuery,
+		Cookies:               cookies,
+		Headers:               headers,
+		QueryStringParameters: queryParams,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:   "$default",
+			Stage:      "$default",
+			RequestID:  uuid.NewString(),
+			DomainName: r.Host,
+			Time:       now.Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:  now.UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      path,
+				Protocol:  r.Proto,
+				SourceIP:  r.RemoteAddr,
+				UserAgent: r.UserAgent(),
+			},
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				Lambda: claims,
+			},
+		},
+	}
+	if utf8.Valid(body) {
+		request.Body = string(body)
+	} else {
+		request.Body = base64.StdEncoding.EncodeToString(body)
+		request.IsBase64Encoded = true
+	}
+	return request, nil
+}
+
+func writeLambdaResponse(w http.ResponseWriter, response *events.APIGatewayV2HTTPResponse) {
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"message": "Lambda response body is not valid base64"})
+			return
+		}
+		body = decoded
+	}
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range response.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	for _, cookie := range response.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+	// API Gateway defaults the content type of payload format 2.0 responses to JSON
+	if len(w.Header().Get("Content-Type")) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(response.StatusCode)
+	_, _ = w.Write(body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
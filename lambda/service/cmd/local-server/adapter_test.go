@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/role"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testClaims = `{"org_claim": {"Role": 16, "IntId": 7, "NodeId": "N:organization:7"}, "dataset_claim": {"Role": 4, "IntId": 3, "NodeId": "N:dataset:3"}}`
+
+// recordingHandler returns a lambdaHandler that stores the request it receives and responds with response.
+func recordingHandler(received *events.APIGatewayV2HTTPRequest, response *events.APIGatewayV2HTTPResponse, err error) lambdaHandler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error) {
+		*received = request
+		return response, err
+	}
+}
+
+func TestLambdaAdapterRequest(t *testing.T) {
+	var received events.APIGatewayV2HTTPRequest
+	adapter := lambdaAdapter{
+		handler:  recordingHandler(&received, &events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK}, nil),
+		claims:   claimsReader{allowDevHeader: true},
+		basePath: "/datasets",
+	}
+	req := httptest.NewRequest("POST", "/datasets/trashcan/restore?dataset_id=N:dataset:3&tag=a&tag=b", strings.NewReader(`{"nodeIds": ["N:package:1"]}`))
+	req.Header.Set(DevClaimsHeader, testClaims)
+	adapter.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "POST", received.RequestContext.HTTP.Method)
+	assert.Equal(t, "/trashcan/restore", received.RequestContext.HTTP.Path)
+	assert.Equal(t, "/trashcan/restore", received.RawPath)
+	assert.Equal(t, map[string]string{"dataset_id": "N:dataset:3", "tag": "a,b"}, received.QueryStringParameters)
+	assert.Equal(t, `{"nodeIds": ["N:package:1"]}`, received.Body)
+	assert.False(t, received.IsBase64Encoded)
+	assert.NotEmpty(t, received.RequestContext.RequestID)
+	assert.Equal(t, testClaims, received.Headers[strings.ToLower(DevClaimsHeader)])
+
+	claims := authorizer.ParseClaims(received.RequestContext.Authorizer.Lambda)
+	if assert.NotNil(t, claims.OrgClaim) && assert.NotNil(t, claims.DatasetClaim) {
+		assert.Equal(t, int64(7), claims.OrgClaim.IntId)
+		assert.Equal(t, "N:dataset:3", claims.DatasetClaim.NodeId)
+		assert.Equal(t, role.Manager, claims.DatasetClaim.Role)
+	}
+}
+
+func TestLambdaAdapterPathWithoutBasePath(t *testing.T) {
+	var received events.APIGatewayV2HTTPRequest
+	adapter := lambdaAdapter{
+		handler:  recordingHandler(&received, &events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK}, nil),
+		basePath: "/datasets",
+	}
+	for requestPath, expectedPath := range map[string]string{
+		"/manifest":             "/manifest",
+		"/datasets/manifest":    "/manifest",
+		"/datasets-other/thing": "/datasets-other/thing",
+	} {
+		adapter.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", requestPath, nil))
+		assert.Equal(t, expectedPath, received.RequestContext.HTTP.Path)
+		assert.Nil(t, received.QueryStringParameters)
+	}
+}
+
+func TestLambdaAdapterResponse(t *testing.T) {
+	var received events.APIGatewayV2HTTPRequest
+	adapter := lambdaAdapter{handler: recordingHandler(&received, &events.APIGatewayV2HTTPResponse{
+		StatusCode: http.StatusAccepted,
+		Headers:    map[string]string{"ETag": `"abc"`},
+		Body:       `{"status": "CREATING"}`,
+	}, nil)}
+	recorder := httptest.NewRecorder()
+	adapter.ServeHTTP(recorder, httptest.NewRequest("GET", "/manifest", nil))
+
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, `"abc"`, recorder.Header().Get("ETag"))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `{"status": "CREATING"}`, recorder.Body.String())
+}
+
+func TestLambdaAdapterBase64Response(t *testing.T) {
+	var received events.APIGatewayV2HTTPRequest
+	adapter := lambdaAdapter{handler: recordingHandler(&received, &events.APIGatewayV2HTTPResponse{
+		StatusCode:      http.StatusOK,
+		Headers:         map[string]string{"Content-Type": "text/csv"},
+		Body:            "YSxiCg==",
+		IsBase64Encoded: true,
+	}, nil)}
+	recorder := httptest.NewRecorder()
+	adapter.ServeHTTP(recorder, httptest.NewRequest("GET", "/manifest", nil))
+
+	assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "a,b\n", recorder.Body.String())
+}
+
+func TestLambdaAdapterHandlerError(t *testing.T) {
+	var received events.APIGatewayV2HTTPRequest
+	adapter := lambdaAdapter{handler: recordingHandler(&received, nil, errors.New("boom"))}
+	recorder := httptest.NewRecorder()
+	adapter.ServeHTTP(recorder, httptest.NewRequest("GET", "/manifest", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.JSONEq(t, `{"message": "Internal Server Error"}`, recorder.Body.String())
+}
+
+func TestLambdaAdapterRejectedClaims(t *testing.T) {
+	secret := []byte("local-secret")
+	validToken, err := signLocalToken([]byte(testClaims), secret)
+	require.NoError(t, err)
+	otherToken, err := signLocalToken([]byte(testClaims), []byte("other-secret"))
+	require.NoError(t, err)
+
+	for tName, tData := range map[string]struct {
+		Reader  claimsReader
+		Headers map[string]string
+	}{
+		"dev header disabled":    {claimsReader{secret: secret}, map[string]string{DevClaimsHeader: testClaims}},
+		"dev header not JSON":    {claimsReader{allowDevHeader: true}, map[string]string{DevClaimsHeader: "role=manager"}},
+		"dev header wrong types": {claimsReader{allowDevHeader: true}, map[string]string{DevClaimsHeader: `{"org_claim": {"Role": "manager"}}`}},
+		"token without secret":   {claimsReader{allowDevHeader: true}, map[string]string{"Authorization": "Bearer " + validToken}},
+		"token wrong secret":     {claimsReader{secret: secret}, map[string]string{"Authorization": "Bearer " + otherToken}},
+		"token malformed":        {claimsReader{secret: secret}, map[string]string{"Authorization": "Bearer abc"}},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			called := false
+			adapter := lambdaAdapter{
+				handler: func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error) {
+					called = true
+					return &events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK}, nil
+				},
+				claims: tData.Reader,
+			}
+			req := httptest.NewRequest("GET", "/manifest", nil)
+			for name, value := range tData.Headers {
+				req.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			adapter.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+			assert.False(t, called)
+			body, _ := io.ReadAll(recorder.Body)
+			assert.Contains(t, string(body), "message")
+		})
+	}
+}
+
+func TestLambdaAdapterLocalToken(t *testing.T) {
+	secret := []byte("local-secret")
+	token, err := signLocalToken([]byte(testClaims), secret)
+	require.NoError(t, err)
+
+	var received events.APIGatewayV2HTTPRequest
+	adapter := lambdaAdapter{
+		handler: recordingHandler(&received, &events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK}, nil),
+		claims:  claimsReader{secret: secret},
+	}
+	req := httptest.NewRequest("GET", "/trashcan?dataset_id=N:dataset:3", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	adapter.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	claims := authorizer.ParseClaims(received.RequestContext.Authorizer.Lambda)
+	if assert.NotNil(t, claims.OrgClaim) {
+		assert.Equal(t, "N:organization:7", claims.OrgClaim.NodeId)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
+	"net/http"
+	"strings"
+)
+
+// DevClaimsHeader carries the claims of a request as JSON, in the format the authorizer Lambda passes to
+// API Gateway, for example {"org_claim": {"Role": 16, "IntId": 1, "NodeId": "N:organization:1"}}.
+const DevClaimsHeader = "X-Dev-Claims"
+
+// claimsReader reads the claims of a request from the DevClaimsHeader or from a local token in the
+// Authorization header. A local token is only accepted if a secret is configured, and the header only if
+// allowDevHeader is true.
+type claimsReader struct {
+	secret         []byte
+	allowDevHeader bool
+}
+
+// read returns the claims in the authorizer's format, or an empty map if the request carries none so that
+// the service responds as it would to a request the authorizer let through without claims.
+func (c claimsReader) read(r *http.Request) (map[string]any, error) {
+	var payload []byte
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if len(c.secret) == 0 {
+			return nil, errors.New("local tokens are not accepted: LOCAL_TOKEN_SECRET is not set")
+		}
+		var err error
+		if payload, err = verifyLocalToken(token, c.secret); err != nil {
+			return nil, err
+		}
+	} else if header := r.Header.Get(DevClaimsHeader); len(header) > 0 {
+		if !c.allowDevHeader {
+			return nil, fmt.Errorf("the %s header is disabled", DevClaimsHeader)
+		}
+		payload = []byte(header)
+	} else {
+		return map[string]any{}, nil
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("claims are not a JSON object: %w", err)
+	}
+	if err := validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validateClaims checks that authorizer.ParseClaims can parse claims. It panics if a claim has missing or
+// mistyped fields, which would otherwise crash the request.
+func validateClaims(claims map[string]any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid claims: %v", r)
+		}
+	}()
+	authorizer.ParseClaims(claims)
+	return nil
+}
+
+// signLocalToken returns a token for the JSON claims that verifyLocalToken accepts: the base64url encoded
+// claims and their HMAC-SHA256, separated by a dot.
+func signLocalToken(claimsJSON []byte, secret []byte) (string, error) {
+	var claims map[string]any
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return "", fmt.Errorf("claims are not a JSON object: %w", err)
+	}
+	if err := validateClaims(claims); err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(claimsJSON)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(localTokenMac(payload, secret)), nil
+}
+
+func verifyLocalToken(token string, secret []byte) ([]byte, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errors.New("malformed local token")
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, localTokenMac(payload, secret)) {
+		return nil, errors.New("invalid local token signature")
+	}
+	return base64.RawURLEncoding.DecodeString(payload)
+}
+
+func localTokenMac(payload string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,124 @@
+// Command local-server runs the datasets service as a plain HTTP server, for trying it out without API Gateway.
+// It connects to the Postgres and MinIO containers of docker-compose.test.yml (see `make local-server`).
+//
+// Requests carry their claims in a local token signed with LOCAL_TOKEN_SECRET sent as "Authorization: Bearer <token>",
+// or, with -dev-header, in the X-Dev-Claims header. The server listens on 127.0.0.1 by default. Tokens are printed by
+//
+//	local-server -sign-token '{"org_claim": {"Role": 16, "IntId": 1, "NodeId": "N:organization:1"}}'
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/datasets-service/api/store"
+	"github.com/pennsieve/datasets-service/service/handler"
+	"github.com/pennsieve/pennsieve-go-core/pkg/queries/pgdb"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+)
+
+func main() {
+	addr := flag.String("addr", getEnv("LOCAL_SERVER_ADDR", "127.0.0.1:8080"), "address to listen on")
+	basePath := flag.String("base-path", "/datasets", "path prefix to strip from requests, like the API Gateway base path mapping")
+	allowDevHeader := flag.Bool("dev-header", false, "accept claims in the "+DevClaimsHeader+" header")
+	signToken := flag.String("sign-token", "", "print a local token for these JSON claims and exit")
+	flag.Parse()
+
+	secret := []byte(os.Getenv("LOCAL_TOKEN_SECRET"))
+	if len(*signToken) > 0 {
+		if len(secret) == 0 {
+			log.Fatal("LOCAL_TOKEN_SECRET must be set to sign tokens")
+		}
+		token, err := signLocalToken([]byte(*signToken), secret)
+		if err != nil {
+			log.Fatalf("unable to sign token: %v", err)
+		}
+		fmt.Println(token)
+		return
+	}
+
+	db, err := pgdb.ConnectENV()
+	if err != nil {
+		log.Fatalf("unable to connect to Postgres: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		log.Fatalf("unable to connect to Postgres, is it running (make local-services)? %v", err)
+	}
+	if err := store.Migrate(context.Background(), db); err != nil {
+		log.Fatalf("unable to migrate Postgres: %v", err)
+	}
+	handler.PennsieveDB = db
+
+	handler.HandlerVars = &models.HandlerVars{
+		S3Bucket:      getEnv("MANIFEST_FILES_BUCKET", "manifest-files-bucket"),
+		SnsTopic:      getEnv("CREATE_MANIFEST_SNS_TOPIC", "create-manifest-topic"),
+		PurgeSnsTopic: getEnv("PURGE_TRASHCAN_SNS_TOPIC", "purge-trashcan-topic"),
+	}
+	handler.S3Client = newMinIOClient(getEnv("MINIO_URL", "http://localhost:9008"))
+	if err := createBucket(context.Background(), handler.S3Client, handler.HandlerVars.S3Bucket); err != nil {
+		log.Fatalf("unable to create bucket %s in MinIO: %v", handler.HandlerVars.S3Bucket, err)
+	}
+	handler.SNSClient = newSNSClient(os.Getenv("SNS_URL"))
+
+	server := lambdaAdapter{
+		handler:  handler.DatasetsServiceHandler,
+		claims:   claimsReader{secret: secret, allowDevHeader: *allowDevHeader},
+		basePath: *basePath,
+	}
+	log.Infof("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
+
+// staticCredentials are the MinIO root user of docker-compose.test.yml. They are also used for SNS, which needs
+// credentials even when it is emulated.
+var staticCredentials = aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+	return aws.Credentials{
+		AccessKeyID:     getEnv("MINIO_ROOT_USER", "minioadmin"),
+		SecretAccessKey: getEnv("MINIO_ROOT_PASSWORD", "minioadmin"),
+	}, nil
+})
+
+func newMinIOClient(endpoint string) *s3.Client {
+	return s3.New(s3.Options{
+		BaseEndpoint: aws.String(endpoint),
+		UsePathStyle: true,
+		Region:       "us-east-1",
+		Credentials:  staticCredentials,
+	})
+}
+
+func createBucket(ctx context.Context, client *s3.Client, bucket string) error {
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err == nil {
+		return nil
+	}
+	_, err := client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(bucket)})
+	return err
+}
+
+// newSNSClient returns a client for the SNS emulator at endpoint, LocalStack for example. Without one, requests
+// that publish events, like starting a manifest job, fail.
+func newSNSClient(endpoint string) *sns.Client {
+	options := sns.Options{
+		Region:      "us-east-1",
+		Credentials: staticCredentials,
+	}
+	if len(endpoint) > 0 {
+		options.BaseEndpoint = aws.String(endpoint)
+	} else {
+		log.Warn("SNS_URL is not set: requests that publish to SNS will fail")
+	}
+	return sns.New(options)
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && len(value) > 0 {
+		return value
+	}
+	return fallback
+}
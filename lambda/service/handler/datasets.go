@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pennsieve/datasets-service/api/models"
+	"net/http"
+	"strings"
+)
+
+func (h *RequestHandler) getDataset(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	ds, err := h.datasetsService.GetDataset(ctx, h.pathParams["id"])
+	if err != nil {
+		return h.handleError(err)
+	}
+	etag := models.DatasetETag(ds)
+	headers := map[string]string{
+		"ETag": etag,
+		// clients may cache the dataset, but must check that it is still current before using it
+		"Cache-Control": "private, no-cache",
+	}
+	if etagMatches(h.request.Headers["if-none-match"], etag) {
+		h.logger.Info("Not Modified")
+		response := buildResponseFromString("", http.StatusNotModified)
+		response.Headers = headers
+		return response, nil
+	}
+	h.logger.Info("OK")
+	response, err := h.buildResponse(models.NewDatasetDTO(ds), http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	headers["Content-Type"] = "application/json"
+	response.Headers = headers
+	return response, nil
+}
+
+func (h *RequestHandler) getDatasetStats(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	stats, err := h.datasetsService.GetDatasetStats(ctx, h.pathParams["id"])
+	if err != nil {
+		return h.handleError(err)
+	}
+	h.logger.WithField("packageCount", stats.PackageCount).Info("OK")
+	return h.buildResponse(stats, http.StatusOK)
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag. It uses the weak comparison
+// RFC 9110 requires for If-None-Match, so W/"x" matches "x".
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/dataset"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/pgdb"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/role"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func testDataset(nodeId string) *pgdb.Dataset {
+	return &pgdb.Dataset{
+		Id:            1234,
+		Name:          "Test Dataset",
+		State:         "READY",
+		Description:   sql.NullString{String: "A dataset", Valid: true},
+		NodeId:        sql.NullString{String: nodeId, Valid: true},
+		PermissionBit: sql.NullInt32{Int32: 7, Valid: true},
+		Role:          sql.NullString{String: "owner", Valid: true},
+		Status:        "NO_STATUS",
+		License:       sql.NullString{String: "MIT", Valid: true},
+		Tags:          pgdb.Tags{"a", "b"},
+		ReadmeId:      uuid.MustParse("6c9c6d0a-4c6e-4d8b-9a2e-3b1f6f4f5a01"),
+		Size:          sql.NullInt64{Int64: 2048, Valid: true},
+		ETag:          time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func newDatasetRequest(datasetID string, claimNodeId string, ifNoneMatch string) (*RequestHandler, *MockDatasetsService) {
+	return newDatasetPathRequest("/"+datasetID, claimNodeId, ifNoneMatch)
+}
+
+func newDatasetPathRequest(path string, claimNodeId string, ifNoneMatch string) (*RequestHandler, *MockDatasetsService) {
+	req := newTestRequest("GET", path, "getDatasetRequestID", map[string]string{"dataset_id": claimNodeId}, "")
+	if len(ifNoneMatch) > 0 {
+		req.Headers = map[string]string{"if-none-match": ifNoneMatch}
+	}
+	claims := authorizer.Claims{
+		DatasetClaim: &dataset.Claim{
+			Role:   role.Viewer,
+			NodeId: claimNodeId,
+			IntId:  1234,
+		}}
+	mockService := new(MockDatasetsService)
+	return NewHandler(req, &claims).WithService(mockService), mockService
+}
+
+func TestDatasetRoute(t *testing.T) {
+	datasetID := "N:dataset:1234"
+	ds := testDataset(datasetID)
+	handler, mockService := newDatasetRequest(datasetID, datasetID, "")
+	mockService.OnGetDatasetReturn(datasetID, ds)
+
+	resp, err := handler.handle(context.Background())
+	if assert.NoError(t, err) {
+		mockService.AssertExpectations(t)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, models.DatasetETag(ds), resp.Headers["ETag"])
+		assert.Equal(t, "application/json", resp.Headers["Content-Type"])
+
+		var body map[string]any
+		if assert.NoError(t, json.Unmarshal([]byte(resp.Body), &body)) {
+			assert.Equal(t, datasetID, body["id"])
+			assert.Equal(t, "Test Dataset", body["name"])
+			assert.Equal(t, "A dataset", body["description"])
+			assert.Equal(t, "MIT", body["license"])
+			assert.Equal(t, []any{"a", "b"}, body["tags"])
+			assert.Equal(t, []any{}, body["contributors"])
+			assert.Equal(t, float64(2048), body["size"])
+			assert.Equal(t, "6c9c6d0a-4c6e-4d8b-9a2e-3b1f6f4f5a01", body["readmeId"])
+			assert.Nil(t, body["bannerId"])
+			assert.Nil(t, body["changelogId"])
+			assert.Nil(t, body["dataUseAgreementId"])
+			assert.NotContains(t, body, "permission_bit")
+			assert.NotContains(t, body, "permissionBit")
+			assert.NotContains(t, body, "role")
+		}
+	}
+}
+
+func TestDatasetRouteConditional(t *testing.T) {
+	datasetID := "N:dataset:1234"
+	ds := testDataset(datasetID)
+	etag := models.DatasetETag(ds)
+	for tName, tData := range map[string]struct {
+		IfNoneMatch    string
+		ExpectedStatus int
+	}{
+		"matching etag":      {etag, http.StatusNotModified},
+		"weak matching etag": {"W/" + etag, http.StatusNotModified},
+		"one of several":     {`"other", ` + etag, http.StatusNotModified},
+		"wildcard":           {"*", http.StatusNotModified},
+		"stale etag":         {`"1234-1"`, http.StatusOK},
+	} {
+		handler, mockService := newDatasetRequest(datasetID, datasetID, tData.IfNoneMatch)
+		mockService.OnGetDatasetReturn(datasetID, ds)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				assert.Equal(t, etag, resp.Headers["ETag"])
+				if tData.ExpectedStatus == http.StatusNotModified {
+					assert.Empty(t, resp.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestDatasetRouteHandledErrors(t *testing.T) {
+	datasetID := "N:dataset:1234"
+	for tName, tData := range map[string]struct {
+		PathId         string
+		ClaimNodeId    string
+		ServiceError   error
+		ExpectedStatus int
+		ExpectedCode   models.ErrorCode
+	}{
+		"dataset not found": {
+			PathId:         datasetID,
+			ClaimNodeId:    datasetID,
+			ServiceError:   models.DatasetNotFoundError{Id: models.DatasetNodeId(datasetID)},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedCode:   models.ErrorCodeDatasetNotFound,
+		},
+		"claim for another dataset": {
+			PathId:         datasetID,
+			ClaimNodeId:    "N:dataset:5678",
+			ExpectedStatus: http.StatusUnauthorized,
+			ExpectedCode:   models.ErrorCodeUnauthorized,
+		},
+		"not a dataset id": {
+			PathId:         "unknown",
+			ClaimNodeId:    datasetID,
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedCode:   models.ErrorCodeResourceNotFound,
+		},
+	} {
+		handler, mockService := newDatasetRequest(tData.PathId, tData.ClaimNodeId, "")
+		if tData.ServiceError != nil {
+			mockService.OnGetDatasetFail(tData.PathId, tData.ServiceError)
+		}
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				assert.Contains(t, resp.Body, string(tData.ExpectedCode))
+			}
+		})
+	}
+}
+
+func TestDatasetStatsRoute(t *testing.T) {
+	datasetID := "N:dataset:1234"
+	handler, mockService := newDatasetPathRequest("/"+datasetID+"/stats", datasetID, "")
+	mockService.OnGetDatasetStatsReturn(datasetID, &models.DatasetStats{
+		DatasetId:     datasetID,
+		PackageCount:  2,
+		PackageTypes:  map[string]models.PackageTypeStats{"CSV": {Count: 2, Size: 30}},
+		PackageStates: map[string]int{"UPLOADED": 2, "DELETED": 1},
+		TrashedCount:  1,
+		LargestFiles:  []models.LargestFile{},
+	})
+
+	resp, err := handler.handle(context.Background())
+	if assert.NoError(t, err) {
+		mockService.AssertExpectations(t)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Body, `"packageTypes":{"CSV":{"count":2,"size":30}}`)
+		assert.Contains(t, resp.Body, `"trashedCount":1`)
+	}
+}
+
+func TestDatasetStatsRouteHandledErrors(t *testing.T) {
+	datasetID := "N:dataset:1234"
+	for tName, tData := range map[string]struct {
+		ClaimNodeId    string
+		ServiceError   error
+		ExpectedStatus int
+		ExpectedCode   models.ErrorCode
+	}{
+		"dataset not found": {
+			ClaimNodeId:    datasetID,
+			ServiceError:   models.DatasetNotFoundError{Id: models.DatasetNodeId(datasetID)},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedCode:   models.ErrorCodeDatasetNotFound,
+		},
+		"claim for another dataset": {
+			ClaimNodeId:    "N:dataset:5678",
+			ExpectedStatus: http.StatusUnauthorized,
+			ExpectedCode:   models.ErrorCodeUnauthorized,
+		},
+	} {
+		handler, mockService := newDatasetPathRequest("/"+datasetID+"/stats", tData.ClaimNodeId, "")
+		if tData.ServiceError != nil {
+			mockService.OnGetDatasetStatsFail(datasetID, tData.ServiceError)
+		}
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				assert.Contains(t, resp.Body, string(tData.ExpectedCode))
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/lib/pq"
+	"github.com/pennsieve/datasets-service/api/models"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// classifyError returns the HTTP status and error code of err. Errors that are not caused by the request are
+// classified as a timeout, the database being unavailable, or else an internal error.
+func classifyError(err error) (status int, code models.ErrorCode) {
+	switch err.(type) {
+	case models.ValidationError:
+		return http.StatusBadRequest, models.ErrorCodeValidation
+	case models.UnauthorizedError:
+		return http.StatusUnauthorized, models.ErrorCodeUnauthorized
+	case models.MethodNotAllowedError:
+		return http.StatusMethodNotAllowed, models.ErrorCodeMethodNotAllowed
+	case models.ResourceNotFoundError:
+		return http.StatusNotFound, models.ErrorCodeResourceNotFound
+	case models.DatasetNotFoundError:
+		return http.StatusNotFound, models.ErrorCodeDatasetNotFound
+	case models.PackageNotFoundError:
+		return http.StatusBadRequest, models.ErrorCodePackageNotFound
+	case models.FolderNotFoundError:
+		return http.StatusBadRequest, models.ErrorCodeFolderNotFound
+	case models.PackageNotDeletedError:
+		return http.StatusBadRequest, models.ErrorCodePackageNotDeleted
+	case models.ManifestJobNotFoundError:
+		return http.StatusNotFound, models.ErrorCodeManifestJobNotFound
+	case models.ManifestNotFoundError:
+		return http.StatusNotFound, models.ErrorCodeManifestNotFound
+	}
+
+	var pqErr *pq.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, models.ErrorCodeResourceNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, models.ErrorCodeTimeout
+	case errors.As(err, &pqErr):
+		return classifyPostgresError(pqErr)
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.As(err, &netErr):
+		return http.StatusServiceUnavailable, models.ErrorCodeUnavailable
+	default:
+		return http.StatusInternalServerError, models.ErrorCodeInternal
+	}
+}
+
+// classifyPostgresError classifies err by its SQLSTATE class (https://www.postgresql.org/docs/current/errcodes-appendix.html).
+func classifyPostgresError(err *pq.Error) (status int, code models.ErrorCode) {
+	switch {
+	case err.Code.Name() == "unique_violation", err.Code.Class() == "40":
+		// a concurrent change, or a serialization failure or deadlock that should succeed if retried
+		return http.StatusConflict, models.ErrorCodeConflict
+	case err.Code.Name() == "query_canceled":
+		return http.StatusGatewayTimeout, models.ErrorCodeTimeout
+	case err.Code.Class() == "08", err.Code.Class() == "53", strings.HasPrefix(string(err.Code), "57P"):
+		// connection exceptions, insufficient resources, and the server shutting down
+		return http.StatusServiceUnavailable, models.ErrorCodeUnavailable
+	default:
+		return http.StatusInternalServerError, models.ErrorCodeInternal
+	}
+}
+
+// handleError builds the error response for err. The messages of server errors are not returned to the caller,
+// since they may contain internal details, but they are logged.
+func (h *RequestHandler) handleError(err error) (*events.APIGatewayV2HTTPResponse, error) {
+	status, code := classifyError(err)
+	if status >= http.StatusInternalServerError {
+		h.logger.WithFields(log.Fields{"status": status, "code": code}).Errorf("%s %s failed: %s", h.method, h.path, err)
+		return h.buildErrorResponse(serverErrorMessage(status), code, nil, status), nil
+	}
+	var details any
+	if validationErr, isValidation := err.(models.ValidationError); isValidation {
+		details = validationErr.Details
+	}
+	response := h.logAndBuildError(err.Error(), code, details, status)
+	if methodErr, isMethod := err.(models.MethodNotAllowedError); isMethod && len(methodErr.Allowed) > 0 {
+		response.Headers["Allow"] = strings.Join(methodErr.Allowed, ", ")
+	}
+	return response, nil
+}
+
+func serverErrorMessage(status int) string {
+	switch status {
+	case http.StatusServiceUnavailable:
+		return "service temporarily unavailable, please retry"
+	case http.StatusGatewayTimeout:
+		return "request timed out"
+	default:
+		return "internal error"
+	}
+}
+
+// logAndBuildError logs message and builds an error response for it. Server errors are logged at error level and
+// errors caused by the request at warning level, so that only the former need attention.
+func (h *RequestHandler) logAndBuildError(message string, code models.ErrorCode, details any, status int) *events.APIGatewayV2HTTPResponse {
+	logger := h.logger.WithFields(log.Fields{"status": status, "code": code})
+	if status >= http.StatusInternalServerError {
+		logger.Error(message)
+	} else {
+		logger.Warn(message)
+	}
+	return h.buildErrorResponse(message, code, details, status)
+}
+
+func (h *RequestHandler) buildErrorResponse(message string, code models.ErrorCode, details any, status int) *events.APIGatewayV2HTTPResponse {
+	errorBody, err := json.Marshal(models.ErrorResponse{
+		Message:   message,
+		Code:      code,
+		RequestId: h.requestID,
+		Details:   details,
+	})
+	if err != nil {
+		h.logger.Errorf("error marshalling details: [%v]: %s", details, err)
+		errorBody, _ = json.Marshal(models.ErrorResponse{Message: message, Code: code, RequestId: h.requestID})
+	}
+	response := buildResponseFromString(string(errorBody), status)
+	response.Headers = map[string]string{"Content-Type": "application/json"}
+	return response
+}
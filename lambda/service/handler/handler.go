@@ -12,8 +12,10 @@ import (
 	"github.com/pennsieve/datasets-service/api/service"
 	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
 	log "github.com/sirupsen/logrus"
+	"math"
 	"os"
 	"strconv"
+	"time"
 )
 
 var (
@@ -39,23 +41,27 @@ func init() {
 }
 
 func DatasetsServiceHandler(ctx context.Context, request events.APIGatewayV2HTTPRequest) (*events.APIGatewayV2HTTPResponse, error) {
-	claims := authorizer.ParseClaims(request.RequestContext.Authorizer.Lambda)
-	handler := NewHandler(&request, claims)
-	
-	// Route to appropriate service based on path
-	path := request.RequestContext.HTTP.Path
-	if path == "/shared-datasets" {
-		handler = handler.WithCrossWorkspaceService()
-	} else {
-		handler = handler.WithDefaultService()
+	// Only what is needed to report errors is set up before the claims are parsed, so that a panic parsing them or
+	// creating the handler is also returned as an internal error.
+	reqID := request.RequestContext.RequestID
+	errorHandler := &RequestHandler{
+		request:   &request,
+		requestID: reqID,
+		method:    request.RequestContext.HTTP.Method,
+		path:      request.RequestContext.HTTP.Path,
+		logger:    log.WithFields(log.Fields{"requestID": reqID}),
 	}
-	
-	return handler.handle(ctx)
+	return errorHandler.withErrorHandling(func(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+		claims := authorizer.ParseClaims(request.RequestContext.Authorizer.Lambda)
+		handler := NewHandler(&request, claims)
+		return handler.withErrorHandling(handler.handle)(ctx)
+	})(ctx)
 }
 
 // RequestHandler wraps the incoming request with a logger and a service.DatasetsService.
-// Some request params are pulled out for convenience. Use NewHandler followed by WithDefaultService to have things
-// initialized nicely. Use WithService in tests where a specially constructed or mock service.DatasetsService is required.
+// Some request params are pulled out for convenience. Use NewHandler to have things initialized nicely; the services
+// a route needs are added by its middleware. Use WithService in tests where a specially constructed or mock
+// service.DatasetsService is required.
 type RequestHandler struct {
 	request   *events.APIGatewayV2HTTPRequest
 	requestID string
@@ -69,6 +75,9 @@ type RequestHandler struct {
 	datasetsService               service.DatasetsService
 	crossWorkspaceDatasetsService service.CrossWorkspaceDatasetsService
 	claims                        *authorizer.Claims
+
+	// pathParams are the values of the parameters in the template of the matched route
+	pathParams map[string]string
 }
 
 // NewHandler creates a RequestHandler that has its logger field initialized with useful fields.
@@ -125,12 +134,6 @@ func (h *RequestHandler) WithService(dsService service.DatasetsService) *Request
 	return h
 }
 
-func (h *RequestHandler) logAndBuildError(message string, status int) *events.APIGatewayV2HTTPResponse {
-	h.logger.Error(message)
-	errorBody := fmt.Sprintf("{'message': '%s (requestID: %s)'}", message, h.requestID)
-	return buildResponseFromString(errorBody, status)
-}
-
 func (h *RequestHandler) queryParamAsInt(paramName string, minValue, maxValue, defaultValue int) (int, error) {
 	strValue, ok := h.request.QueryStringParameters[paramName]
 	if !ok {
@@ -149,6 +152,58 @@ func (h *RequestHandler) queryParamAsInt(paramName string, minValue, maxValue, d
 	return v, nil
 }
 
+func (h *RequestHandler) queryParamAsBool(paramName string, defaultValue bool) (bool, error) {
+	strValue, ok := h.request.QueryStringParameters[paramName]
+	if !ok {
+		return defaultValue, nil
+	}
+	v, err := strconv.ParseBool(strValue)
+	if err != nil {
+		return false, fmt.Errorf("%q is not a valid value for %q", strValue, paramName)
+	}
+	return v, nil
+}
+
+// queryParamAsTime parses an RFC 3339 query param into UTC. A missing param is the zero time.
+func (h *RequestHandler) queryParamAsTime(paramName string) (time.Time, error) {
+	strValue, ok := h.request.QueryStringParameters[paramName]
+	if !ok {
+		return time.Time{}, nil
+	}
+	v, err := time.Parse(time.RFC3339, strValue)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a valid RFC 3339 time for %q", strValue, paramName)
+	}
+	return v.UTC(), nil
+}
+
+// queryParamsAsPaging reads the limit, offset, cursor and include_total query params. parseCursor decodes the
+// cursor, which cannot be combined with an offset.
+func queryParamsAsPaging[C any](h *RequestHandler, parseCursor func(string) (*C, error)) (models.Paging[C], error) {
+	var paging models.Paging[C]
+	var err error
+	if paging.Limit, err = h.queryParamAsInt("limit", 0, 100, DefaultLimit); err != nil {
+		return paging, err
+	}
+	if paging.Offset, err = h.queryParamAsInt("offset", 0, math.MaxInt, DefaultOffset); err != nil {
+		return paging, err
+	}
+	includeTotal, err := h.queryParamAsBool("include_total", true)
+	if err != nil {
+		return paging, err
+	}
+	paging.SkipTotal = !includeTotal
+	if cursor, ok := h.request.QueryStringParameters["cursor"]; ok {
+		if _, hasOffset := h.request.QueryStringParameters["offset"]; hasOffset {
+			return paging, fmt.Errorf("query params 'cursor' and 'offset' cannot be combined")
+		}
+		if paging.After, err = parseCursor(cursor); err != nil {
+			return paging, err
+		}
+	}
+	return paging, nil
+}
+
 func (h *RequestHandler) buildResponse(body any, status int) (*events.APIGatewayV2HTTPResponse, error) {
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
@@ -3,39 +3,93 @@ package handler
 import (
 	"context"
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
-	"github.com/pennsieve/pennsieve-go-core/pkg/models/permissions"
+	"github.com/pennsieve/datasets-service/api/models"
 	"net/http"
+	"time"
 )
 
-type ManifestHandler struct {
-	RequestHandler
-}
+func (h *RequestHandler) getManifest(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	datasetNodeId, ok := h.request.QueryStringParameters["dataset_id"]
+	if !ok {
+		return h.handleError(models.ValidationError{Message: "query param 'dataset_id' is required"})
+	}
 
-func (h *ManifestHandler) handle(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
-	switch h.method {
-	case "GET":
-		return h.get(ctx)
-	default:
-		return h.logAndBuildError("method not allowed: "+h.method, http.StatusMethodNotAllowed), nil
+	force, err := h.queryParamAsBool("force", false)
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
+	}
+	format, err := models.ParseManifestFormat(h.request.QueryStringParameters["format"])
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
+	}
+	absolutePaths, err := h.queryParamAsBool("absolute_paths", false)
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
+	}
+	rootNodeId := h.request.QueryStringParameters["root_node_id"]
+	includeUrls, err := h.queryParamAsBool("include_urls", false)
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
+	}
+	if includeUrls && format == models.BagItManifest {
+		return h.handleError(models.ValidationError{Message: "'include_urls' is not supported for bagit manifests"})
+	}
+	urlExpiry, err := h.queryParamAsInt("url_expiry", 1, int(models.MaxManifestUrlExpiry/time.Second), int(models.DefaultManifestUrlExpiry/time.Second))
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
 	}
 
+	options := models.ManifestOptions{Format: format, Force: force, RootNodeId: rootNodeId, AbsolutePaths: absolutePaths}
+	if includeUrls {
+		options.IncludeUrls = true
+		options.UrlExpiry = time.Duration(urlExpiry) * time.Second
+	}
+	manifestResult, err := h.datasetsService.TriggerAsyncGetManifest(ctx, datasetNodeId, options)
+	if err == nil {
+		h.logger.Info("OK")
+		if manifestResult.Status == models.READY {
+			// an existing manifest was reused, no job was started
+			return h.buildResponse(manifestResult, http.StatusOK)
+		}
+		return h.buildResponse(manifestResult, http.StatusAccepted)
+	}
+	return h.handleError(err)
 }
 
-func (h *ManifestHandler) get(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
-	if authorized := authorizer.HasRole(*h.claims, permissions.ViewFiles); !authorized {
-		return h.logAndBuildError("unauthorized", http.StatusUnauthorized), nil
+func (h *RequestHandler) getManifestStatus(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	datasetNodeId, ok := h.request.QueryStringParameters["dataset_id"]
+	if !ok {
+		return h.handleError(models.ValidationError{Message: "query param 'dataset_id' is required"})
+	}
+	jobId, ok := h.request.QueryStringParameters["job_id"]
+	if !ok {
+		return h.handleError(models.ValidationError{Message: "query param 'job_id' is required"})
 	}
 
+	manifestResult, err := h.datasetsService.GetManifestJobStatus(ctx, datasetNodeId, jobId)
+	if err == nil {
+		h.logger.Info("OK")
+		return h.buildResponse(manifestResult, http.StatusOK)
+	}
+	return h.handleError(err)
+}
+
+func (h *RequestHandler) getManifestDiff(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
 	datasetNodeId, ok := h.request.QueryStringParameters["dataset_id"]
 	if !ok {
-		return h.logAndBuildError("query param 'dataset_id' is required", http.StatusBadRequest), nil
+		return h.handleError(models.ValidationError{Message: "query param 'dataset_id' is required"})
 	}
-
-	manifestResult, err := h.datasetsService.GetManifest(ctx, datasetNodeId)
-	if err != nil {
-		return nil, err
+	from, ok := h.request.QueryStringParameters["from"]
+	if !ok {
+		return h.handleError(models.ValidationError{Message: "query param 'from' is required"})
 	}
+	// without 'to' the manifest is compared with the live dataset
+	to := h.request.QueryStringParameters["to"]
 
-	return h.buildResponse(manifestResult, http.StatusOK)
+	diff, err := h.datasetsService.GetManifestDiff(ctx, datasetNodeId, from, to)
+	if err == nil {
+		h.logger.Info("OK")
+		return h.buildResponse(diff, http.StatusOK)
+	}
+	return h.handleError(err)
 }
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pennsieve/datasets-service/api/models"
+	"net/http"
+	"runtime/debug"
+)
+
+type handlerFunc func(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error)
+
+// withErrorHandling wraps next so that it always returns a well-formed response carrying the request ID and never
+// an error, which API Gateway would turn into an opaque 502. Panics are recovered and returned as internal errors.
+func (h *RequestHandler) withErrorHandling(next handlerFunc) handlerFunc {
+	return func(ctx context.Context) (response *events.APIGatewayV2HTTPResponse, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				h.logger.WithField("stack", string(debug.Stack())).Errorf("%s %s panicked: %v", h.method, h.path, r)
+				response, err = h.buildErrorResponse("internal error", models.ErrorCodeInternal, nil, http.StatusInternalServerError), nil
+			}
+		}()
+		response, err = next(ctx)
+		if err != nil {
+			return h.handleError(err)
+		}
+		if response == nil {
+			return h.handleError(errors.New("handler returned neither a response nor an error"))
+		}
+		return response, nil
+	}
+}
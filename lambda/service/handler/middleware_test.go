@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/lib/pq"
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestWithErrorHandling(t *testing.T) {
+	for tName, tData := range map[string]struct {
+		Next            handlerFunc
+		ExpectedStatus  int
+		ExpectedCode    models.ErrorCode
+		ExpectedMessage string
+	}{
+		"panic": {
+			Next: func(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+				var result *models.ManifestResult
+				return buildResponseFromString(result.JobId, http.StatusOK), nil
+			},
+			ExpectedStatus:  http.StatusInternalServerError,
+			ExpectedCode:    models.ErrorCodeInternal,
+			ExpectedMessage: "internal error",
+		},
+		"unclassified error": {
+			Next: func(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+				return nil, errors.New("secret connection string")
+			},
+			ExpectedStatus:  http.StatusInternalServerError,
+			ExpectedCode:    models.ErrorCodeInternal,
+			ExpectedMessage: "internal error",
+		},
+		"no response": {
+			Next: func(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+				return nil, nil
+			},
+			ExpectedStatus:  http.StatusInternalServerError,
+			ExpectedCode:    models.ErrorCodeInternal,
+			ExpectedMessage: "internal error",
+		},
+		"models error": {
+			Next: func(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+				return nil, models.DatasetNotFoundError{OrgId: 3, Id: models.DatasetNodeId("N:dataset:1")}
+			},
+			ExpectedStatus:  http.StatusNotFound,
+			ExpectedCode:    models.ErrorCodeDatasetNotFound,
+			ExpectedMessage: `dataset N:dataset:1 not found in workspace 3`,
+		},
+		"timeout": {
+			Next: func(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+				return nil, fmt.Errorf("query failed: %w", context.DeadlineExceeded)
+			},
+			ExpectedStatus:  http.StatusGatewayTimeout,
+			ExpectedCode:    models.ErrorCodeTimeout,
+			ExpectedMessage: "request timed out",
+		},
+	} {
+		req := newTestRequest("GET", "/manifest", "middlewareRequestID", map[string]string{}, "")
+		handler := NewHandler(req, &authorizer.Claims{})
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.withErrorHandling(tData.Next)(context.Background())
+			if assert.NoError(t, err) && assert.NotNil(t, resp) {
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				var errorResponse models.ErrorResponse
+				if assert.NoError(t, json.Unmarshal([]byte(resp.Body), &errorResponse)) {
+					assert.Equal(t, models.ErrorResponse{
+						Message:   tData.ExpectedMessage,
+						Code:      tData.ExpectedCode,
+						RequestId: "middlewareRequestID",
+					}, errorResponse)
+				}
+			}
+		})
+	}
+}
+
+func TestWithErrorHandlingPassesResponse(t *testing.T) {
+	req := newTestRequest("GET", "/manifest", "middlewareRequestID", map[string]string{}, "")
+	handler := NewHandler(req, &authorizer.Claims{})
+	expected := buildResponseFromString(`{"status": "READY"}`, http.StatusOK)
+	resp, err := handler.withErrorHandling(func(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+		return expected, nil
+	})(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, resp)
+	}
+}
+
+func TestDatasetsServiceHandlerMissingAuthorizer(t *testing.T) {
+	req := newTestRequest("GET", "/manifest", "middlewareRequestID", map[string]string{}, "")
+	resp, err := DatasetsServiceHandler(context.Background(), *req)
+	if assert.NoError(t, err) && assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		var errorResponse models.ErrorResponse
+		if assert.NoError(t, json.Unmarshal([]byte(resp.Body), &errorResponse)) {
+			assert.Equal(t, models.ErrorResponse{Message: "internal error", Code: models.ErrorCodeInternal, RequestId: "middlewareRequestID"}, errorResponse)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	for tName, tData := range map[string]struct {
+		Err            error
+		ExpectedStatus int
+		ExpectedCode   models.ErrorCode
+	}{
+		"validation":           {models.ValidationError{Message: "bad"}, http.StatusBadRequest, models.ErrorCodeValidation},
+		"folder not found":     {models.FolderNotFoundError{}, http.StatusBadRequest, models.ErrorCodeFolderNotFound},
+		"no rows":              {fmt.Errorf("get package: %w", sql.ErrNoRows), http.StatusNotFound, models.ErrorCodeResourceNotFound},
+		"unique violation":     {&pq.Error{Code: "23505"}, http.StatusConflict, models.ErrorCodeConflict},
+		"serialization":        {&pq.Error{Code: "40001"}, http.StatusConflict, models.ErrorCodeConflict},
+		"query canceled":       {&pq.Error{Code: "57014"}, http.StatusGatewayTimeout, models.ErrorCodeTimeout},
+		"connection failure":   {&pq.Error{Code: "08006"}, http.StatusServiceUnavailable, models.ErrorCodeUnavailable},
+		"too many connections": {&pq.Error{Code: "53300"}, http.StatusServiceUnavailable, models.ErrorCodeUnavailable},
+		"admin shutdown":       {&pq.Error{Code: "57P01"}, http.StatusServiceUnavailable, models.ErrorCodeUnavailable},
+		"syntax error":         {&pq.Error{Code: "42601"}, http.StatusInternalServerError, models.ErrorCodeInternal},
+		"bad connection":       {driver.ErrBadConn, http.StatusServiceUnavailable, models.ErrorCodeUnavailable},
+		"connection refused":   {&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, http.StatusServiceUnavailable, models.ErrorCodeUnavailable},
+		"deadline":             {context.DeadlineExceeded, http.StatusGatewayTimeout, models.ErrorCodeTimeout},
+		"other":                {errors.New("boom"), http.StatusInternalServerError, models.ErrorCodeInternal},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			status, code := classifyError(tData.Err)
+			assert.Equal(t, tData.ExpectedStatus, status)
+			assert.Equal(t, tData.ExpectedCode, code)
+		})
+	}
+}
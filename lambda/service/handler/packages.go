@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pennsieve/datasets-service/api/models"
+	"math"
+	"net/http"
+)
+
+// getPackages lists the live contents of a dataset folder, the counterpart of getTrashcan.
+func (h *RequestHandler) getPackages(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	datasetID, ok := h.request.QueryStringParameters["dataset_id"]
+	if !ok {
+		return h.handleError(models.ValidationError{Message: "query param 'dataset_id' is required"})
+	}
+	limit, err := h.queryParamAsInt("limit", 0, 100, DefaultLimit)
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
+	}
+	offset, err := h.queryParamAsInt("offset", 0, math.MaxInt, DefaultOffset)
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
+	}
+	sort, err := models.ParsePackageSort(h.request.QueryStringParameters["sort"], h.request.QueryStringParameters["order"])
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
+	}
+	rootNodeId := h.request.QueryStringParameters["root_node_id"]
+	page, err := h.datasetsService.GetPackagesPage(ctx, datasetID, rootNodeId, sort, limit, offset)
+	if err != nil {
+		return h.handleError(err)
+	}
+	h.logger.Info("OK")
+	return h.buildResponse(page, http.StatusOK)
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/dataset"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/role"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func newPackagesRequest(datasetID string, queryParams queryParamMap) (*RequestHandler, *MockDatasetsService) {
+	req := newTestRequest("GET", "/packages", "getPackagesRequestID", queryParams, "")
+	claims := authorizer.Claims{
+		DatasetClaim: &dataset.Claim{
+			Role:   role.Viewer,
+			NodeId: datasetID,
+			IntId:  1234,
+		}}
+	mockService := new(MockDatasetsService)
+	return NewHandler(req, &claims).WithService(mockService), mockService
+}
+
+func TestPackagesRoute(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	for tName, tData := range map[string]struct {
+		QueryParams  queryParamMap
+		ExpectedSort models.PackageSort
+	}{
+		"without optional params": {queryParamMap{"dataset_id": datasetID}, models.DefaultPackageSort},
+		"with root_node_id param": {queryParamMap{"dataset_id": datasetID, "root_node_id": "N:collection:abcd"}, models.DefaultPackageSort},
+		"with limit and offset":   {queryParamMap{"dataset_id": datasetID, "limit": "30", "offset": "60"}, models.DefaultPackageSort},
+		"with sort param":         {queryParamMap{"dataset_id": datasetID, "sort": "size"}, models.PackageSort{Field: models.SortBySize, Order: models.Ascending}},
+		"with sort and order":     {queryParamMap{"dataset_id": datasetID, "sort": "created_at", "order": "desc"}, models.PackageSort{Field: models.SortByCreatedAt, Order: models.Descending}},
+	} {
+		handler, mockService := newPackagesRequest(datasetID, tData.QueryParams)
+		expectedPage := &models.PackagesPage{Limit: tData.QueryParams.expectedLimit(t), Offset: tData.QueryParams.expectedOffset(t), Packages: []models.PackageItem{}}
+		mockService.OnGetPackagesPageReturn(datasetID, tData.QueryParams["root_node_id"], tData.ExpectedSort, expectedPage.Limit, expectedPage.Offset, expectedPage)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.Contains(t, resp.Body, `"packages":[]`)
+			}
+		})
+	}
+}
+
+func TestPackagesRouteHandledErrors(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	rootNodeID := "N:collection:abcd"
+	for tName, tData := range map[string]struct {
+		QueryParams         queryParamMap
+		ServiceError        error
+		ExpectedStatus      int
+		ExpectedSubMessages []string
+	}{
+		"with too high limit": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "limit": "101"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"max value", "limit"}},
+		"with unknown sort field": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "sort": "owner_id"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"unsupported sort field", "owner_id"}},
+		"with unknown sort order": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "order": "random"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"unsupported sort order", "random"}},
+		"dataset not found": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			ServiceError:        models.DatasetNotFoundError{Id: models.DatasetNodeId(datasetID)},
+			ExpectedStatus:      http.StatusNotFound,
+			ExpectedSubMessages: []string{"not found", datasetID}},
+		"folder not found": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "root_node_id": rootNodeID},
+			ServiceError:        models.PackageNotFoundError{Id: models.PackageNodeId(rootNodeID), DatasetId: models.DatasetIntId(13)},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"not found", rootNodeID}},
+	} {
+		handler, mockService := newPackagesRequest(datasetID, tData.QueryParams)
+		if tData.ServiceError != nil {
+			mockService.OnGetPackagesPageFail(datasetID, tData.QueryParams["root_node_id"], models.DefaultPackageSort, DefaultLimit, DefaultOffset, tData.ServiceError)
+		}
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				for _, messageFragment := range tData.ExpectedSubMessages {
+					assert.Contains(t, resp.Body, messageFragment)
+				}
+			}
+		})
+	}
+}
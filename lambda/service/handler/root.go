@@ -3,22 +3,120 @@ package handler
 import (
 	"context"
 	"github.com/aws/aws-lambda-go/events"
-	"net/http"
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/permissions"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/role"
 )
 
+var routes = newRouter()
+
+func newRouter() *router {
+	r := &router{}
+	// With the "datasets" API mapping this is GET /datasets/{id}. The id only matches dataset node ids, so that
+	// other paths are not found rather than only allowing GET.
+	r.add("GET", "/{id:"+datasetNodeIdPattern+"}", (*RequestHandler).getDataset,
+		requireDatasetPathParam("id"), requirePermission(permissions.ViewFiles), withDatasetsService)
+	r.add("GET", "/{id:"+datasetNodeIdPattern+"}/stats", (*RequestHandler).getDatasetStats,
+		requireDatasetPathParam("id"), requirePermission(permissions.ViewFiles), withDatasetsService)
+	r.add("GET", "/trashcan", (*RequestHandler).getTrashcan,
+		requirePermission(permissions.ViewFiles), withDatasetsService)
+	r.add("GET", "/trashcan/summary", (*RequestHandler).getTrashcanSummary,
+		requirePermission(permissions.ViewFiles), withDatasetsService)
+	// Exporting writes a new report each time, so it is not a GET
+	r.add("POST", "/trashcan/export", (*RequestHandler).exportTrashcan,
+		requirePermission(permissions.ViewFiles), withDatasetsService)
+	// Purging cannot be undone, so it requires the Manager role rather than a file permission
+	r.add("DELETE", "/trashcan", (*RequestHandler).purgeTrashcan,
+		requireDatasetRole(role.Manager), withDatasetsService)
+	r.add("POST", "/trashcan/restore", (*RequestHandler).restoreTrashcan,
+		requirePermission(permissions.CreateDeleteFiles), withDatasetsService)
+	r.add("GET", "/packages", (*RequestHandler).getPackages,
+		requirePermission(permissions.ViewFiles), withDatasetsService)
+	r.add("GET", "/manifest", (*RequestHandler).getManifest,
+		requirePermission(permissions.ViewFiles), withDatasetsService)
+	r.add("GET", "/manifest/status", (*RequestHandler).getManifestStatus,
+		requirePermission(permissions.ViewFiles), withDatasetsService)
+	r.add("GET", "/manifest/diff", (*RequestHandler).getManifestDiff,
+		requirePermission(permissions.ViewFiles), withDatasetsService)
+	// Shared datasets span workspaces, so only an authenticated user is needed
+	r.add("GET", "/shared-datasets", (*RequestHandler).getSharedDatasets,
+		requireUser, withCrossWorkspaceService)
+	return r
+}
+
 func (h *RequestHandler) handle(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	return routes.handle(h, ctx)
+}
+
+// requirePermission only lets requests through whose dataset claim has the permission.
+func requirePermission(permission permissions.DatasetPermission) middleware {
+	return func(next routeFunc) routeFunc {
+		return func(h *RequestHandler, ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+			if h.claims == nil || h.claims.DatasetClaim == nil || !authorizer.HasRole(*h.claims, permission) {
+				return h.handleError(models.UnauthorizedError{})
+			}
+			return next(h, ctx)
+		}
+	}
+}
+
+// requireDatasetPathParam only lets requests through whose path parameter is the node id of the dataset in the
+// dataset claim: the authorizer grants the claim for the dataset_id query parameter, so both must name the same
+// dataset.
+func requireDatasetPathParam(name string) middleware {
+	return func(next routeFunc) routeFunc {
+		return func(h *RequestHandler, ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+			nodeId := h.pathParams[name]
+			if h.claims == nil || h.claims.DatasetClaim == nil || h.claims.DatasetClaim.NodeId != nodeId {
+				return h.handleError(models.UnauthorizedError{})
+			}
+			return next(h, ctx)
+		}
+	}
+}
+
+// datasetNodeIdPattern matches dataset node ids in path parameters.
+const datasetNodeIdPattern = "(?i)N:dataset:.+"
+
+// requireDatasetRole only lets requests through whose dataset claim has at least the role.
+func requireDatasetRole(minRole role.Role) middleware {
+	return func(next routeFunc) routeFunc {
+		return func(h *RequestHandler, ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+			if h.claims == nil || h.claims.DatasetClaim == nil || !h.claims.DatasetClaim.Role.Implies(minRole) {
+				return h.handleError(models.UnauthorizedError{})
+			}
+			return next(h, ctx)
+		}
+	}
+}
+
+// requireUser only lets requests through that have a user claim.
+func requireUser(next routeFunc) routeFunc {
+	return func(h *RequestHandler, ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+		if h.claims == nil || h.claims.UserClaim == nil {
+			return h.handleError(models.UnauthorizedError{})
+		}
+		return next(h, ctx)
+	}
+}
+
+// withDatasetsService adds the default service.DatasetsService unless one was set with WithService.
+func withDatasetsService(next routeFunc) routeFunc {
+	return func(h *RequestHandler, ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+		if h.datasetsService == nil {
+			h.WithDefaultService()
+		}
+		return next(h, ctx)
+	}
+}
 
-	switch h.path {
-	case "/trashcan":
-		trashcanHandler := TrashcanHandler{*h}
-		return trashcanHandler.handle(ctx)
-	case "/manifest":
-		manifestHandler := ManifestHandler{*h}
-		return manifestHandler.handle(ctx)
-	case "/shared-datasets":
-		sharedDatasetsHandler := SharedDatasetsHandler{*h}
-		return sharedDatasetsHandler.handle(ctx)
-	default:
-		return h.logAndBuildError("resource not found: "+h.path, http.StatusNotFound), nil
+// withCrossWorkspaceService adds the default service.CrossWorkspaceDatasetsService unless one was set already.
+func withCrossWorkspaceService(next routeFunc) routeFunc {
+	return func(h *RequestHandler, ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+		if h.crossWorkspaceDatasetsService == nil {
+			h.WithCrossWorkspaceService()
+		}
+		return next(h, ctx)
 	}
 }
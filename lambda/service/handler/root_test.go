@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/pennsieve/datasets-service/api/models"
 	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
@@ -14,6 +15,7 @@ import (
 	"net/http"
 	"strconv"
 	"testing"
+	"time"
 )
 
 type queryParamMap map[string]string
@@ -40,16 +42,44 @@ func (m queryParamMap) expectedOffset(t *testing.T) int {
 
 func TestTrashcanRoute(t *testing.T) {
 	expectedDatasetID := "N:Dataset:1234"
-	for tName, expectedQueryParams := range map[string]queryParamMap{
-		"without root_node_id param": {"dataset_id": expectedDatasetID},
-		"with root_node_id param":    {"dataset_id": expectedDatasetID, "root_node_id": "N:collection:abcd"},
-		"with limit param":           {"dataset_id": expectedDatasetID, "root_node_id": "N:collection:abcd", "limit": "30"},
-		"with offset param":          {"dataset_id": expectedDatasetID, "offset": "10"},
+	for tName, tData := range map[string]struct {
+		QueryParams    queryParamMap
+		ExpectedFilter models.TrashcanFilter
+		ExpectedSort   models.TrashcanSort
+	}{
+		"without root_node_id param": {queryParamMap{"dataset_id": expectedDatasetID}, models.TrashcanFilter{}, models.DefaultTrashcanSort},
+		"with root_node_id param":    {queryParamMap{"dataset_id": expectedDatasetID, "root_node_id": "N:collection:abcd"}, models.TrashcanFilter{}, models.DefaultTrashcanSort},
+		"with limit param":           {queryParamMap{"dataset_id": expectedDatasetID, "root_node_id": "N:collection:abcd", "limit": "30"}, models.TrashcanFilter{}, models.DefaultTrashcanSort},
+		"with offset param":          {queryParamMap{"dataset_id": expectedDatasetID, "offset": "10"}, models.TrashcanFilter{}, models.DefaultTrashcanSort},
+		"with name params": {
+			queryParamMap{"dataset_id": expectedDatasetID, "name": "scan", "name_prefix": "true"},
+			models.TrashcanFilter{Name: "scan", NamePrefix: true},
+			models.DefaultTrashcanSort},
+		"with type and state params": {
+			queryParamMap{"dataset_id": expectedDatasetID, "type": "Image", "state": "deleting"},
+			models.TrashcanFilter{Type: "Image", State: "DELETING"},
+			models.DefaultTrashcanSort},
+		"with deleted time params": {
+			queryParamMap{"dataset_id": expectedDatasetID, "deleted_after": "2023-02-03T00:00:00Z", "deleted_before": "2023-02-06T12:30:00+01:00"},
+			models.TrashcanFilter{DeletedAfter: time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC), DeletedBefore: time.Date(2023, 2, 6, 11, 30, 0, 0, time.UTC)},
+			models.DefaultTrashcanSort},
+		"with flat param": {
+			queryParamMap{"dataset_id": expectedDatasetID, "root_node_id": "N:collection:abcd", "flat": "true"},
+			models.TrashcanFilter{Flat: true},
+			models.DefaultTrashcanSort},
+		"with sort param": {
+			queryParamMap{"dataset_id": expectedDatasetID, "sort": "deleted_at"},
+			models.TrashcanFilter{},
+			models.TrashcanSort{Field: models.TrashcanSortByDeletedAt, Order: models.Descending}},
+		"with sort and order": {
+			queryParamMap{"dataset_id": expectedDatasetID, "sort": "size", "order": "desc"},
+			models.TrashcanFilter{},
+			models.TrashcanSort{Field: models.TrashcanSortBySize, Order: models.Descending}},
 	} {
 		req := newTestRequest("GET",
 			"/trashcan",
 			"getTrashcanRequestID",
-			expectedQueryParams,
+			tData.QueryParams,
 			"")
 		mockService := new(MockDatasetsService)
 
@@ -59,9 +89,9 @@ func TestTrashcanRoute(t *testing.T) {
 				NodeId: expectedDatasetID,
 				IntId:  1234,
 			}}
-		expectedLimit := expectedQueryParams.expectedLimit(t)
-		expectedOffset := expectedQueryParams.expectedOffset(t)
-		mockService.OnGetTrashcanPageReturn(expectedDatasetID, expectedQueryParams["root_node_id"], expectedLimit, expectedOffset, &models.TrashcanPage{})
+		expectedLimit := tData.QueryParams.expectedLimit(t)
+		expectedOffset := tData.QueryParams.expectedOffset(t)
+		mockService.OnGetTrashcanPageReturn(expectedDatasetID, tData.QueryParams["root_node_id"], tData.ExpectedFilter, tData.ExpectedSort, models.Paging[models.TrashcanCursor]{Limit: expectedLimit, Offset: expectedOffset}, &models.TrashcanPage{})
 		handler := NewHandler(req, &claims).WithService(mockService)
 		t.Run(tName, func(t *testing.T) {
 			_, err := handler.handle(context.Background())
@@ -73,6 +103,42 @@ func TestTrashcanRoute(t *testing.T) {
 	}
 }
 
+func TestTrashcanRoutePaging(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	sort := models.TrashcanSort{Field: models.TrashcanSortByDeletedAt, Order: models.Descending}
+	cursor := models.NewTrashcanCursor(sort, 42, "scan.png", "Image", time.Date(2023, 2, 3, 4, 5, 6, 0, time.UTC), nil)
+	for tName, tData := range map[string]struct {
+		QueryParams    queryParamMap
+		ExpectedPaging models.Paging[models.TrashcanCursor]
+	}{
+		"with cursor": {
+			queryParamMap{"dataset_id": datasetID, "sort": "deleted_at", "cursor": models.EncodeCursor(cursor)},
+			models.Paging[models.TrashcanCursor]{Limit: DefaultLimit, After: &cursor}},
+		"without total": {
+			queryParamMap{"dataset_id": datasetID, "sort": "deleted_at", "offset": "20", "include_total": "false"},
+			models.Paging[models.TrashcanCursor]{Limit: DefaultLimit, Offset: 20, SkipTotal: true}},
+	} {
+		req := newTestRequest("GET", "/trashcan", "getTrashcanRequestID", tData.QueryParams, "")
+		mockService := new(MockDatasetsService)
+		claims := authorizer.Claims{
+			DatasetClaim: &dataset.Claim{
+				Role:   role.Viewer,
+				NodeId: datasetID,
+				IntId:  1234,
+			}}
+		mockService.OnGetTrashcanPageReturn(datasetID, "", models.TrashcanFilter{}, sort, tData.ExpectedPaging, &models.TrashcanPage{NextCursor: "next"})
+		handler := NewHandler(req, &claims).WithService(mockService)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Contains(t, resp.Body, `"nextCursor":"next"`)
+				assert.NotContains(t, resp.Body, "totalCount")
+			}
+		})
+	}
+}
+
 func TestTrashcanRouteHandledErrors(t *testing.T) {
 	datasetID := "N:Dataset:1234"
 	rootNodeID := "N:collection:abcd"
@@ -94,6 +160,34 @@ func TestTrashcanRouteHandledErrors(t *testing.T) {
 			QueryParams:         queryParamMap{"dataset_id": datasetID, "root_node_id": rootNodeID, "offset": "-4"},
 			ExpectedStatus:      http.StatusBadRequest,
 			ExpectedSubMessages: []string{"min value", "offset"}},
+		"with unknown state": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "state": "READY"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"unsupported trashcan state", "READY"}},
+		"with invalid deleted_after": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "deleted_after": "2023-02-03"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"2023-02-03", "deleted_after"}},
+		"with cursor for another sort": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "cursor": models.EncodeCursor(models.TrashcanCursor{Field: models.TrashcanSortBySize, Order: models.Ascending, Id: 3})},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"cursor is for sort size asc"}},
+		"with cursor and offset": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "offset": "0", "cursor": models.EncodeCursor(models.TrashcanCursor{Field: models.TrashcanSortByName, Order: models.Ascending, Id: 3})},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"cannot be combined"}},
+		"with unknown sort field": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "sort": "created_at"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"unsupported sort field", "created_at"}},
+		"with unknown sort order": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "sort": "deleted_at", "order": "newest"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"unsupported sort order", "newest"}},
+		"with invalid flat": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "flat": "yes"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"yes", "flat"}},
 		"dataset not found": {
 			QueryParams:         queryParamMap{"dataset_id": datasetID},
 			ServiceError:        models.DatasetNotFoundError{Id: models.DatasetNodeId(datasetID)},
@@ -126,7 +220,7 @@ func TestTrashcanRouteHandledErrors(t *testing.T) {
 				IntId:  1234,
 			}}
 		if tData.ServiceError != nil {
-			mockService.OnGetTrashcanPageFail(tData.QueryParams["dataset_id"], tData.QueryParams["root_node_id"], tData.QueryParams.expectedLimit(t), tData.QueryParams.expectedOffset(t),
+			mockService.OnGetTrashcanPageFail(tData.QueryParams["dataset_id"], tData.QueryParams["root_node_id"], models.TrashcanFilter{}, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: tData.QueryParams.expectedLimit(t), Offset: tData.QueryParams.expectedOffset(t)},
 				tData.ServiceError)
 		}
 		handler := NewHandler(req, &claims).WithService(mockService)
@@ -144,6 +238,404 @@ func TestTrashcanRouteHandledErrors(t *testing.T) {
 	}
 }
 
+func TestTrashcanRestoreRoute(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	nodeIds := []string{"N:package:1234", "N:collection:abcd"}
+	req := newTestRequest("POST",
+		"/trashcan/restore",
+		"restoreTrashcanRequestID",
+		map[string]string{"dataset_id": datasetID},
+		`{"nodeIds": ["N:package:1234", "N:collection:abcd"]}`)
+	mockService := new(MockDatasetsService)
+	claims := authorizer.Claims{
+		DatasetClaim: &dataset.Claim{
+			Role:   role.Editor,
+			NodeId: datasetID,
+			IntId:  1234,
+		}}
+	mockService.OnRestorePackagesReturn(datasetID, nodeIds, &models.RestoreResponse{
+		Success:  []models.RestoredItem{{NodeId: nodeIds[0], Name: "restored.txt"}},
+		Failures: []models.RestoreFailure{{NodeId: nodeIds[1], Error: "not in the trashcan"}},
+	})
+
+	handler := NewHandler(req, &claims).WithService(mockService)
+	resp, err := handler.handle(context.Background())
+	if assert.NoError(t, err) {
+		mockService.AssertExpectations(t)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Body, "restored.txt")
+		assert.Contains(t, resp.Body, "not in the trashcan")
+	}
+}
+
+func TestTrashcanRestoreRouteHandledErrors(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	for tName, tData := range map[string]struct {
+		Method              string
+		QueryParams         queryParamMap
+		Body                string
+		Role                role.Role
+		ServiceError        error
+		ExpectedStatus      int
+		ExpectedSubMessages []string
+	}{
+		"viewer role": {
+			Method:              "POST",
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			Body:                `{"nodeIds": ["N:package:1"]}`,
+			Role:                role.Viewer,
+			ExpectedStatus:      http.StatusUnauthorized,
+			ExpectedSubMessages: []string{"unauthorized"}},
+		"wrong method": {
+			Method:              "GET",
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			Role:                role.Editor,
+			ExpectedStatus:      http.StatusMethodNotAllowed,
+			ExpectedSubMessages: []string{"method not allowed"}},
+		"missing dataset_id": {
+			Method:              "POST",
+			QueryParams:         queryParamMap{},
+			Body:                `{"nodeIds": ["N:package:1"]}`,
+			Role:                role.Editor,
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"dataset_id"}},
+		"malformed body": {
+			Method:              "POST",
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			Body:                `{"nodeIds": `,
+			Role:                role.Editor,
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"request body"}},
+		"empty node ids": {
+			Method:              "POST",
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			Body:                `{"nodeIds": []}`,
+			Role:                role.Editor,
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"nodeIds"}},
+		"dataset not found": {
+			Method:              "POST",
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			Body:                `{"nodeIds": ["N:package:1"]}`,
+			Role:                role.Editor,
+			ServiceError:        models.DatasetNotFoundError{Id: models.DatasetNodeId(datasetID)},
+			ExpectedStatus:      http.StatusNotFound,
+			ExpectedSubMessages: []string{"not found", datasetID}},
+	} {
+		req := newTestRequest(tData.Method,
+			"/trashcan/restore",
+			"restoreTrashcanRequestID",
+			tData.QueryParams,
+			tData.Body)
+		mockService := new(MockDatasetsService)
+		claims := authorizer.Claims{
+			DatasetClaim: &dataset.Claim{
+				Role:   tData.Role,
+				NodeId: datasetID,
+				IntId:  1234,
+			}}
+		if tData.ServiceError != nil {
+			mockService.OnRestorePackagesFail(datasetID, []string{"N:package:1"}, tData.ServiceError)
+		}
+		handler := NewHandler(req, &claims).WithService(mockService)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				for _, messageFragment := range tData.ExpectedSubMessages {
+					assert.Contains(t, resp.Body, messageFragment)
+				}
+			}
+		})
+	}
+}
+
+func TestTrashcanPurgeRoute(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	for tName, tData := range map[string]struct {
+		QueryParams         queryParamMap
+		Role                role.Role
+		ServiceError        error
+		ExpectedStatus      int
+		ExpectedSubMessages []string
+	}{
+		"whole trashcan": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			Role:                role.Manager,
+			ExpectedStatus:      http.StatusAccepted,
+			ExpectedSubMessages: []string{"packageCount", "12"}},
+		"folder": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "root_node_id": "N:collection:abcd"},
+			Role:                role.Owner,
+			ExpectedStatus:      http.StatusAccepted,
+			ExpectedSubMessages: []string{"packageCount", "12"}},
+		"editor role": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			Role:                role.Editor,
+			ExpectedStatus:      http.StatusUnauthorized,
+			ExpectedSubMessages: []string{"unauthorized"}},
+		"missing dataset_id": {
+			QueryParams:         queryParamMap{},
+			Role:                role.Manager,
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"dataset_id"}},
+		"package not a folder": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "root_node_id": "N:collection:abcd"},
+			Role:                role.Manager,
+			ServiceError:        models.FolderNotFoundError{NodeId: "N:collection:abcd", ActualType: packageType.CSV},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"not found", "N:collection:abcd"}},
+	} {
+		req := newTestRequest("DELETE",
+			"/trashcan",
+			"purgeTrashcanRequestID",
+			tData.QueryParams,
+			"")
+		mockService := new(MockDatasetsService)
+		claims := authorizer.Claims{
+			DatasetClaim: &dataset.Claim{
+				Role:   tData.Role,
+				NodeId: datasetID,
+				IntId:  1234,
+			}}
+		if tData.ExpectedStatus == http.StatusAccepted {
+			mockService.OnPurgeTrashcanReturn(datasetID, tData.QueryParams["root_node_id"], &models.TrashcanPurgeResult{PackageCount: 12})
+		} else if tData.ServiceError != nil {
+			mockService.OnPurgeTrashcanFail(datasetID, tData.QueryParams["root_node_id"], tData.ServiceError)
+		}
+		handler := NewHandler(req, &claims).WithService(mockService)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				for _, messageFragment := range tData.ExpectedSubMessages {
+					assert.Contains(t, resp.Body, messageFragment)
+				}
+			}
+		})
+	}
+}
+
+func TestTrashcanSummaryRoute(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	for tName, tData := range map[string]struct {
+		QueryParams         queryParamMap
+		ServiceError        error
+		ExpectedStatus      int
+		ExpectedSubMessages []string
+	}{
+		"whole trashcan": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			ExpectedStatus:      http.StatusOK,
+			ExpectedSubMessages: []string{`"packageCount":12`, `"totalSize":4096`, `"oldestDeletedAt":null`}},
+		"folder": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "root_node_id": "N:collection:abcd"},
+			ExpectedStatus:      http.StatusOK,
+			ExpectedSubMessages: []string{`"packageCount":12`}},
+		"missing dataset_id": {
+			QueryParams:         queryParamMap{},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"dataset_id"}},
+		"package not a folder": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "root_node_id": "N:collection:abcd"},
+			ServiceError:        models.FolderNotFoundError{NodeId: "N:collection:abcd", ActualType: packageType.CSV},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"not found", "N:collection:abcd"}},
+	} {
+		req := newTestRequest("GET",
+			"/trashcan/summary",
+			"trashcanSummaryRequestID",
+			tData.QueryParams,
+			"")
+		mockService := new(MockDatasetsService)
+		claims := authorizer.Claims{
+			DatasetClaim: &dataset.Claim{
+				Role:   role.Viewer,
+				NodeId: datasetID,
+				IntId:  1234,
+			}}
+		if tData.ExpectedStatus == http.StatusOK {
+			mockService.OnGetTrashcanSummaryReturn(datasetID, tData.QueryParams["root_node_id"], &models.TrashcanSummary{PackageCount: 12, FileCount: 3, TotalSize: 4096})
+		} else if tData.ServiceError != nil {
+			mockService.OnGetTrashcanSummaryFail(datasetID, tData.QueryParams["root_node_id"], tData.ServiceError)
+		}
+		handler := NewHandler(req, &claims).WithService(mockService)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				for _, messageFragment := range tData.ExpectedSubMessages {
+					assert.Contains(t, resp.Body, messageFragment)
+				}
+			}
+		})
+	}
+}
+
+func TestTrashcanExportRoute(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	for tName, tData := range map[string]struct {
+		QueryParams         queryParamMap
+		ServiceError        error
+		ExpectedStatus      int
+		ExpectedSubMessages []string
+	}{
+		"export": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			ExpectedStatus:      http.StatusOK,
+			ExpectedSubMessages: []string{`"url":"https://manifest-bucket/1234/trashcan/export.json"`, `"s3_key":"1234/trashcan/export.json"`, `"deleted_count":21`}},
+		"missing dataset_id": {
+			QueryParams:         queryParamMap{},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"dataset_id"}},
+		"dataset not found": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			ServiceError:        models.DatasetNotFoundError{OrgId: 7, Id: models.DatasetNodeId(datasetID)},
+			ExpectedStatus:      http.StatusNotFound,
+			ExpectedSubMessages: []string{"not found", datasetID}},
+	} {
+		req := newTestRequest("POST",
+			"/trashcan/export",
+			"trashcanExportRequestID",
+			tData.QueryParams,
+			"")
+		mockService := new(MockDatasetsService)
+		claims := authorizer.Claims{
+			DatasetClaim: &dataset.Claim{
+				Role:   role.Viewer,
+				NodeId: datasetID,
+				IntId:  1234,
+			}}
+		if tData.ExpectedStatus == http.StatusOK {
+			mockService.OnExportTrashcanReturn(datasetID, &models.TrashcanExportResult{
+				Url:          "https://manifest-bucket/1234/trashcan/export.json",
+				S3Bucket:     "manifest-bucket",
+				S3Key:        "1234/trashcan/export.json",
+				DeletedCount: 21,
+			})
+		} else if tData.ServiceError != nil {
+			mockService.OnExportTrashcanFail(datasetID, tData.ServiceError)
+		}
+		handler := NewHandler(req, &claims).WithService(mockService)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				for _, messageFragment := range tData.ExpectedSubMessages {
+					assert.Contains(t, resp.Body, messageFragment)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorResponseBody(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	claims := authorizer.Claims{
+		DatasetClaim: &dataset.Claim{
+			Role:   role.Viewer,
+			NodeId: datasetID,
+			IntId:  1234,
+		}}
+	for tName, tData := range map[string]struct {
+		Method          string
+		Path            string
+		QueryParams     queryParamMap
+		ServiceError    error
+		ExpectedStatus  int
+		ExpectedCode    models.ErrorCode
+		ExpectedMessage string
+	}{
+		"validation error": {
+			Method:          "GET",
+			Path:            "/trashcan",
+			QueryParams:     queryParamMap{},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedCode:    models.ErrorCodeValidation,
+			ExpectedMessage: "query param 'dataset_id' is required",
+		},
+		"dataset not found": {
+			Method:          "GET",
+			Path:            "/trashcan",
+			QueryParams:     queryParamMap{"dataset_id": datasetID},
+			ServiceError:    models.DatasetNotFoundError{OrgId: 2, Id: models.DatasetNodeId(datasetID)},
+			ExpectedStatus:  http.StatusNotFound,
+			ExpectedCode:    models.ErrorCodeDatasetNotFound,
+			ExpectedMessage: models.DatasetNotFoundError{OrgId: 2, Id: models.DatasetNodeId(datasetID)}.Error(),
+		},
+		"message with quotes": {
+			Method:          "GET",
+			Path:            "/trashcan",
+			QueryParams:     queryParamMap{"dataset_id": datasetID, "root_node_id": "N:collection:'\"quoted\"'"},
+			ServiceError:    models.FolderNotFoundError{OrgId: 2, NodeId: "N:collection:'\"quoted\"'", DatasetId: models.DatasetIntId(13), ActualType: -1},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedCode:    models.ErrorCodeFolderNotFound,
+			ExpectedMessage: models.FolderNotFoundError{OrgId: 2, NodeId: "N:collection:'\"quoted\"'", DatasetId: models.DatasetIntId(13), ActualType: -1}.Error(),
+		},
+		"unauthorized": {
+			Method:          "DELETE",
+			Path:            "/trashcan",
+			QueryParams:     queryParamMap{"dataset_id": datasetID},
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedCode:    models.ErrorCodeUnauthorized,
+			ExpectedMessage: "unauthorized",
+		},
+		"method not allowed": {
+			Method:          "PUT",
+			Path:            "/manifest",
+			QueryParams:     queryParamMap{"dataset_id": datasetID},
+			ExpectedStatus:  http.StatusMethodNotAllowed,
+			ExpectedCode:    models.ErrorCodeMethodNotAllowed,
+			ExpectedMessage: "method not allowed: PUT",
+		},
+		"resource not found": {
+			Method:          "GET",
+			Path:            "/unknown",
+			QueryParams:     queryParamMap{},
+			ExpectedStatus:  http.StatusNotFound,
+			ExpectedCode:    models.ErrorCodeResourceNotFound,
+			ExpectedMessage: "resource not found: /unknown",
+		},
+	} {
+		req := newTestRequest(tData.Method, tData.Path, "errorResponseRequestID", tData.QueryParams, "")
+		mockService := new(MockDatasetsService)
+		if tData.ServiceError != nil {
+			mockService.OnGetTrashcanPageFail(tData.QueryParams["dataset_id"], tData.QueryParams["root_node_id"], models.TrashcanFilter{}, models.DefaultTrashcanSort, models.Paging[models.TrashcanCursor]{Limit: DefaultLimit, Offset: DefaultOffset}, tData.ServiceError)
+		}
+		handler := NewHandler(req, &claims).WithService(mockService)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				assert.Equal(t, "application/json", resp.Headers["Content-Type"])
+				var errorResponse models.ErrorResponse
+				if assert.NoError(t, json.Unmarshal([]byte(resp.Body), &errorResponse)) {
+					assert.Equal(t, models.ErrorResponse{
+						Message:   tData.ExpectedMessage,
+						Code:      tData.ExpectedCode,
+						RequestId: "errorResponseRequestID",
+					}, errorResponse)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorResponseDetails(t *testing.T) {
+	req := newTestRequest("GET", "/trashcan", "detailsRequestID", queryParamMap{}, "")
+	handler := NewHandler(req, &authorizer.Claims{})
+	resp, err := handler.handleError(models.ValidationError{Message: "invalid", Details: map[string]string{"param": "limit"}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.JSONEq(t, `{"message": "invalid", "code": "VALIDATION_ERROR", "requestId": "detailsRequestID", "details": {"param": "limit"}}`, resp.Body)
+	}
+}
+
 func newTestRequest(method string, path string, requestID string, queryParams map[string]string, body string) *events.APIGatewayV2HTTPRequest {
 	request := events.APIGatewayV2HTTPRequest{
 		QueryStringParameters: queryParams,
@@ -159,6 +651,257 @@ func newTestRequest(method string, path string, requestID string, queryParams ma
 	return &request
 }
 
+func TestManifestRoute(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	for tName, tData := range map[string]struct {
+		QueryParams         queryParamMap
+		Options             models.ManifestOptions
+		ServiceResult       *models.ManifestResult
+		ServiceError        error
+		ExpectedStatus      int
+		ExpectedSubMessages []string
+	}{
+		"job started": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			ServiceResult:       &models.ManifestResult{JobId: "job-1234", Status: models.CREATING},
+			ExpectedStatus:      http.StatusAccepted,
+			ExpectedSubMessages: []string{"job-1234", string(models.CREATING)}},
+		"existing manifest": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			ServiceResult:       &models.ManifestResult{Status: models.READY, Url: "https://example.com/manifest.json"},
+			ExpectedStatus:      http.StatusOK,
+			ExpectedSubMessages: []string{string(models.READY), "https://example.com/manifest.json"}},
+		"forced": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "force": "true"},
+			Options:             models.ManifestOptions{Format: models.JSONManifest, Force: true},
+			ServiceResult:       &models.ManifestResult{JobId: "job-1234", Status: models.CREATING},
+			ExpectedStatus:      http.StatusAccepted,
+			ExpectedSubMessages: []string{"job-1234", string(models.CREATING)}},
+		"csv": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "format": "csv"},
+			Options:             models.ManifestOptions{Format: models.CSVManifest},
+			ServiceResult:       &models.ManifestResult{JobId: "job-1234", Status: models.CREATING, S3Key: "1234/1234_2023.csv"},
+			ExpectedStatus:      http.StatusAccepted,
+			ExpectedSubMessages: []string{"job-1234", ".csv"}},
+		"subtree": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "root_node_id": "N:collection:abc", "absolute_paths": "true"},
+			Options:             models.ManifestOptions{Format: models.JSONManifest, RootNodeId: "N:collection:abc", AbsolutePaths: true},
+			ServiceResult:       &models.ManifestResult{JobId: "job-1234", Status: models.CREATING},
+			ExpectedStatus:      http.StatusAccepted,
+			ExpectedSubMessages: []string{"job-1234"}},
+		"subtree root not a collection": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "root_node_id": "N:package:abc"},
+			Options:             models.ManifestOptions{Format: models.JSONManifest, RootNodeId: "N:package:abc"},
+			ServiceError:        models.FolderNotFoundError{NodeId: "N:package:abc", ActualType: packageType.CSV},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"N:package:abc"}},
+		"subtree root not found": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "root_node_id": "N:collection:abc"},
+			Options:             models.ManifestOptions{Format: models.JSONManifest, RootNodeId: "N:collection:abc"},
+			ServiceError:        models.PackageNotFoundError{Id: models.PackageNodeId("N:collection:abc")},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"N:collection:abc"}},
+		"include urls": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "include_urls": "true", "url_expiry": "600"},
+			Options:             models.ManifestOptions{Format: models.JSONManifest, IncludeUrls: true, UrlExpiry: 10 * time.Minute},
+			ServiceResult:       &models.ManifestResult{JobId: "job-1234", Status: models.CREATING},
+			ExpectedStatus:      http.StatusAccepted,
+			ExpectedSubMessages: []string{"job-1234"}},
+		"include urls default expiry": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "include_urls": "true"},
+			Options:             models.ManifestOptions{Format: models.JSONManifest, IncludeUrls: true, UrlExpiry: time.Hour},
+			ServiceResult:       &models.ManifestResult{JobId: "job-1234", Status: models.CREATING},
+			ExpectedStatus:      http.StatusAccepted,
+			ExpectedSubMessages: []string{"job-1234"}},
+		"url_expiry too long": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "include_urls": "true", "url_expiry": "43201"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"url_expiry"}},
+		"include urls in bagit": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "include_urls": "true", "format": "bagit"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"include_urls", "bagit"}},
+		"invalid absolute_paths": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "absolute_paths": "maybe"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"absolute_paths"}},
+		"invalid format": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "format": "xlsx"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"xlsx"}},
+		"invalid force": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "force": "maybe"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"maybe", "force"}},
+		"missing dataset_id": {
+			QueryParams:         queryParamMap{},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"dataset_id"}},
+		"dataset not found": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			ServiceError:        models.DatasetNotFoundError{Id: models.DatasetNodeId(datasetID)},
+			ExpectedStatus:      http.StatusNotFound,
+			ExpectedSubMessages: []string{"not found", datasetID}},
+	} {
+		req := newTestRequest("GET",
+			"/manifest",
+			"triggerManifestRequestID",
+			tData.QueryParams,
+			"")
+		if len(tData.Options.Format) == 0 {
+			tData.Options.Format = models.JSONManifest
+		}
+		mockService := new(MockDatasetsService)
+		claims := authorizer.Claims{
+			DatasetClaim: &dataset.Claim{
+				Role:   role.Viewer,
+				NodeId: datasetID,
+				IntId:  1234,
+			}}
+		if tData.ServiceResult != nil {
+			mockService.OnTriggerAsyncGetManifestReturn(datasetID, tData.Options, tData.ServiceResult)
+		} else if tData.ServiceError != nil {
+			mockService.OnTriggerAsyncGetManifestFail(datasetID, tData.Options, tData.ServiceError)
+		}
+		handler := NewHandler(req, &claims).WithService(mockService)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				for _, messageFragment := range tData.ExpectedSubMessages {
+					assert.Contains(t, resp.Body, messageFragment)
+				}
+			}
+		})
+	}
+}
+
+func TestManifestStatusRoute(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	for tName, tData := range map[string]struct {
+		QueryParams         queryParamMap
+		ServiceError        error
+		ExpectedStatus      int
+		ExpectedSubMessages []string
+	}{
+		"ready": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "job_id": "job-1234"},
+			ExpectedStatus:      http.StatusOK,
+			ExpectedSubMessages: []string{string(models.READY), "https://example.com/manifest.json"}},
+		"missing job_id": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"job_id"}},
+		"job not found": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "job_id": "job-1234"},
+			ServiceError:        models.ManifestJobNotFoundError{JobId: "job-1234", DatasetNodeId: datasetID},
+			ExpectedStatus:      http.StatusNotFound,
+			ExpectedSubMessages: []string{"not found", "job-1234"}},
+	} {
+		req := newTestRequest("GET",
+			"/manifest/status",
+			"manifestStatusRequestID",
+			tData.QueryParams,
+			"")
+		mockService := new(MockDatasetsService)
+		claims := authorizer.Claims{
+			DatasetClaim: &dataset.Claim{
+				Role:   role.Viewer,
+				NodeId: datasetID,
+				IntId:  1234,
+			}}
+		if tData.ExpectedStatus == http.StatusOK {
+			mockService.OnGetManifestJobStatusReturn(datasetID, "job-1234", &models.ManifestResult{JobId: "job-1234", Status: models.READY, Url: "https://example.com/manifest.json"})
+		} else if tData.ServiceError != nil {
+			mockService.OnGetManifestJobStatusFail(datasetID, "job-1234", tData.ServiceError)
+		}
+		handler := NewHandler(req, &claims).WithService(mockService)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				for _, messageFragment := range tData.ExpectedSubMessages {
+					assert.Contains(t, resp.Body, messageFragment)
+				}
+			}
+		})
+	}
+}
+
+func TestManifestDiffRoute(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	fromKey := "1234/1234_2023-05-17T10_04_32Z.json"
+	for tName, tData := range map[string]struct {
+		QueryParams         queryParamMap
+		To                  string
+		ServiceResult       *models.ManifestDiff
+		ServiceError        error
+		ExpectedStatus      int
+		ExpectedSubMessages []string
+	}{
+		"against live": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "from": fromKey},
+			ServiceResult:       &models.ManifestDiff{From: fromKey, To: models.LiveManifest, Removed: []models.ManifestDTO{{PackageNodeId: "N:package:1"}}},
+			ExpectedStatus:      http.StatusOK,
+			ExpectedSubMessages: []string{fromKey, `"to":"live"`, "N:package:1"}},
+		"two versions": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "from": fromKey, "to": "1234/1234_2023-05-18T10_04_32Z.json"},
+			To:                  "1234/1234_2023-05-18T10_04_32Z.json",
+			ServiceResult:       &models.ManifestDiff{From: fromKey, To: "1234/1234_2023-05-18T10_04_32Z.json"},
+			ExpectedStatus:      http.StatusOK,
+			ExpectedSubMessages: []string{"2023-05-18"}},
+		"manifest not found": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "from": fromKey},
+			ServiceError:        models.ManifestNotFoundError{S3Key: fromKey, DatasetNodeId: datasetID},
+			ExpectedStatus:      http.StatusNotFound,
+			ExpectedSubMessages: []string{"not found", fromKey}},
+		"dataset not found": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID, "from": fromKey},
+			ServiceError:        models.DatasetNotFoundError{Id: models.DatasetNodeId(datasetID)},
+			ExpectedStatus:      http.StatusNotFound,
+			ExpectedSubMessages: []string{"not found", datasetID}},
+		"missing from": {
+			QueryParams:         queryParamMap{"dataset_id": datasetID},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"from"}},
+		"missing dataset_id": {
+			QueryParams:         queryParamMap{"from": fromKey},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"dataset_id"}},
+	} {
+		req := newTestRequest("GET",
+			"/manifest/diff",
+			"manifestDiffRequestID",
+			tData.QueryParams,
+			"")
+		mockService := new(MockDatasetsService)
+		claims := authorizer.Claims{
+			DatasetClaim: &dataset.Claim{
+				Role:   role.Viewer,
+				NodeId: datasetID,
+				IntId:  1234,
+			}}
+		if tData.ServiceResult != nil {
+			mockService.OnGetManifestDiffReturn(datasetID, fromKey, tData.To, tData.ServiceResult)
+		} else if tData.ServiceError != nil {
+			mockService.OnGetManifestDiffFail(datasetID, fromKey, tData.To, tData.ServiceError)
+		}
+		handler := NewHandler(req, &claims).WithService(mockService)
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				for _, messageFragment := range tData.ExpectedSubMessages {
+					assert.Contains(t, resp.Body, messageFragment)
+				}
+			}
+		})
+	}
+}
+
 type MockDatasetsService struct {
 	mock.Mock
 }
@@ -170,13 +913,39 @@ func (m *MockDatasetsService) GetDataset(ctx context.Context, datasetId string)
 	return args.Get(0).(*pgdb.Dataset), args.Error(1)
 }
 
-func (m *MockDatasetsService) GetTrashcanPage(ctx context.Context, datasetID string, rootNodeId string, limit int, offset int) (*models.TrashcanPage, error) {
-	args := m.Called(ctx, datasetID, rootNodeId, limit, offset)
+func (m *MockDatasetsService) GetTrashcanPage(ctx context.Context, datasetID string, rootNodeId string, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor]) (*models.TrashcanPage, error) {
+	args := m.Called(ctx, datasetID, rootNodeId, filter, sort, paging)
 	return args.Get(0).(*models.TrashcanPage), args.Error(1)
 }
 
-func (m *MockDatasetsService) GetManifest(ctx context.Context, datasetNodeId string) (*models.ManifestResult, error) {
-	return nil, nil
+func (m *MockDatasetsService) GetPackagesPage(ctx context.Context, datasetNodeId string, rootNodeId string, sort models.PackageSort, limit int, offset int) (*models.PackagesPage, error) {
+	args := m.Called(ctx, datasetNodeId, rootNodeId, sort, limit, offset)
+	return args.Get(0).(*models.PackagesPage), args.Error(1)
+}
+
+func (m *MockDatasetsService) TriggerAsyncGetManifest(ctx context.Context, datasetNodeId string, options models.ManifestOptions) (*models.ManifestResult, error) {
+	args := m.Called(ctx, datasetNodeId, options)
+	return args.Get(0).(*models.ManifestResult), args.Error(1)
+}
+
+func (m *MockDatasetsService) GetManifestJobStatus(ctx context.Context, datasetNodeId string, jobId string) (*models.ManifestResult, error) {
+	args := m.Called(ctx, datasetNodeId, jobId)
+	return args.Get(0).(*models.ManifestResult), args.Error(1)
+}
+
+func (m *MockDatasetsService) GetManifestDiff(ctx context.Context, datasetNodeId string, fromS3Key string, toS3Key string) (*models.ManifestDiff, error) {
+	args := m.Called(ctx, datasetNodeId, fromS3Key, toS3Key)
+	return args.Get(0).(*models.ManifestDiff), args.Error(1)
+}
+
+func (m *MockDatasetsService) ProcessManifestJob(ctx context.Context, input models.ManifestWorkerInput) error {
+	args := m.Called(ctx, input)
+	return args.Error(0)
+}
+
+func (m *MockDatasetsService) FailManifestJob(ctx context.Context, jobId string, reason string) error {
+	args := m.Called(ctx, jobId, reason)
+	return args.Error(0)
 }
 
 func (m *MockDatasetsService) GetSharedDatasetsPage(ctx context.Context, limit int, offset int) (*models.SharedDatasetsPage, error) {
@@ -184,14 +953,47 @@ func (m *MockDatasetsService) GetSharedDatasetsPage(ctx context.Context, limit i
 	return args.Get(0).(*models.SharedDatasetsPage), args.Error(1)
 }
 
+func (m *MockDatasetsService) RestorePackages(ctx context.Context, datasetNodeId string, nodeIds []string) (*models.RestoreResponse, error) {
+	args := m.Called(ctx, datasetNodeId, nodeIds)
+	return args.Get(0).(*models.RestoreResponse), args.Error(1)
+}
+
+func (m *MockDatasetsService) PurgeTrashcan(ctx context.Context, datasetNodeId string, rootNodeId string) (*models.TrashcanPurgeResult, error) {
+	args := m.Called(ctx, datasetNodeId, rootNodeId)
+	return args.Get(0).(*models.TrashcanPurgeResult), args.Error(1)
+}
+
+func (m *MockDatasetsService) GetDatasetStats(ctx context.Context, datasetNodeId string) (*models.DatasetStats, error) {
+	args := m.Called(ctx, datasetNodeId)
+	return args.Get(0).(*models.DatasetStats), args.Error(1)
+}
+
+func (m *MockDatasetsService) GetTrashcanSummary(ctx context.Context, datasetNodeId string, rootNodeId string) (*models.TrashcanSummary, error) {
+	args := m.Called(ctx, datasetNodeId, rootNodeId)
+	return args.Get(0).(*models.TrashcanSummary), args.Error(1)
+}
+
+func (m *MockDatasetsService) ExportTrashcan(ctx context.Context, datasetNodeId string) (*models.TrashcanExportResult, error) {
+	args := m.Called(ctx, datasetNodeId)
+	return args.Get(0).(*models.TrashcanExportResult), args.Error(1)
+}
+
 // Type safe convenience methods for setting up expectations
 
-func (m *MockDatasetsService) OnGetTrashcanPageReturn(datasetID string, rootNodeId string, limit int, offset int, returnedPage *models.TrashcanPage) {
-	m.On("GetTrashcanPage", mock.Anything, datasetID, rootNodeId, limit, offset).Return(returnedPage, nil)
+func (m *MockDatasetsService) OnGetTrashcanPageReturn(datasetID string, rootNodeId string, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor], returnedPage *models.TrashcanPage) {
+	m.On("GetTrashcanPage", mock.Anything, datasetID, rootNodeId, filter, sort, paging).Return(returnedPage, nil)
 }
 
-func (m *MockDatasetsService) OnGetTrashcanPageFail(datasetID string, rootNodeId string, limit int, offset int, returnedError error) {
-	m.On("GetTrashcanPage", mock.Anything, datasetID, rootNodeId, limit, offset).Return(&models.TrashcanPage{}, returnedError)
+func (m *MockDatasetsService) OnGetTrashcanPageFail(datasetID string, rootNodeId string, filter models.TrashcanFilter, sort models.TrashcanSort, paging models.Paging[models.TrashcanCursor], returnedError error) {
+	m.On("GetTrashcanPage", mock.Anything, datasetID, rootNodeId, filter, sort, paging).Return(&models.TrashcanPage{}, returnedError)
+}
+
+func (m *MockDatasetsService) OnGetPackagesPageReturn(datasetID string, rootNodeId string, sort models.PackageSort, limit int, offset int, returnedPage *models.PackagesPage) {
+	m.On("GetPackagesPage", mock.Anything, datasetID, rootNodeId, sort, limit, offset).Return(returnedPage, nil)
+}
+
+func (m *MockDatasetsService) OnGetPackagesPageFail(datasetID string, rootNodeId string, sort models.PackageSort, limit int, offset int, returnedError error) {
+	m.On("GetPackagesPage", mock.Anything, datasetID, rootNodeId, sort, limit, offset).Return(&models.PackagesPage{}, returnedError)
 }
 
 func (m *MockDatasetsService) OnGetDatasetReturn(datasetId string, returnedDataset *pgdb.Dataset) {
@@ -200,3 +1002,67 @@ func (m *MockDatasetsService) OnGetDatasetReturn(datasetId string, returnedDatas
 func (m *MockDatasetsService) OnGetDatasetFail(datasetId string, returnedError error) {
 	m.On("GetDataset", mock.Anything, datasetId).Return(&pgdb.Dataset{}, returnedError)
 }
+
+func (m *MockDatasetsService) OnGetDatasetStatsReturn(datasetId string, returnedStats *models.DatasetStats) {
+	m.On("GetDatasetStats", mock.Anything, datasetId).Return(returnedStats, nil)
+}
+
+func (m *MockDatasetsService) OnGetDatasetStatsFail(datasetId string, returnedError error) {
+	m.On("GetDatasetStats", mock.Anything, datasetId).Return(&models.DatasetStats{}, returnedError)
+}
+
+func (m *MockDatasetsService) OnRestorePackagesReturn(datasetId string, nodeIds []string, returnedResponse *models.RestoreResponse) {
+	m.On("RestorePackages", mock.Anything, datasetId, nodeIds).Return(returnedResponse, nil)
+}
+
+func (m *MockDatasetsService) OnRestorePackagesFail(datasetId string, nodeIds []string, returnedError error) {
+	m.On("RestorePackages", mock.Anything, datasetId, nodeIds).Return(&models.RestoreResponse{}, returnedError)
+}
+
+func (m *MockDatasetsService) OnGetTrashcanSummaryReturn(datasetId string, rootNodeId string, returnedSummary *models.TrashcanSummary) {
+	m.On("GetTrashcanSummary", mock.Anything, datasetId, rootNodeId).Return(returnedSummary, nil)
+}
+
+func (m *MockDatasetsService) OnGetTrashcanSummaryFail(datasetId string, rootNodeId string, returnedError error) {
+	m.On("GetTrashcanSummary", mock.Anything, datasetId, rootNodeId).Return(&models.TrashcanSummary{}, returnedError)
+}
+
+func (m *MockDatasetsService) OnExportTrashcanReturn(datasetId string, returnedResult *models.TrashcanExportResult) {
+	m.On("ExportTrashcan", mock.Anything, datasetId).Return(returnedResult, nil)
+}
+
+func (m *MockDatasetsService) OnExportTrashcanFail(datasetId string, returnedError error) {
+	m.On("ExportTrashcan", mock.Anything, datasetId).Return(&models.TrashcanExportResult{}, returnedError)
+}
+
+func (m *MockDatasetsService) OnPurgeTrashcanReturn(datasetId string, rootNodeId string, returnedResult *models.TrashcanPurgeResult) {
+	m.On("PurgeTrashcan", mock.Anything, datasetId, rootNodeId).Return(returnedResult, nil)
+}
+
+func (m *MockDatasetsService) OnPurgeTrashcanFail(datasetId string, rootNodeId string, returnedError error) {
+	m.On("PurgeTrashcan", mock.Anything, datasetId, rootNodeId).Return(&models.TrashcanPurgeResult{}, returnedError)
+}
+
+func (m *MockDatasetsService) OnTriggerAsyncGetManifestReturn(datasetId string, options models.ManifestOptions, returnedResult *models.ManifestResult) {
+	m.On("TriggerAsyncGetManifest", mock.Anything, datasetId, options).Return(returnedResult, nil)
+}
+
+func (m *MockDatasetsService) OnTriggerAsyncGetManifestFail(datasetId string, options models.ManifestOptions, returnedError error) {
+	m.On("TriggerAsyncGetManifest", mock.Anything, datasetId, options).Return(&models.ManifestResult{}, returnedError)
+}
+
+func (m *MockDatasetsService) OnGetManifestDiffReturn(datasetId string, from string, to string, returnedDiff *models.ManifestDiff) {
+	m.On("GetManifestDiff", mock.Anything, datasetId, from, to).Return(returnedDiff, nil)
+}
+
+func (m *MockDatasetsService) OnGetManifestDiffFail(datasetId string, from string, to string, returnedError error) {
+	m.On("GetManifestDiff", mock.Anything, datasetId, from, to).Return(&models.ManifestDiff{}, returnedError)
+}
+
+func (m *MockDatasetsService) OnGetManifestJobStatusReturn(datasetId string, jobId string, returnedResult *models.ManifestResult) {
+	m.On("GetManifestJobStatus", mock.Anything, datasetId, jobId).Return(returnedResult, nil)
+}
+
+func (m *MockDatasetsService) OnGetManifestJobStatusFail(datasetId string, jobId string, returnedError error) {
+	m.On("GetManifestJobStatus", mock.Anything, datasetId, jobId).Return(&models.ManifestResult{}, returnedError)
+}
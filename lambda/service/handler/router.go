@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pennsieve/datasets-service/api/models"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// routeFunc handles a request matched by a route. The signature matches method expressions such as
+// (*RequestHandler).getTrashcan.
+type routeFunc func(h *RequestHandler, ctx context.Context) (*events.APIGatewayV2HTTPResponse, error)
+
+// middleware wraps the routeFunc of a route, to check the claims or construct a service before it is called.
+type middleware func(next routeFunc) routeFunc
+
+type route struct {
+	method   string
+	segments []string
+	// patterns holds the pattern of each parameter segment that has one, by the index of the segment
+	patterns map[int]*regexp.Regexp
+	handle   routeFunc
+}
+
+// router matches requests by method and path template. Templates are paths whose segments may be parameters
+// in braces, as in /datasets/{datasetId}/packages/{packageId}. A parameter may be followed by a colon and a
+// regular expression that its whole value must match, as in /datasets/{datasetId:N:dataset:.+}; a path that only
+// fits a template with a value the pattern rejects does not match it at all.
+type router struct {
+	routes []route
+}
+
+// add registers handle for requests with the method and a path matching template. The middleware is applied
+// in order, so the first one runs first.
+func (r *router) add(method string, template string, handle routeFunc, middleware ...middleware) {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handle = middleware[i](handle)
+	}
+	segments := pathSegments(template)
+	patterns := map[int]*regexp.Regexp{}
+	for i, segment := range segments {
+		if _, pattern, isParam := pathParam(segment); isParam && len(pattern) > 0 {
+			patterns[i] = regexp.MustCompile("^(?:" + pattern + ")$")
+		}
+	}
+	r.routes = append(r.routes, route{method: method, segments: segments, patterns: patterns, handle: handle})
+}
+
+// match returns the route for the method and path, and the values of its path parameters. It returns a
+// models.MethodNotAllowedError listing the allowed methods if only the path matches, or a
+// models.ResourceNotFoundError if nothing does. Literal segments take precedence over parameters, so that
+// /manifest/status is not matched by /manifest/{id}.
+func (r *router) match(method string, path string) (route, map[string]string, error) {
+	requestSegments := pathSegments(path)
+	var best *route
+	var bestParams map[string]string
+	bestLiterals := -1
+	allowed := map[string]bool{}
+	for i := range r.routes {
+		candidate := &r.routes[i]
+		params, literals, ok := candidate.matchPath(requestSegments)
+		if !ok {
+			continue
+		}
+		if candidate.method != method {
+			allowed[candidate.method] = true
+			continue
+		}
+		if literals > bestLiterals {
+			best, bestParams, bestLiterals = candidate, params, literals
+		}
+	}
+	if best != nil {
+		return *best, bestParams, nil
+	}
+	if len(allowed) > 0 {
+		methods := make([]string, 0, len(allowed))
+		for m := range allowed {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		return route{}, nil, models.MethodNotAllowedError{Method: method, Allowed: methods}
+	}
+	return route{}, nil, models.ResourceNotFoundError{Path: path}
+}
+
+func (r *route) matchPath(requestSegments []string) (params map[string]string, literals int, ok bool) {
+	if len(requestSegments) != len(r.segments) {
+		return nil, 0, false
+	}
+	for i, segment := range r.segments {
+		if name, _, isParam := pathParam(segment); isParam {
+			value, err := url.PathUnescape(requestSegments[i])
+			if err != nil || len(value) == 0 {
+				return nil, 0, false
+			}
+			if pattern, ok := r.patterns[i]; ok && !pattern.MatchString(value) {
+				return nil, 0, false
+			}
+			if params == nil {
+				params = map[string]string{}
+			}
+			params[name] = value
+		} else if segment == requestSegments[i] {
+			literals++
+		} else {
+			return nil, 0, false
+		}
+	}
+	return params, literals, true
+}
+
+func (r *router) handle(h *RequestHandler, ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	matched, params, err := r.match(h.method, h.path)
+	if err != nil {
+		return h.handleError(err)
+	}
+	h.pathParams = params
+	return matched.handle(h, ctx)
+}
+
+func pathSegments(path string) []string {
+	trimmed := strings.Trim(path, "/")
+	if len(trimmed) == 0 {
+		return nil
+	}
+	return strings.Split(trimmed, "/")
+}
+
+// pathParam returns the name of the parameter of a template segment, and its pattern if it has one.
+func pathParam(segment string) (name string, pattern string, ok bool) {
+	if len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		name, pattern, _ = strings.Cut(segment[1:len(segment)-1], ":")
+		return name, pattern, true
+	}
+	return "", "", false
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pennsieve/datasets-service/api/models"
+	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/dataset"
+	"github.com/pennsieve/pennsieve-go-core/pkg/models/role"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// namedRoute returns a routeFunc that responds with name and the path parameters, so tests can tell which route matched.
+func namedRoute(name string) routeFunc {
+	return func(h *RequestHandler, ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+		body := name
+		for _, param := range []string{"datasetId", "packageId", "id"} {
+			if value, ok := h.pathParams[param]; ok {
+				body += " " + param + "=" + value
+			}
+		}
+		return buildResponseFromString(body, http.StatusOK), nil
+	}
+}
+
+func newTestRouter() *router {
+	r := &router{}
+	r.add("GET", "/datasets/{datasetId}", namedRoute("get dataset"))
+	r.add("GET", "/datasets/{datasetId}/packages/{packageId}", namedRoute("get package"))
+	r.add("DELETE", "/datasets/{datasetId}/packages/{packageId}", namedRoute("delete package"))
+	r.add("GET", "/manifest/{id}", namedRoute("get manifest by id"))
+	r.add("GET", "/manifest/status", namedRoute("get manifest status"))
+	r.add("POST", "/trashcan/restore", namedRoute("restore"))
+	r.add("GET", "/items/{id:(?i)N:item:.+}", namedRoute("get item"))
+	return r
+}
+
+func TestRouterMatch(t *testing.T) {
+	for tName, tData := range map[string]struct {
+		Method       string
+		Path         string
+		ExpectedBody string
+	}{
+		"literal path":              {"POST", "/trashcan/restore", "restore"},
+		"trailing slash":            {"POST", "/trashcan/restore/", "restore"},
+		"one param":                 {"GET", "/datasets/N:dataset:1", "get dataset datasetId=N:dataset:1"},
+		"two params":                {"GET", "/datasets/N:dataset:1/packages/N:package:2", "get package datasetId=N:dataset:1 packageId=N:package:2"},
+		"method selects route":      {"DELETE", "/datasets/N:dataset:1/packages/N:package:2", "delete package datasetId=N:dataset:1 packageId=N:package:2"},
+		"escaped param":             {"GET", "/datasets/N%3Adataset%3A1", "get dataset datasetId=N:dataset:1"},
+		"literal before param":      {"GET", "/manifest/status", "get manifest status"},
+		"param when no literal hit": {"GET", "/manifest/1234", "get manifest by id id=1234"},
+		"param pattern":             {"GET", "/items/n:item:1", "get item id=n:item:1"},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			req := newTestRequest(tData.Method, tData.Path, "routerRequestID", nil, "")
+			resp, err := newTestRouter().handle(NewHandler(req, &authorizer.Claims{}), context.Background())
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.Equal(t, tData.ExpectedBody, resp.Body)
+			}
+		})
+	}
+}
+
+func TestRouterNoMatch(t *testing.T) {
+	for tName, tData := range map[string]struct {
+		Method         string
+		Path           string
+		ExpectedStatus int
+		ExpectedCode   models.ErrorCode
+		ExpectedAllow  string
+	}{
+		"unknown path":          {"GET", "/unknown", http.StatusNotFound, models.ErrorCodeResourceNotFound, ""},
+		"too many segments":     {"GET", "/datasets/N:dataset:1/packages", http.StatusNotFound, models.ErrorCodeResourceNotFound, ""},
+		"empty param":           {"GET", "/datasets//packages/N:package:2", http.StatusNotFound, models.ErrorCodeResourceNotFound, ""},
+		"method not allowed":    {"PUT", "/datasets/N:dataset:1/packages/N:package:2", http.StatusMethodNotAllowed, models.ErrorCodeMethodNotAllowed, "DELETE, GET"},
+		"single allowed method": {"GET", "/trashcan/restore", http.StatusMethodNotAllowed, models.ErrorCodeMethodNotAllowed, "POST"},
+		"param pattern rejects": {"GET", "/items/1", http.StatusNotFound, models.ErrorCodeResourceNotFound, ""},
+		"pattern is anchored":   {"GET", "/items/x-N:item:1", http.StatusNotFound, models.ErrorCodeResourceNotFound, ""},
+		"rejected param method": {"POST", "/items/1", http.StatusNotFound, models.ErrorCodeResourceNotFound, ""},
+		"pattern method":        {"POST", "/items/N:item:1", http.StatusMethodNotAllowed, models.ErrorCodeMethodNotAllowed, "GET"},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			req := newTestRequest(tData.Method, tData.Path, "routerRequestID", nil, "")
+			resp, err := newTestRouter().handle(NewHandler(req, &authorizer.Claims{}), context.Background())
+			if assert.NoError(t, err) {
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+				assert.Contains(t, resp.Body, string(tData.ExpectedCode))
+				assert.Equal(t, tData.ExpectedAllow, resp.Headers["Allow"])
+			}
+		})
+	}
+}
+
+func TestRoutesUnknownPath(t *testing.T) {
+	for tName, tData := range map[string]struct {
+		Method         string
+		Path           string
+		ExpectedStatus int
+	}{
+		"unknown GET":          {"GET", "/unknown", http.StatusNotFound},
+		"unknown POST":         {"POST", "/unknown", http.StatusNotFound},
+		"unknown DELETE":       {"DELETE", "/unknown/stats", http.StatusNotFound},
+		"dataset wrong method": {"POST", "/N:dataset:1234", http.StatusMethodNotAllowed},
+		"export is not a GET":  {"GET", "/trashcan/export", http.StatusMethodNotAllowed},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			req := newTestRequest(tData.Method, tData.Path, "routerRequestID", nil, "")
+			resp, err := NewHandler(req, &authorizer.Claims{}).handle(context.Background())
+			if assert.NoError(t, err) {
+				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRouterMiddlewareOrder(t *testing.T) {
+	var calls []string
+	tracing := func(name string) middleware {
+		return func(next routeFunc) routeFunc {
+			return func(h *RequestHandler, ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+				calls = append(calls, name)
+				return next(h, ctx)
+			}
+		}
+	}
+	r := &router{}
+	r.add("GET", "/trashcan", namedRoute("trashcan"), tracing("first"), tracing("second"))
+
+	req := newTestRequest("GET", "/trashcan", "routerRequestID", nil, "")
+	resp, err := r.handle(NewHandler(req, &authorizer.Claims{}), context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, "trashcan", resp.Body)
+		assert.Equal(t, []string{"first", "second"}, calls)
+	}
+}
+
+func TestRouteAuthorization(t *testing.T) {
+	datasetID := "N:Dataset:1234"
+	for tName, tData := range map[string]struct {
+		Method string
+		Path   string
+		Claims *authorizer.Claims
+	}{
+		"no claims":               {"GET", "/trashcan", nil},
+		"no dataset claim":        {"GET", "/manifest", &authorizer.Claims{}},
+		"role without permission": {"POST", "/trashcan/restore", &authorizer.Claims{DatasetClaim: &dataset.Claim{Role: role.Viewer, NodeId: datasetID}}},
+		"role below manager":      {"DELETE", "/trashcan", &authorizer.Claims{DatasetClaim: &dataset.Claim{Role: role.Editor, NodeId: datasetID}}},
+		"no user claim":           {"GET", "/shared-datasets", &authorizer.Claims{DatasetClaim: &dataset.Claim{Role: role.Owner, NodeId: datasetID}}},
+	} {
+		t.Run(tName, func(t *testing.T) {
+			req := newTestRequest(tData.Method, tData.Path, "authRequestID", map[string]string{"dataset_id": datasetID}, "")
+			mockService := new(MockDatasetsService)
+			handler := NewHandler(req, tData.Claims).WithService(mockService)
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+				assert.Contains(t, resp.Body, string(models.ErrorCodeUnauthorized))
+				mockService.AssertExpectations(t)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/pennsieve/datasets-service/api/models"
 	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
@@ -16,17 +17,17 @@ type MockCrossWorkspaceDatasetsService struct {
 	mock.Mock
 }
 
-func (m *MockCrossWorkspaceDatasetsService) GetSharedDatasetsPage(ctx context.Context, userId int, limit int, offset int) (*models.SharedDatasetsPage, error) {
-	args := m.Called(ctx, userId, limit, offset)
+func (m *MockCrossWorkspaceDatasetsService) GetSharedDatasetsPage(ctx context.Context, userId int, paging models.Paging[models.SharedDatasetsCursor]) (*models.SharedDatasetsPage, error) {
+	args := m.Called(ctx, userId, paging)
 	return args.Get(0).(*models.SharedDatasetsPage), args.Error(1)
 }
 
-func (m *MockCrossWorkspaceDatasetsService) OnGetSharedDatasetsPageReturn(userId int, limit int, offset int, returnedPage *models.SharedDatasetsPage) {
-	m.On("GetSharedDatasetsPage", mock.Anything, userId, limit, offset).Return(returnedPage, nil)
+func (m *MockCrossWorkspaceDatasetsService) OnGetSharedDatasetsPageReturn(userId int, paging models.Paging[models.SharedDatasetsCursor], returnedPage *models.SharedDatasetsPage) {
+	m.On("GetSharedDatasetsPage", mock.Anything, userId, paging).Return(returnedPage, nil)
 }
 
-func (m *MockCrossWorkspaceDatasetsService) OnGetSharedDatasetsPageFail(userId int, limit int, offset int, returnedError error) {
-	m.On("GetSharedDatasetsPage", mock.Anything, userId, limit, offset).Return(&models.SharedDatasetsPage{}, returnedError)
+func (m *MockCrossWorkspaceDatasetsService) OnGetSharedDatasetsPageFail(userId int, paging models.Paging[models.SharedDatasetsCursor], returnedError error) {
+	m.On("GetSharedDatasetsPage", mock.Anything, userId, paging).Return(&models.SharedDatasetsPage{}, returnedError)
 }
 
 func TestSharedDatasetsRoute(t *testing.T) {
@@ -51,10 +52,11 @@ func TestSharedDatasetsRoute(t *testing.T) {
 
 		expectedLimit := expectedQueryParams.expectedLimit(t)
 		expectedOffset := expectedQueryParams.expectedOffset(t)
+		totalCount := 2
 		expectedPage := &models.SharedDatasetsPage{
 			Limit:      expectedLimit,
 			Offset:     expectedOffset,
-			TotalCount: 2,
+			TotalCount: &totalCount,
 			Datasets: []models.SharedDatasetItem{
 				{
 					Content: models.SharedDatasetContent{
@@ -72,14 +74,13 @@ func TestSharedDatasetsRoute(t *testing.T) {
 				},
 			},
 		}
-		mockService.OnGetSharedDatasetsPageReturn(expectedUserId, expectedLimit, expectedOffset, expectedPage)
+		mockService.OnGetSharedDatasetsPageReturn(expectedUserId, models.Paging[models.SharedDatasetsCursor]{Limit: expectedLimit, Offset: expectedOffset}, expectedPage)
 		
 		handler := NewHandler(req, &claims)
-		sharedHandler := &SharedDatasetsHandler{RequestHandler: *handler}
-		sharedHandler.crossWorkspaceDatasetsService = mockService
+		handler.crossWorkspaceDatasetsService = mockService
 		
 		t.Run(tName, func(t *testing.T) {
-			resp, err := sharedHandler.handle(context.Background())
+			resp, err := handler.handle(context.Background())
 			if assert.NoError(t, err) {
 				mockService.AssertExpectations(t)
 				assert.Equal(t, http.StatusOK, resp.StatusCode)
@@ -90,6 +91,40 @@ func TestSharedDatasetsRoute(t *testing.T) {
 	}
 }
 
+func TestSharedDatasetsRouteCursor(t *testing.T) {
+	userId := 123
+	cursor := models.SharedDatasetsCursor{UpdatedAt: time.Date(2023, 1, 2, 13, 0, 0, 0, time.UTC), Name: "Beta Dataset 2", NodeId: "N:dataset:beta2"}
+	for tName, tData := range map[string]struct {
+		QueryParams    queryParamMap
+		ExpectedPaging models.Paging[models.SharedDatasetsCursor]
+	}{
+		"with cursor": {
+			queryParamMap{"limit": "20", "cursor": models.EncodeCursor(cursor)},
+			models.Paging[models.SharedDatasetsCursor]{Limit: 20, After: &cursor}},
+		"without total": {
+			queryParamMap{"include_total": "false"},
+			models.Paging[models.SharedDatasetsCursor]{Limit: DefaultLimit, SkipTotal: true}},
+	} {
+		req := newTestRequest("GET", "/shared-datasets", "getSharedDatasetsRequestID", tData.QueryParams, "")
+		mockService := new(MockCrossWorkspaceDatasetsService)
+		claims := authorizer.Claims{UserClaim: &user.Claim{Id: int64(userId)}}
+		mockService.OnGetSharedDatasetsPageReturn(userId, tData.ExpectedPaging, &models.SharedDatasetsPage{Limit: tData.ExpectedPaging.Limit, NextCursor: "next"})
+
+		handler := NewHandler(req, &claims)
+		handler.crossWorkspaceDatasetsService = mockService
+
+		t.Run(tName, func(t *testing.T) {
+			resp, err := handler.handle(context.Background())
+			if assert.NoError(t, err) {
+				mockService.AssertExpectations(t)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.Contains(t, resp.Body, `"nextCursor":"next"`)
+				assert.NotContains(t, resp.Body, "totalCount")
+			}
+		})
+	}
+}
+
 func TestSharedDatasetsRouteUnauthorized(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -111,10 +146,9 @@ func TestSharedDatasetsRouteUnauthorized(t *testing.T) {
 			mockService := new(MockCrossWorkspaceDatasetsService)
 			
 			handler := NewHandler(req, tt.claims)
-			sharedHandler := &SharedDatasetsHandler{RequestHandler: *handler}
-			sharedHandler.crossWorkspaceDatasetsService = mockService
+			handler.crossWorkspaceDatasetsService = mockService
 			
-			resp, err := sharedHandler.handle(context.Background())
+			resp, err := handler.handle(context.Background())
 			assert.NoError(t, err)
 			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
 			assert.Contains(t, resp.Body, "unauthorized")
@@ -158,6 +192,21 @@ func TestSharedDatasetsRouteHandledErrors(t *testing.T) {
 			ExpectedStatus:      http.StatusBadRequest,
 			ExpectedSubMessages: []string{"strconv.Atoi", "xyz"},
 		},
+		"with cursor and offset": {
+			QueryParams:         queryParamMap{"offset": "10", "cursor": models.EncodeCursor(models.SharedDatasetsCursor{Name: "a"})},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"cursor", "offset", "cannot be combined"},
+		},
+		"with invalid cursor": {
+			QueryParams:         queryParamMap{"cursor": "not-a-cursor"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"invalid cursor", "not-a-cursor"},
+		},
+		"with invalid include_total": {
+			QueryParams:         queryParamMap{"include_total": "maybe"},
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedSubMessages: []string{"maybe", "include_total"},
+		},
 	} {
 		req := newTestRequest("GET",
 			"/shared-datasets",
@@ -175,18 +224,16 @@ func TestSharedDatasetsRouteHandledErrors(t *testing.T) {
 		if tData.ServiceError != nil {
 			mockService.OnGetSharedDatasetsPageFail(
 				userId, 
-				tData.QueryParams.expectedLimit(t), 
-				tData.QueryParams.expectedOffset(t),
+				models.Paging[models.SharedDatasetsCursor]{Limit: tData.QueryParams.expectedLimit(t), Offset: tData.QueryParams.expectedOffset(t)},
 				tData.ServiceError,
 			)
 		}
 
 		handler := NewHandler(req, &claims)
-		sharedHandler := &SharedDatasetsHandler{RequestHandler: *handler}
-		sharedHandler.crossWorkspaceDatasetsService = mockService
+		handler.crossWorkspaceDatasetsService = mockService
 		
 		t.Run(tName, func(t *testing.T) {
-			resp, err := sharedHandler.handle(context.Background())
+			resp, err := handler.handle(context.Background())
 			if assert.NoError(t, err) {
 				assert.Equal(t, tData.ExpectedStatus, resp.StatusCode)
 				for _, messageFragment := range tData.ExpectedSubMessages {
@@ -207,13 +254,13 @@ func TestSharedDatasetsRouteMethodNotAllowed(t *testing.T) {
 		}}
 
 	handler := NewHandler(req, &claims)
-	sharedHandler := &SharedDatasetsHandler{RequestHandler: *handler}
-	sharedHandler.crossWorkspaceDatasetsService = mockService
+	handler.crossWorkspaceDatasetsService = mockService
 	
-	resp, err := sharedHandler.handle(context.Background())
+	resp, err := handler.handle(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
 	assert.Contains(t, resp.Body, "method not allowed")
+	assert.Equal(t, "GET", resp.Headers["Allow"])
 	
 	// Should not call service for unsupported method
 	mockService.AssertNotCalled(t, "GetSharedDatasetsPage")
@@ -228,10 +275,10 @@ func TestSharedDatasetsRouteNoCrossWorkspaceService(t *testing.T) {
 		}}
 
 	handler := NewHandler(req, &claims)
-	sharedHandler := &SharedDatasetsHandler{RequestHandler: *handler}
-	// Note: not setting crossWorkspaceDatasetsService
+	// Note: not setting crossWorkspaceDatasetsService, and calling the route function directly since the
+	// route's middleware would construct the default service
 
-	resp, err := sharedHandler.handle(context.Background())
+	resp, err := handler.getSharedDatasets(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 	assert.Contains(t, resp.Body, "cross-workspace service not configured")
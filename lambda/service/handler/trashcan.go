@@ -2,11 +2,9 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/pennsieve/datasets-service/api/models"
-	"github.com/pennsieve/pennsieve-go-core/pkg/authorizer"
-	"github.com/pennsieve/pennsieve-go-core/pkg/models/permissions"
-	"math"
 	"net/http"
 )
 
@@ -15,53 +13,124 @@ const (
 	DefaultOffset = 0
 )
 
-type TrashcanHandler struct {
-	RequestHandler
-}
-
-func (h *TrashcanHandler) handle(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
-	switch h.method {
-	case "GET":
-		return h.get(ctx)
-	default:
-		return h.logAndBuildError("method not allowed: "+h.method, http.StatusMethodNotAllowed), nil
+func (h *RequestHandler) getTrashcan(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	datasetID, ok := h.request.QueryStringParameters["dataset_id"]
+	if !ok {
+		return h.handleError(models.ValidationError{Message: "query param 'dataset_id' is required"})
+	}
+	filter, err := h.trashcanFilter()
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
+	}
+	sort, err := models.ParseTrashcanSort(h.request.QueryStringParameters["sort"], h.request.QueryStringParameters["order"])
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
+	}
+	paging, err := queryParamsAsPaging(h, func(cursor string) (*models.TrashcanCursor, error) {
+		return models.ParseTrashcanCursor(cursor, sort)
+	})
+	if err != nil {
+		return h.handleError(models.ValidationError{Message: err.Error()})
+	}
+	rootNodeId := h.request.QueryStringParameters["root_node_id"]
+	page, err := h.datasetsService.GetTrashcanPage(ctx, datasetID, rootNodeId, filter, sort, paging)
+	if err == nil {
+		h.logger.Info("OK")
+		return h.buildResponse(page, http.StatusOK)
 	}
+	return h.handleError(err)
 
 }
 
-func (h *TrashcanHandler) get(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
-	if authorized := authorizer.HasRole(*h.claims, permissions.ViewFiles); !authorized {
-		return h.logAndBuildError("unauthorized", http.StatusUnauthorized), nil
+// trashcanFilter reads the optional filter query params of getTrashcan.
+func (h *RequestHandler) trashcanFilter() (models.TrashcanFilter, error) {
+	filter := models.TrashcanFilter{
+		Name: h.request.QueryStringParameters["name"],
+		Type: h.request.QueryStringParameters["type"],
+	}
+	var err error
+	if filter.NamePrefix, err = h.queryParamAsBool("name_prefix", false); err != nil {
+		return filter, err
+	}
+	if state, ok := h.request.QueryStringParameters["state"]; ok {
+		if filter.State, err = models.ParseTrashcanState(state); err != nil {
+			return filter, err
+		}
 	}
+	if filter.DeletedAfter, err = h.queryParamAsTime("deleted_after"); err != nil {
+		return filter, err
+	}
+	if filter.DeletedBefore, err = h.queryParamAsTime("deleted_before"); err != nil {
+		return filter, err
+	}
+	if filter.Flat, err = h.queryParamAsBool("flat", false); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
 
+// getTrashcanSummary totals what purgeTrashcan would delete with the same parameters.
+func (h *RequestHandler) getTrashcanSummary(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
 	datasetID, ok := h.request.QueryStringParameters["dataset_id"]
 	if !ok {
-		return h.logAndBuildError("query param 'dataset_id' is required", http.StatusBadRequest), nil
+		return h.handleError(models.ValidationError{Message: "query param 'dataset_id' is required"})
 	}
-	limit, err := h.queryParamAsInt("limit", 0, 100, DefaultLimit)
+	rootNodeId := h.request.QueryStringParameters["root_node_id"]
+	summary, err := h.datasetsService.GetTrashcanSummary(ctx, datasetID, rootNodeId)
 	if err != nil {
-		return h.logAndBuildError(err.Error(), http.StatusBadRequest), nil
+		return h.handleError(err)
 	}
-	offset, err := h.queryParamAsInt("offset", 0, math.MaxInt, DefaultOffset)
+	h.logger.WithField("packageCount", summary.PackageCount).Info("OK")
+	return h.buildResponse(summary, http.StatusOK)
+}
+
+// exportTrashcan writes the whole trashcan to a new S3 object and returns a presigned URL for it, so that it can be reviewed
+// without paging through getTrashcan folder by folder.
+func (h *RequestHandler) exportTrashcan(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	datasetID, ok := h.request.QueryStringParameters["dataset_id"]
+	if !ok {
+		return h.handleError(models.ValidationError{Message: "query param 'dataset_id' is required"})
+	}
+	result, err := h.datasetsService.ExportTrashcan(ctx, datasetID)
 	if err != nil {
-		return h.logAndBuildError(err.Error(), http.StatusBadRequest), nil
+		return h.handleError(err)
+	}
+	h.logger.WithField("deletedCount", result.DeletedCount).Info("OK")
+	return h.buildResponse(result, http.StatusOK)
+}
+
+// purgeTrashcan permanently removes the contents of the trashcan. Since this cannot be undone, its route
+// requires the Manager role rather than a file permission.
+func (h *RequestHandler) purgeTrashcan(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	datasetID, ok := h.request.QueryStringParameters["dataset_id"]
+	if !ok {
+		return h.handleError(models.ValidationError{Message: "query param 'dataset_id' is required"})
 	}
 	rootNodeId := h.request.QueryStringParameters["root_node_id"]
-	page, err := h.datasetsService.GetTrashcanPage(ctx, datasetID, rootNodeId, limit, offset)
+	result, err := h.datasetsService.PurgeTrashcan(ctx, datasetID, rootNodeId)
 	if err == nil {
-		h.logger.Info("OK")
-		return h.buildResponse(page, http.StatusOK)
-	}
-	switch err.(type) {
-	case models.DatasetNotFoundError:
-		return h.logAndBuildError(err.Error(), http.StatusNotFound), nil
-	case models.PackageNotFoundError:
-		return h.logAndBuildError(err.Error(), http.StatusBadRequest), nil
-	case models.FolderNotFoundError:
-		return h.logAndBuildError(err.Error(), http.StatusBadRequest), nil
-	default:
-		h.logger.Errorf("get trashcan failed: %s", err)
-		return nil, err
+		h.logger.WithField("packageCount", result.PackageCount).Info("OK")
+		return h.buildResponse(result, http.StatusAccepted)
 	}
+	return h.handleError(err)
+}
 
+func (h *RequestHandler) restoreTrashcan(ctx context.Context) (*events.APIGatewayV2HTTPResponse, error) {
+	datasetID, ok := h.request.QueryStringParameters["dataset_id"]
+	if !ok {
+		return h.handleError(models.ValidationError{Message: "query param 'dataset_id' is required"})
+	}
+	var restoreRequest models.RestoreRequest
+	if err := json.Unmarshal([]byte(h.body), &restoreRequest); err != nil {
+		return h.handleError(models.ValidationError{Message: "unable to parse request body: " + err.Error()})
+	}
+	if len(restoreRequest.NodeIds) == 0 {
+		return h.handleError(models.ValidationError{Message: "request body 'nodeIds' must not be empty"})
+	}
+	response, err := h.datasetsService.RestorePackages(ctx, datasetID, restoreRequest.NodeIds)
+	if err == nil {
+		h.logger.Info("OK")
+		return h.buildResponse(response, http.StatusOK)
+	}
+	return h.handleError(err)
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/pennsieve/datasets-service/api/service"
+	"github.com/pennsieve/datasets-service/api/store"
 	"github.com/pennsieve/datasets-service/service/handler"
 	"github.com/pennsieve/pennsieve-go-core/pkg/queries/pgdb"
 	"github.com/sirupsen/logrus"
@@ -20,6 +21,9 @@ func init() {
 		panic(fmt.Sprintf("unable to connect to RDS database: %s", err))
 	}
 	logrus.Info("connected to RDS database")
+	if err := store.Migrate(context.Background(), db); err != nil {
+		panic(fmt.Sprintf("unable to migrate RDS database: %s", err))
+	}
 	handler.PennsieveDB = db
 
 	// Get SSM variables
@@ -12,6 +12,14 @@ echo "RUNNING lambda/service TESTS"
 cd "$root_dir/lambda/service"
 go test -v -p 1 ./...; exit_status=$((exit_status || $? ))
 
+echo "RUNNING lambda/manifest-worker TESTS"
+cd "$root_dir/lambda/manifest-worker"
+go test -v -p 1 ./...; exit_status=$((exit_status || $? ))
+
+echo "RUNNING lambda/purge-worker TESTS"
+cd "$root_dir/lambda/purge-worker"
+go test -v -p 1 ./...; exit_status=$((exit_status || $? ))
+
 cd "$root_dir/api"
 echo "RUNNING api TESTS"
 # using -p=1 because more than one package's tests share the same postgres/docker instance
@@ -79,6 +79,34 @@ data "aws_iam_policy_document" "datasets_service_iam_policy_document" {
     resources = ["arn:aws:ssm:${data.aws_region.current_region.name}:${data.aws_caller_identity.current.account_id}:parameter/${var.environment_name}/${var.service_name}/*"]
   }
 
+  statement {
+    sid    = "DatasetsServiceLambdaSNSPermissions"
+    effect = "Allow"
+
+    actions = [
+      "sns:Publish",
+    ]
+
+    resources = [
+      aws_sns_topic.create_manifest_topic.arn,
+      aws_sns_topic.purge_trashcan_topic.arn,
+    ]
+  }
+
+  statement {
+    sid    = "ManifestWorkerDLQPermissions"
+    effect = "Allow"
+
+    actions = [
+      "sqs:SendMessage",
+    ]
+
+    resources = [
+      aws_sqs_queue.manifest_worker_dlq.arn,
+      aws_sqs_queue.purge_worker_dlq.arn,
+    ]
+  }
+
   statement {
     effect = "Allow"
 
@@ -92,4 +120,32 @@ data "aws_iam_policy_document" "datasets_service_iam_policy_document" {
     ]
   }
 
+  dynamic "statement" {
+    for_each = length(var.storage_bucket_arns) > 0 ? [1] : []
+    content {
+      sid    = "ManifestFileUrlPermissions"
+      effect = "Allow"
+
+      actions = [
+        "s3:GetObject",
+      ]
+
+      resources = [for arn in var.storage_bucket_arns : "${arn}/*"]
+    }
+  }
+
+  dynamic "statement" {
+    for_each = length(var.storage_bucket_arns) > 0 ? [1] : []
+    content {
+      sid    = "PurgeWorkerFilePermissions"
+      effect = "Allow"
+
+      actions = [
+        "s3:DeleteObject",
+      ]
+
+      resources = [for arn in var.storage_bucket_arns : "${arn}/*"]
+    }
+  }
+
 }
@@ -15,6 +15,78 @@ resource "aws_lambda_function" "service_lambda" {
     security_group_ids = [data.terraform_remote_state.platform_infrastructure.outputs.upload_v2_security_group_id]
   }
 
+  environment {
+    variables = {
+      ENV                = var.environment_name
+      PENNSIEVE_DOMAIN   = data.terraform_remote_state.account.outputs.domain_name,
+      REGION             = var.aws_region
+      RDS_PROXY_ENDPOINT = data.terraform_remote_state.pennsieve_postgres.outputs.rds_proxy_endpoint,
+      CREATE_MANIFEST_SNS_TOPIC = aws_sns_topic.create_manifest_topic.arn,
+      PURGE_TRASHCAN_SNS_TOPIC = aws_sns_topic.purge_trashcan_topic.arn,
+      LOG_LEVEL = "INFO"
+    }
+  }
+}
+
+resource "aws_lambda_function" "manifest_worker_lambda" {
+  description   = "Lambda Function which generates dataset manifests for the datasets-service"
+  function_name = "${var.environment_name}-${var.service_name}-manifest-worker-lambda-${data.terraform_remote_state.region.outputs.aws_region_shortname}"
+  handler       = "manifest_worker"
+  runtime       = "provided.al2"
+  architectures = ["arm64"]
+  role          = aws_iam_role.datasets_service_lambda_role.arn
+  timeout       = 900
+  memory_size   = 3008
+  s3_bucket     = var.lambda_bucket
+  s3_key        = "${var.service_name}/${var.service_name}-manifest-worker-${var.image_tag}.zip"
+
+  vpc_config {
+    subnet_ids         = tolist(data.terraform_remote_state.vpc.outputs.private_subnet_ids)
+    security_group_ids = [data.terraform_remote_state.platform_infrastructure.outputs.upload_v2_security_group_id]
+  }
+
+  dead_letter_config {
+    target_arn = aws_sqs_queue.manifest_worker_dlq.arn
+  }
+
+  environment {
+    variables = {
+      ENV                = var.environment_name
+      PENNSIEVE_DOMAIN   = data.terraform_remote_state.account.outputs.domain_name,
+      REGION             = var.aws_region
+      RDS_PROXY_ENDPOINT = data.terraform_remote_state.pennsieve_postgres.outputs.rds_proxy_endpoint,
+      LOG_LEVEL = "INFO"
+    }
+  }
+}
+
+// The worker retries failed jobs itself, so a failed invocation goes straight to the dead letter queue.
+resource "aws_lambda_function_event_invoke_config" "manifest_worker_invoke_config" {
+  function_name          = aws_lambda_function.manifest_worker_lambda.function_name
+  maximum_retry_attempts = 0
+}
+
+resource "aws_lambda_function" "purge_worker_lambda" {
+  description   = "Lambda Function which removes the files of packages purged from the trashcan for the datasets-service"
+  function_name = "${var.environment_name}-${var.service_name}-purge-worker-lambda-${data.terraform_remote_state.region.outputs.aws_region_shortname}"
+  handler       = "purge_worker"
+  runtime       = "provided.al2"
+  architectures = ["arm64"]
+  role          = aws_iam_role.datasets_service_lambda_role.arn
+  timeout       = 900
+  memory_size   = 512
+  s3_bucket     = var.lambda_bucket
+  s3_key        = "${var.service_name}/${var.service_name}-purge-worker-${var.image_tag}.zip"
+
+  vpc_config {
+    subnet_ids         = tolist(data.terraform_remote_state.vpc.outputs.private_subnet_ids)
+    security_group_ids = [data.terraform_remote_state.platform_infrastructure.outputs.upload_v2_security_group_id]
+  }
+
+  dead_letter_config {
+    target_arn = aws_sqs_queue.purge_worker_dlq.arn
+  }
+
   environment {
     variables = {
       ENV                = var.environment_name
@@ -9,3 +9,27 @@ output "service_lambda_invoke_arn" {
 output "service_lambda_function_name" {
   value = aws_lambda_function.service_lambda.function_name
 }
+
+output "manifest_worker_lambda_arn" {
+  value = aws_lambda_function.manifest_worker_lambda.arn
+}
+
+output "manifest_worker_dlq_arn" {
+  value = aws_sqs_queue.manifest_worker_dlq.arn
+}
+
+output "purge_worker_lambda_arn" {
+  value = aws_lambda_function.purge_worker_lambda.arn
+}
+
+output "purge_worker_dlq_arn" {
+  value = aws_sqs_queue.purge_worker_dlq.arn
+}
+
+output "purge_trashcan_topic_arn" {
+  value = aws_sns_topic.purge_trashcan_topic.arn
+}
+
+output "create_manifest_topic_arn" {
+  value = aws_sns_topic.create_manifest_topic.arn
+}
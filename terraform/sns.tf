@@ -0,0 +1,43 @@
+# Topic for manifest generation jobs. The manifest worker subscribes to it
+# and writes the requested manifest to the manifest bucket.
+resource "aws_sns_topic" "create_manifest_topic" {
+  name = "${var.environment_name}-${var.service_name}-create-manifest-${data.terraform_remote_state.region.outputs.aws_region_shortname}"
+
+  tags = local.common_tags
+}
+
+# Topic for trashcan purge events. The purge worker subscribes to it
+# and permanently removes the files of the purged packages.
+resource "aws_sns_topic" "purge_trashcan_topic" {
+  name = "${var.environment_name}-${var.service_name}-purge-trashcan-${data.terraform_remote_state.region.outputs.aws_region_shortname}"
+
+  tags = local.common_tags
+}
+
+resource "aws_sns_topic_subscription" "create_manifest_worker_subscription" {
+  topic_arn = aws_sns_topic.create_manifest_topic.arn
+  protocol  = "lambda"
+  endpoint  = aws_lambda_function.manifest_worker_lambda.arn
+}
+
+resource "aws_lambda_permission" "create_manifest_worker_permission" {
+  statement_id  = "AllowExecutionFromSNS"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.manifest_worker_lambda.function_name
+  principal     = "sns.amazonaws.com"
+  source_arn    = aws_sns_topic.create_manifest_topic.arn
+}
+
+resource "aws_sns_topic_subscription" "purge_worker_subscription" {
+  topic_arn = aws_sns_topic.purge_trashcan_topic.arn
+  protocol  = "lambda"
+  endpoint  = aws_lambda_function.purge_worker_lambda.arn
+}
+
+resource "aws_lambda_permission" "purge_worker_permission" {
+  statement_id  = "AllowExecutionFromSNS"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.purge_worker_lambda.function_name
+  principal     = "sns.amazonaws.com"
+  source_arn    = aws_sns_topic.purge_trashcan_topic.arn
+}
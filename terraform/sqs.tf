@@ -0,0 +1,15 @@
+# Dead letter queue for manifest messages the worker could not process
+resource "aws_sqs_queue" "manifest_worker_dlq" {
+  name                      = "${var.environment_name}-${var.service_name}-manifest-worker-dlq-${data.terraform_remote_state.region.outputs.aws_region_shortname}"
+  message_retention_seconds = 1209600
+
+  tags = local.common_tags
+}
+
+# Dead letter queue for purge messages the worker could not process after Lambda's retries
+resource "aws_sqs_queue" "purge_worker_dlq" {
+  name                      = "${var.environment_name}-${var.service_name}-purge-worker-dlq-${data.terraform_remote_state.region.outputs.aws_region_shortname}"
+  message_retention_seconds = 1209600
+
+  tags = local.common_tags
+}
@@ -16,6 +16,13 @@ variable "lambda_bucket" {
   default = "pennsieve-cc-lambda-functions-use1"
 }
 
+# Buckets holding dataset files. Manifests requested with include_urls contain URLs presigned by
+# the manifest worker, which only work if its role can read the files.
+variable "storage_bucket_arns" {
+  type    = list(string)
+  default = []
+}
+
 locals {
   # domain_name = data.terraform_remote_state.account.outputs.domain_name
   hosted_zone = data.terraform_remote_state.account.outputs.public_hosted_zone_id